@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// RecalcResponse summarizes an on-demand recalculation
+type RecalcResponse struct {
+	Period     string  `json:"period"`
+	Date       string  `json:"date"`
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Rows       string  `json:"rows"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// recalcPeriods maps the accepted ?period= values to the aggregate they
+// recompute for the period containing the given date
+var recalcPeriods = map[string]func(db *ReconnectingDB, day time.Time) (RecalcResponse, error){
+	"hourly":  recalcHourly,
+	"daily":   recalcDaily,
+	"weekly":  recalcWeekly,
+	"monthly": recalcMonthly,
+}
+
+// requireAdminToken rejects requests without a matching "Authorization:
+// Bearer <ADMIN_TOKEN>" header
+func requireAdminToken(next http.HandlerFunc) http.HandlerFunc {
+	return requireBearerToken(config.AdminToken, next)
+}
+
+// recalcHandler recomputes the statistics of one period right away, e.g.
+// after bad rows in weather were corrected by hand
+func recalcHandler(db *ReconnectingDB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		period := r.URL.Query().Get("period")
+		recalc, ok := recalcPeriods[period]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "period must be hourly, daily, weekly or monthly")
+			return
+		}
+		date, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("date"), config.Location)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "date must be in YYYY-MM-DD format")
+			return
+		}
+
+		start := time.Now()
+		resp, err := recalc(db, date)
+		if errors.Is(err, errReadOnly) {
+			writeError(w, http.StatusForbidden, "statistics cannot be recalculated in read-only mode")
+			return
+		}
+		if err != nil {
+			slog.Error("Failed to recalculate statistics", "component", "admin", "period", period,
+				"date", date.Format("2006-01-02"), "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to recalculate statistics")
+			return
+		}
+
+		resp.Period = period
+		resp.Date = date.Format("2006-01-02")
+		resp.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+		slog.Info("Statistics recalculated on demand", "component", "admin", "period", period,
+			"from", resp.From, "to", resp.To)
+		writeJSON(w, http.StatusAccepted, resp)
+	}
+}
+
+// recalcHourly recomputes the 24 (23 or 25 on DST days) hours of a day
+func recalcHourly(db *ReconnectingDB, day time.Time) (RecalcResponse, error) {
+	if config.ReadOnly {
+		return RecalcResponse{}, errReadOnly
+	}
+	next := day.AddDate(0, 0, 1)
+	hours := 0
+	err := forEachStation(db.DB(), func(db Store) error {
+		hours = 0
+		return withAggregateTx(db, func(db dbExecutor) error {
+			for hour := day; hour.Before(next); hour = hour.Add(time.Hour) {
+				if err := updateHourlyAverages(db, hour); err != nil {
+					return err
+				}
+				hours++
+			}
+			return nil
+		})
+	})
+	return RecalcResponse{
+		From: day.Format("2006-01-02"),
+		To:   day.Format("2006-01-02"),
+		Rows: fmt.Sprintf("weather_hourly (%d hours)", hours),
+	}, err
+}
+
+func recalcDaily(db *ReconnectingDB, day time.Time) (RecalcResponse, error) {
+	err := forEachStation(db.DB(), func(db Store) error {
+		return withAggregateTx(db, func(db dbExecutor) error {
+			return updateDailyStatisticsFor(db, day)
+		})
+	})
+	return RecalcResponse{
+		From: day.Format("2006-01-02"),
+		To:   day.Format("2006-01-02"),
+		Rows: "weather_daily",
+	}, err
+}
+
+func recalcWeekly(db *ReconnectingDB, day time.Time) (RecalcResponse, error) {
+	monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	err := forEachStation(db.DB(), func(db Store) error {
+		return withAggregateTx(db, func(db dbExecutor) error {
+			return updateWeeklyStatisticsFor(db, monday)
+		})
+	})
+	year, week := monday.ISOWeek()
+	return RecalcResponse{
+		From: monday.Format("2006-01-02"),
+		To:   monday.AddDate(0, 0, 6).Format("2006-01-02"),
+		Rows: fmt.Sprintf("weather_weekly (week %d/%d)", week, year),
+	}, err
+}
+
+func recalcMonthly(db *ReconnectingDB, day time.Time) (RecalcResponse, error) {
+	first := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	err := forEachStation(db.DB(), func(db Store) error {
+		return withAggregateTx(db, func(db dbExecutor) error {
+			return updateMonthlyStatisticsFor(db, first.Year(), first.Month())
+		})
+	})
+	return RecalcResponse{
+		From: first.Format("2006-01-02"),
+		To:   first.AddDate(0, 1, -1).Format("2006-01-02"),
+		Rows: fmt.Sprintf("weather_monthly (%s)", first.Format("2006-01")),
+	}, err
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// heatmapColumns maps the metric names accepted by the API to columns of
+// weather_hourly. Only names listed here ever reach the SQL query.
+var heatmapColumns = map[string]string{
+	"temperature": "avg_temperature",
+	"pressure":    "avg_pressure",
+	"humidity":    "avg_humidity",
+}
+
+// HeatmapResponse is a (day x hour) matrix of hourly averages for one month.
+// Values[d][h] is nil when no hourly aggregate exists for that cell.
+type HeatmapResponse struct {
+	Metric string       `json:"metric"`
+	Month  string       `json:"month"`
+	Days   []string     `json:"days"`
+	Hours  []int        `json:"hours"`
+	Values [][]*float64 `json:"values"`
+}
+
+// Timeouts of the HTTP API, so slow or idle clients cannot hold connections
+// open indefinitely
+const (
+	httpReadHeaderTimeout = 10 * time.Second
+	httpReadTimeout       = 30 * time.Second
+	httpWriteTimeout      = 60 * time.Second
+	httpIdleTimeout       = 120 * time.Second
+)
+
+// httpServer is the running HTTP API, nil when HTTP_PORT is not set
+var httpServer *http.Server
+
+// startHTTPServer starts the HTTP API in the background
+func startHTTPServer(db *ReconnectingDB) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/heatmap", heatmapHandler(db))
+	mux.HandleFunc("GET /api/since", sinceHandler(db))
+	mux.HandleFunc("GET /api/hourly", hourlyHandler(db))
+	mux.HandleFunc("GET /api/daily", dailyHandler(db))
+	mux.HandleFunc("GET /api/latest", latestHandler(db))
+	mux.HandleFunc("GET /healthz", healthzHandler(db))
+	mux.HandleFunc("GET /api/current", currentHandler)
+	mux.HandleFunc("GET /api/station", stationHandler)
+	mux.Handle("GET /metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+
+	if config.EnableDashboard {
+		mux.HandleFunc("GET /{$}", dashboardHandler)
+	}
+
+	// Endpoints exposing internals are only available with an API token
+	if config.APIToken != "" {
+		mux.HandleFunc("GET /api/config", requireAPIToken(configHandler))
+		mux.HandleFunc("GET /api/jobs", requireAPIToken(jobsHandler))
+	}
+
+	// Endpoints changing stored data have a separate token
+	if config.AdminToken != "" {
+		mux.HandleFunc("POST /admin/recalc", requireAdminToken(recalcHandler(db)))
+	}
+
+	server := &http.Server{
+		Addr:              ":" + config.HTTPPort,
+		Handler:           mux,
+		ReadHeaderTimeout: httpReadHeaderTimeout,
+		ReadTimeout:       httpReadTimeout,
+		WriteTimeout:      httpWriteTimeout,
+		IdleTimeout:       httpIdleTimeout,
+	}
+	httpServer = server
+	go func() {
+		slog.Info("HTTP API listening", "component", "api", "addr", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("HTTP server error", "component", "api", "error", err)
+		}
+	}()
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Failed to encode JSON response", "component", "api", "error", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// roundDisplay rounds a value to ROUND_DECIMALS for presentation
+func roundDisplay(value float64) float64 {
+	return roundTo(value, config.RoundDecimals)
+}
+
+// ------------------------- HEATMAP ------------------------------
+func heatmapHandler(db *ReconnectingDB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		metric := r.URL.Query().Get("metric")
+		if metric == "" {
+			metric = "temperature"
+		}
+		column, ok := heatmapColumns[metric]
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown metric %q", metric))
+			return
+		}
+
+		month := r.URL.Query().Get("month")
+		firstDay, err := time.Parse("2006-01", month)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "month must be in YYYY-MM format")
+			return
+		}
+
+		resp, err := buildHeatmap(db.DB(), metric, column, firstDay)
+		db.Observe(err)
+		if err != nil {
+			slog.Error("Failed to build heatmap", "component", "api", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to query hourly data")
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func buildHeatmap(db *sql.DB, metric, column string, firstDay time.Time) (*HeatmapResponse, error) {
+	lastDay := firstDay.AddDate(0, 1, -1)
+	days := lastDay.Day()
+
+	resp := &HeatmapResponse{
+		Metric: metric,
+		Month:  firstDay.Format("2006-01"),
+		Days:   make([]string, days),
+		Hours:  make([]int, 24),
+		Values: make([][]*float64, days),
+	}
+	for h := range resp.Hours {
+		resp.Hours[h] = h
+	}
+	for d := 0; d < days; d++ {
+		resp.Days[d] = firstDay.AddDate(0, 0, d).Format("2006-01-02")
+		resp.Values[d] = make([]*float64, 24)
+	}
+
+	// column comes from heatmapColumns, never from user input
+	query := fmt.Sprintf(`
+		SELECT date, hour, %s
+		FROM weather_hourly
+		WHERE date >= ? AND date <= ?%s
+	`, column, stationFilter(db))
+
+	rows, err := db.Query(query, firstDay.Format("2006-01-02"), lastDay.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weather_hourly: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date time.Time
+		var hour int
+		var value sql.NullFloat64
+		if err := rows.Scan(&date, &hour, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan hourly row: %w", err)
+		}
+		if !value.Valid || hour < 0 || hour > 23 {
+			continue
+		}
+		d := date.Day() - 1
+		if d < 0 || d >= days {
+			continue
+		}
+		v := roundDisplay(value.Float64)
+		resp.Values[d][hour] = &v
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate hourly rows: %w", err)
+	}
+
+	return resp, nil
+}
+
+// ------------------------- SINCE ------------------------------
+
+// SinceResponse is a page of raw readings newer than the requested cursor.
+// NextID is the cursor for the following request.
+type SinceResponse struct {
+	Readings []StoredReading `json:"readings"`
+	NextID   int64           `json:"next_id"`
+}
+
+// sinceHandler returns raw readings with id greater than ?id=, or measured
+// after ?ts= (Unix epoch or RFC3339), in ascending order
+func sinceHandler(db *ReconnectingDB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idParam := r.URL.Query().Get("id")
+		tsParam := r.URL.Query().Get("ts")
+
+		var query string
+		var arg any
+		var cursor int64
+
+		switch {
+		case idParam != "":
+			id, err := strconv.ParseInt(idParam, 10, 64)
+			if err != nil || id < 0 {
+				writeError(w, http.StatusBadRequest, "id must be a non-negative integer")
+				return
+			}
+			query = `
+				SELECT id, measured_at, temperature, pressure, humidity
+				FROM weather
+				WHERE id > ?` + stationFilter(db.DB()) + `
+				ORDER BY id ASC
+				LIMIT ?
+			`
+			arg = id
+			cursor = id
+		case tsParam != "":
+			ts, err := parseTimestampParam(tsParam)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "ts must be a Unix timestamp or RFC3339 time")
+				return
+			}
+			query = `
+				SELECT id, measured_at, temperature, pressure, humidity
+				FROM weather
+				WHERE measured_at > ?` + stationFilter(db.DB()) + `
+				ORDER BY measured_at ASC, id ASC
+				LIMIT ?
+			`
+			arg = ts
+		default:
+			writeError(w, http.StatusBadRequest, "either id or ts is required")
+			return
+		}
+
+		rows, err := db.DB().Query(query, arg, config.APIPageSize)
+		db.Observe(err)
+		if err != nil {
+			slog.Error("Failed to query readings", "component", "api", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to query readings")
+			return
+		}
+		defer rows.Close()
+
+		resp := SinceResponse{Readings: []StoredReading{}, NextID: cursor}
+		for rows.Next() {
+			var reading StoredReading
+			if err := rows.Scan(&reading.ID, &reading.MeasuredAt,
+				&reading.Temperature, &reading.Pressure, &reading.Humidity); err != nil {
+				slog.Error("Failed to scan reading", "component", "api", "error", err)
+				writeError(w, http.StatusInternalServerError, "failed to read readings")
+				return
+			}
+			resp.Readings = append(resp.Readings, reading)
+			if reading.ID > resp.NextID {
+				resp.NextID = reading.ID
+			}
+		}
+		if err := rows.Err(); err != nil {
+			slog.Error("Failed to iterate readings", "component", "api", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to read readings")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// parseTimestampParam parses a Unix epoch or an RFC3339 time
+func parseTimestampParam(value string) (time.Time, error) {
+	if epoch, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return measurementTime(epoch), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t.In(config.Location), err
+}
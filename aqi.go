@@ -0,0 +1,134 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+)
+
+// aqiBreakpoint maps a concentration range to an index range
+type aqiBreakpoint struct {
+	CLow, CHigh float64
+	ILow, IHigh int
+}
+
+// US EPA AQI breakpoints (2024 revision) for 24-hour PM2.5 and PM10 in µg/m³
+var (
+	pm25Breakpoints = []aqiBreakpoint{
+		{0.0, 9.0, 0, 50},
+		{9.1, 35.4, 51, 100},
+		{35.5, 55.4, 101, 150},
+		{55.5, 125.4, 151, 200},
+		{125.5, 225.4, 201, 300},
+		{225.5, 325.4, 301, 500},
+	}
+	pm10Breakpoints = []aqiBreakpoint{
+		{0, 54, 0, 50},
+		{55, 154, 51, 100},
+		{155, 254, 101, 150},
+		{255, 354, 151, 200},
+		{355, 424, 201, 300},
+		{425, 604, 301, 500},
+	}
+)
+
+// subIndex computes the AQI of one pollutant. The concentration must already
+// be truncated to the precision of the breakpoint table; values above the
+// table are capped at 500.
+func subIndex(c float64, table []aqiBreakpoint) int {
+	for _, bp := range table {
+		if c <= bp.CHigh {
+			if c < bp.CLow {
+				// Between two truncated ranges, belongs to the lower one
+				c = bp.CLow
+			}
+			return int(math.Round(float64(bp.IHigh-bp.ILow)/(bp.CHigh-bp.CLow)*(c-bp.CLow))) + bp.ILow
+		}
+	}
+	return 500
+}
+
+// computeAQI returns the US EPA AQI as the higher of the PM2.5 and PM10
+// sub-indices. A negative concentration means the pollutant is not measured;
+// the result is -1 when neither is.
+func computeAQI(pm25, pm10 float64) int {
+	aqi := -1
+	if pm25 >= 0 {
+		aqi = subIndex(math.Floor(pm25*10)/10, pm25Breakpoints)
+	}
+	if pm10 >= 0 {
+		aqi = max(aqi, subIndex(math.Floor(pm10), pm10Breakpoints))
+	}
+	return aqi
+}
+
+// nullToMissing maps a NULL concentration to the "not measured" value of
+// computeAQI
+func nullToMissing(v sql.NullFloat64) float64 {
+	if !v.Valid {
+		return -1
+	}
+	return v.Float64
+}
+
+// updateDailyAirQuality stores the daily average and maximum CO2, PM2.5 and
+// PM10 and the highest hourly AQI of a date. Without air-quality probes all
+// values stay NULL.
+func updateDailyAirQuality(db dbExecutor, date string) error {
+	var avgCO2, maxCO2, avgPM25, maxPM25, avgPM10, maxPM10 sql.NullFloat64
+
+	query := `
+		SELECT AVG(co2), MAX(co2), AVG(pm25), MAX(pm25), AVG(pm10), MAX(pm10)
+		FROM weather
+		WHERE ` + observationDay() + ` = ?` + exclusionFilter() + stationFilter(db)
+	err := db.QueryRow(query, date).Scan(&avgCO2, &maxCO2, &avgPM25, &maxPM25, &avgPM10, &maxPM10)
+	if err != nil {
+		return fmt.Errorf("failed to calculate daily air quality: %w", err)
+	}
+
+	hourlyQuery := `
+		SELECT AVG(pm25), AVG(pm10)
+		FROM weather
+		WHERE ` + observationDay() + ` = ?` + exclusionFilter() + stationFilter(db) + `
+		GROUP BY HOUR(measured_at)
+	`
+	rows, err := db.Query(hourlyQuery, date)
+	if err != nil {
+		return fmt.Errorf("failed to query hourly particulates: %w", err)
+	}
+	defer rows.Close()
+
+	var maxAQI sql.NullInt64
+	for rows.Next() {
+		var pm25, pm10 sql.NullFloat64
+		if err := rows.Scan(&pm25, &pm10); err != nil {
+			return fmt.Errorf("failed to scan hourly particulates: %w", err)
+		}
+		if aqi := computeAQI(nullToMissing(pm25), nullToMissing(pm10)); aqi >= 0 && (!maxAQI.Valid || int64(aqi) > maxAQI.Int64) {
+			maxAQI = sql.NullInt64{Int64: int64(aqi), Valid: true}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate hourly particulates: %w", err)
+	}
+
+	if maxAQI.Valid {
+		slog.Info("Max AQI", "component", "stats", "date", date, "aqi", maxAQI.Int64)
+	}
+
+	update := `
+		UPDATE weather_daily
+		SET avg_co2 = ?, max_co2 = ?, avg_pm25 = ?, max_pm25 = ?, avg_pm10 = ?, max_pm10 = ?, max_aqi = ?
+		WHERE date = ?` + stationFilter(db) + `
+	`
+	_, err = db.Exec(update,
+		roundNullAggregate(avgCO2), roundNullAggregate(maxCO2),
+		roundNullAggregate(avgPM25), roundNullAggregate(maxPM25),
+		roundNullAggregate(avgPM10), roundNullAggregate(maxPM10),
+		maxAQI, date)
+	if err != nil {
+		return fmt.Errorf("failed to store daily air quality: %w", err)
+	}
+	return nil
+}
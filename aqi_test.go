@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestComputeAQI(t *testing.T) {
+	tests := []struct {
+		name       string
+		pm25, pm10 float64
+		want       int
+	}{
+		{"clean air", 0, 0, 0},
+		{"pm2.5 top of good", 9.0, -1, 50},
+		{"pm2.5 truncated into good", 9.09, -1, 50},
+		{"pm2.5 bottom of moderate", 9.1, -1, 51},
+		{"pm2.5 inside moderate", 12.0, -1, 56},
+		{"pm2.5 top of moderate", 35.4, -1, 100},
+		{"pm2.5 bottom of unhealthy for sensitive groups", 35.5, -1, 101},
+		{"pm2.5 top of unhealthy for sensitive groups", 55.4, -1, 150},
+		{"pm2.5 bottom of unhealthy", 55.5, -1, 151},
+		{"pm2.5 top of unhealthy", 125.4, -1, 200},
+		{"pm2.5 bottom of very unhealthy", 125.5, -1, 201},
+		{"pm2.5 top of very unhealthy", 225.4, -1, 300},
+		{"pm2.5 bottom of hazardous", 225.5, -1, 301},
+		{"pm2.5 top of the table", 325.4, -1, 500},
+		{"pm2.5 above the table", 400, -1, 500},
+		{"pm10 top of good", -1, 54, 50},
+		{"pm10 truncated into good", -1, 54.9, 50},
+		{"pm10 bottom of moderate", -1, 55, 51},
+		{"pm10 inside moderate", -1, 100, 73},
+		{"pm10 top of moderate", -1, 154, 100},
+		{"pm10 bottom of unhealthy for sensitive groups", -1, 155, 101},
+		{"pm10 bottom of unhealthy", -1, 255, 151},
+		{"pm10 bottom of very unhealthy", -1, 355, 201},
+		{"pm10 bottom of hazardous", -1, 425, 301},
+		{"pm10 above the table", -1, 700, 500},
+		{"higher of both, pm2.5", 35.5, 54, 101},
+		{"higher of both, pm10", 9.0, 155, 101},
+		{"nothing measured", -1, -1, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeAQI(tt.pm25, tt.pm10); got != tt.want {
+				t.Errorf("computeAQI(%v, %v) = %d, want %d", tt.pm25, tt.pm10, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// weather_archive keeps every reading unmodified for compliance: values at
+// full precision plus the raw JSON payload as read from the source. Nothing
+// in the processor ever updates or deletes archive rows.
+
+// archiveReading stores a reading in weather_archive, linked to its row in
+// the weather table
+func archiveReading(db dbExecutor, weatherID int64, measuredAt time.Time, data WeatherData) error {
+	insert := `
+		INSERT INTO weather_archive (
+			weather_id, measured_at,
+			temperature, pressure, humidity, solar_radiation,
+			payload
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	var payload any
+	if len(data.Raw) > 0 {
+		payload = string(data.Raw)
+	}
+
+	_, err := db.Exec(insert, weatherID, measuredAt,
+		presentValue(data, "temperature", data.Temperature),
+		presentValue(data, "pressure", data.Pressure),
+		presentValue(data, "humidity", data.Humidity),
+		data.SolarRadiation,
+		payload)
+	if err != nil {
+		return fmt.Errorf("failed to archive reading: %w", err)
+	}
+	return nil
+}
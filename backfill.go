@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// The scheduled statistics jobs only compute the period that just ended, so
+// periods missed while the service was down stay empty. The -backfill-*
+// flags recompute every period of a date range and exit.
+
+// parseDateRange parses FROM:TO (YYYY-MM-DD, inclusive) in TIMEZONE
+func parseDateRange(value string) (time.Time, time.Time, error) {
+	fromStr, toStr, ok := strings.Cut(value, ":")
+	if !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("expected FROM:TO, got %q", value)
+	}
+	return parseDates(fromStr, toStr)
+}
+
+// parseDates parses an inclusive range of two YYYY-MM-DD dates in TIMEZONE
+func parseDates(fromStr, toStr string) (time.Time, time.Time, error) {
+	from, err := time.ParseInLocation("2006-01-02", fromStr, config.Location)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from date: %w", err)
+	}
+	to, err := time.ParseInLocation("2006-01-02", toStr, config.Location)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to date: %w", err)
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to %s is before from %s", toStr, fromStr)
+	}
+	return from, to, nil
+}
+
+// backfillDaily computes the daily statistics of every day from from to to
+func backfillDaily(db *sql.DB, from, to time.Time) error {
+	days := 0
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		waitCatchup()
+		err := forEachStation(db, func(db Store) error {
+			return withAggregateTx(db, func(db dbExecutor) error {
+				return updateDailyStatisticsFor(db, day)
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to backfill daily statistics for %s: %w", day.Format("2006-01-02"), err)
+		}
+		days++
+	}
+	slog.Info("Backfilled statistics", "component", "stats", "period", "daily", "days", days)
+	return nil
+}
+
+// backfillWeekly computes the weekly statistics of every ISO week that
+// overlaps the range from from to to
+func backfillWeekly(db *sql.DB, from, to time.Time) error {
+	weeks := 0
+	firstMonday := from.AddDate(0, 0, -(int(from.Weekday())+6)%7)
+	for monday := firstMonday; !monday.After(to); monday = monday.AddDate(0, 0, 7) {
+		waitCatchup()
+		err := forEachStation(db, func(db Store) error {
+			return withAggregateTx(db, func(db dbExecutor) error {
+				return updateWeeklyStatisticsFor(db, monday)
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to backfill weekly statistics for %s: %w", monday.Format("2006-01-02"), err)
+		}
+		weeks++
+	}
+	slog.Info("Backfilled statistics", "component", "stats", "period", "weekly", "weeks", weeks)
+	return nil
+}
+
+// backfillMonthly computes the monthly statistics of every month that
+// overlaps the range from from to to
+func backfillMonthly(db *sql.DB, from, to time.Time) error {
+	months := 0
+	firstMonth := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+	for month := firstMonth; !month.After(to); month = month.AddDate(0, 1, 0) {
+		waitCatchup()
+		err := forEachStation(db, func(db Store) error {
+			return withAggregateTx(db, func(db dbExecutor) error {
+				return updateMonthlyStatisticsFor(db, month.Year(), month.Month())
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to backfill monthly statistics for %s: %w", month.Format("2006-01"), err)
+		}
+		months++
+	}
+	slog.Info("Backfilled statistics", "component", "stats", "period", "monthly", "months", months)
+	return nil
+}
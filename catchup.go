@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// catchupLimiter paces inserts of historical readings (buffered or
+// backfilled) so a large catch-up does not starve live processing. It is nil
+// when CATCHUP_RATE_PER_SEC is not set.
+var catchupLimiter *rate.Limiter
+
+// newCatchupLimiter returns a token bucket allowing perSecond inserts with a
+// burst of one second's worth, or nil for an unlimited catch-up
+func newCatchupLimiter(perSecond float64) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	burst := int(perSecond)
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), burst)
+}
+
+// waitCatchup blocks until the next catch-up insert is allowed. Live
+// readings never go through it.
+func waitCatchup() {
+	if catchupLimiter == nil {
+		return
+	}
+	catchupLimiter.Wait(context.Background())
+}
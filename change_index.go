@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Daily ranges at which a component of the change index saturates
+const (
+	changeIndexTemperatureSpan = 15.0 // °C
+	changeIndexPressureSpan    = 15.0 // hPa
+	changeIndexHumiditySpan    = 50.0 // %
+)
+
+// ChangeIndexWeights are the relative weights of the change index components
+type ChangeIndexWeights struct {
+	Temperature float64
+	Pressure    float64
+	Humidity    float64
+}
+
+// changeIndex combines the temperature amplitude, pressure range and
+// humidity range of a day into a 0–100 score: each range is normalized to
+// its saturation span and the results are averaged with the given weights
+func changeIndex(tempAmplitude, pressureRange, humidityRange float64, w ChangeIndexWeights) int {
+	total := w.Temperature + w.Pressure + w.Humidity
+	if total <= 0 {
+		return 0
+	}
+
+	score := w.Temperature*normalizeRange(tempAmplitude, changeIndexTemperatureSpan) +
+		w.Pressure*normalizeRange(pressureRange, changeIndexPressureSpan) +
+		w.Humidity*normalizeRange(humidityRange, changeIndexHumiditySpan)
+
+	return int(math.Round(score / total * 100))
+}
+
+// normalizeRange scales a range to 0..1 of span, clamped
+func normalizeRange(value, span float64) float64 {
+	return math.Max(0, math.Min(value/span, 1))
+}
+
+// updateDailyChangeIndex stores the change index of a date
+func updateDailyChangeIndex(db dbExecutor, date string, index int) error {
+	_, err := db.Exec(`UPDATE weather_daily SET change_index = ? WHERE date = ?`+stationFilter(db), index, date)
+	if err != nil {
+		return fmt.Errorf("failed to store change index: %w", err)
+	}
+	return nil
+}
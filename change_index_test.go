@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestChangeIndex(t *testing.T) {
+	equal := ChangeIndexWeights{Temperature: 1, Pressure: 1, Humidity: 1}
+
+	tests := []struct {
+		name                          string
+		amplitude, pressure, humidity float64
+		weights                       ChangeIndexWeights
+		want                          int
+	}{
+		{"flat day", 1, 0.5, 3, equal, 5},
+		{"stormy day", 14, 18, 55, equal, 98},
+		{"no change", 0, 0, 0, equal, 0},
+		{"pressure only weighted", 1, 15, 3, ChangeIndexWeights{Pressure: 1}, 100},
+		{"no weights", 14, 18, 55, ChangeIndexWeights{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changeIndex(tt.amplitude, tt.pressure, tt.humidity, tt.weights); got != tt.want {
+				t.Errorf("changeIndex(%v, %v, %v) = %d, want %d", tt.amplitude, tt.pressure, tt.humidity, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDailyChangeIndexFlatAndStormyDay(t *testing.T) {
+	setupTestConfig(t, map[string]string{"ENABLE_CHANGE_INDEX": "true"})
+	db := newTestStore(t)
+
+	flat := time.Date(2024, 6, 1, 0, 0, 0, 0, config.Location)
+	stormy := flat.AddDate(0, 0, 1)
+	days := []struct {
+		day      time.Time
+		readings [][3]float64 // temperature, pressure, humidity
+	}{
+		{flat, [][3]float64{{18.0, 1015.0, 70}, {19.0, 1015.5, 67}, {18.5, 1015.2, 69}}},
+		{stormy, [][3]float64{{30.0, 1012.0, 40}, {16.0, 994.0, 95}, {18.0, 1000.0, 90}}},
+	}
+	for _, d := range days {
+		for i, r := range d.readings {
+			processFixture(t, db, []byte(fmt.Sprintf(
+				`{"timestamp": %d, "temperature": %.1f, "pressure": %.1f, "humidity": %.1f}`,
+				d.day.Add(time.Duration(8+4*i)*time.Hour).Unix(), r[0], r[1], r[2])))
+		}
+		if err := updateDailyStatisticsFor(db, d.day); err != nil {
+			t.Fatalf("updateDailyStatisticsFor: %v", err)
+		}
+	}
+
+	rows := queryRows(t, db, `SELECT date, change_index FROM weather_daily ORDER BY date`)
+	want := []map[string]string{
+		{"date": "2024-06-01", "change_index": "5"},
+		{"date": "2024-06-02", "change_index": "98"},
+	}
+	if fmt.Sprint(rows) != fmt.Sprint(want) {
+		t.Errorf("weather_daily = %v, want %v", rows, want)
+	}
+}
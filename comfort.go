@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Comfort band labels
+const (
+	ComfortCold        = "cold"
+	ComfortCool        = "cool"
+	ComfortComfortable = "comfortable"
+	ComfortWarm        = "warm"
+	ComfortHot         = "hot"
+)
+
+// ComfortThresholds are the upper feels-like bounds (°C, exclusive) of the
+// bands; everything at or above WarmMax is hot
+type ComfortThresholds struct {
+	ColdMax        float64
+	CoolMax        float64
+	ComfortableMax float64
+	WarmMax        float64
+}
+
+// comfortBand assigns a feels-like temperature to a band
+func comfortBand(apparent float64, t ComfortThresholds) string {
+	switch {
+	case apparent < t.ColdMax:
+		return ComfortCold
+	case apparent < t.CoolMax:
+		return ComfortCool
+	case apparent < t.ComfortableMax:
+		return ComfortComfortable
+	case apparent < t.WarmMax:
+		return ComfortWarm
+	default:
+		return ComfortHot
+	}
+}
+
+// comfortThresholds returns the band thresholds from the config
+func comfortThresholds() ComfortThresholds {
+	return ComfortThresholds{
+		ColdMax:        config.ComfortColdMax,
+		CoolMax:        config.ComfortCoolMax,
+		ComfortableMax: config.ComfortComfortableMax,
+		WarmMax:        config.ComfortWarmMax,
+	}
+}
+
+// updateDailyComfortBands classifies every hourly average of an observation
+// day by its feels-like temperature and stores the number of hours in each
+// band
+func updateDailyComfortBands(db dbExecutor, date string) error {
+	hours, args, err := observationDayHours(date)
+	if err != nil {
+		return err
+	}
+	rows, err := db.Query(`SELECT avg_temperature, avg_humidity FROM weather_hourly
+		WHERE `+hours+` AND avg_temperature IS NOT NULL AND avg_humidity IS NOT NULL`+stationFilter(db), args...)
+	if err != nil {
+		return fmt.Errorf("failed to query hourly averages: %w", err)
+	}
+	defer rows.Close()
+
+	thresholds := comfortThresholds()
+	counts := make(map[string]int)
+	for rows.Next() {
+		var temperature, humidity float64
+		if err := rows.Scan(&temperature, &humidity); err != nil {
+			return fmt.Errorf("failed to scan hourly average: %w", err)
+		}
+		counts[comfortBand(feelsLike(temperature, humidity), thresholds)]++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate hourly averages: %w", err)
+	}
+
+	slog.Info("Comfort hours", "component", "stats", "date", date,
+		ComfortCold, counts[ComfortCold], ComfortCool, counts[ComfortCool], ComfortComfortable, counts[ComfortComfortable],
+		ComfortWarm, counts[ComfortWarm], ComfortHot, counts[ComfortHot])
+
+	update := `
+		UPDATE weather_daily
+		SET comfort_cold_hours = ?, comfort_cool_hours = ?, comfort_comfortable_hours = ?,
+			comfort_warm_hours = ?, comfort_hot_hours = ?
+		WHERE date = ?` + stationFilter(db) + `
+	`
+	_, err = db.Exec(update,
+		counts[ComfortCold], counts[ComfortCool], counts[ComfortComfortable],
+		counts[ComfortWarm], counts[ComfortHot], date)
+	if err != nil {
+		return fmt.Errorf("failed to store comfort hours: %w", err)
+	}
+	return nil
+}
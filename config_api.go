@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// secretFieldPattern matches Config fields whose values are never exposed
+var secretFieldPattern = regexp.MustCompile(`(Password|Token|Secret)$`)
+
+const redacted = "[REDACTED]"
+
+// requireAPIToken rejects requests without a matching "Authorization:
+// Bearer <API_TOKEN>" header
+func requireAPIToken(next http.HandlerFunc) http.HandlerFunc {
+	return requireBearerToken(config.APIToken, next)
+}
+
+// requireBearerToken rejects requests whose bearer token differs from
+// expected with 401
+func requireBearerToken(expected string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// redactedConfig returns the effective configuration with secrets removed.
+// Password/token/secret fields are replaced entirely and URL fields are
+// reduced to scheme and host, since webhook URLs carry credentials in the
+// path or query. URL list fields are redacted entry by entry.
+func redactedConfig(c Config) map[string]any {
+	out := make(map[string]any)
+
+	v := reflect.ValueOf(c)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		value := v.Field(i).Interface()
+
+		switch {
+		case secretFieldPattern.MatchString(name):
+			if !v.Field(i).IsZero() {
+				value = redacted
+			}
+		case strings.HasSuffix(name, "URL"):
+			if s, ok := value.(string); ok && s != "" {
+				value = redactURL(s)
+			}
+		case strings.HasSuffix(name, "URLs"):
+			if s, ok := value.(string); ok && s != "" {
+				value = redactURLList(s)
+			}
+		}
+
+		if loc, ok := value.(*time.Location); ok {
+			value = loc.String()
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// redactURL keeps only the scheme and host of a URL
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
+
+// redactURLList redacts each URL of a comma-separated list, keeping the
+// station= prefix of JSON_SOURCE_URLS entries
+func redactURLList(raw string) string {
+	entries := strings.Split(raw, ",")
+	for i, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if station, u, ok := strings.Cut(entry, "="); ok && !strings.Contains(station, "://") {
+			entries[i] = station + "=" + redactURL(u)
+		} else {
+			entries[i] = redactURL(entry)
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+func configHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, redactedConfig(config))
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestRedactedConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		set   func(c *Config)
+		want  any
+	}{
+		{"password", "DBPassword", func(c *Config) { c.DBPassword = "hunter2" }, redacted},
+		{"empty password", "DBPassword", func(c *Config) { c.DBPassword = "" }, ""},
+		{"url", "AlertWebhookURL", func(c *Config) { c.AlertWebhookURL = "https://hooks.example.com/T0/B1/secret" },
+			"https://hooks.example.com/" + redacted},
+		{"source urls", "JSONSourceURLs", func(c *Config) {
+			c.JSONSourceURLs = "garden=http://user:pw@10.0.0.5/weather.json?key=abc, roof=http://10.0.0.6/w.json"
+		}, "garden=http://10.0.0.5/" + redacted + ",roof=http://10.0.0.6/" + redacted},
+		{"source url without station", "JSONSourceURLs", func(c *Config) { c.JSONSourceURLs = "http://10.0.0.5/w.json?token=x" },
+			"http://10.0.0.5/" + redacted},
+		{"unparsable source url", "JSONSourceURLs", func(c *Config) { c.JSONSourceURLs = "garden=not a url" },
+			"garden=" + redacted},
+		{"empty source urls", "JSONSourceURLs", func(c *Config) { c.JSONSourceURLs = "" }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Config
+			tt.set(&c)
+			if got := redactedConfig(c)[tt.field]; got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.field, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// CurrentConditions is the latest reading served by /api/current.
+// CarriedForward is set when the source file is missing and the last known
+// reading is shown instead; MeasuredAt always keeps its original time.
+// Values that held a SENSOR_SENTINELS value are null.
+type CurrentConditions struct {
+	MeasuredAt     time.Time `json:"measured_at"`
+	Temperature    *float64  `json:"temperature"`
+	Pressure       *float64  `json:"pressure"`
+	Humidity       *float64  `json:"humidity"`
+	CarriedForward bool      `json:"carried_forward"`
+	AgeSeconds     int64     `json:"age_seconds"`
+}
+
+// currentCache holds the last reading for the API only; it never feeds the
+// stored aggregates
+type currentCache struct {
+	mu      sync.Mutex
+	reading *WeatherData
+	missing bool
+}
+
+var current currentCache
+
+// Set stores a freshly read reading
+func (c *currentCache) Set(data WeatherData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reading = &data
+	c.missing = false
+}
+
+// MarkMissing records that the source file could not be found
+func (c *currentCache) MarkMissing() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.missing = true
+}
+
+// Get returns the current conditions, or nil when there is nothing to show.
+// While the file is missing the last reading is carried forward only with
+// CARRY_FORWARD_ON_MISSING and only until it is older than the maximum age.
+func (c *currentCache) Get(at time.Time) *CurrentConditions {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reading == nil {
+		return nil
+	}
+
+	measuredAt := time.Unix(c.reading.Timestamp, 0).In(config.Location)
+	age := at.Sub(measuredAt)
+	if c.missing {
+		maxAge := time.Duration(config.CarryForwardMaxAgeMin) * time.Minute
+		if !config.CarryForwardOnMissing || age > maxAge {
+			return nil
+		}
+	}
+
+	return &CurrentConditions{
+		MeasuredAt:     measuredAt,
+		Temperature:    presentValue(*c.reading, "temperature", roundDisplay(c.reading.Temperature)),
+		Pressure:       presentValue(*c.reading, "pressure", roundDisplay(c.reading.Pressure)),
+		Humidity:       presentValue(*c.reading, "humidity", roundDisplay(c.reading.Humidity)),
+		CarriedForward: c.missing,
+		AgeSeconds:     int64(age.Seconds()),
+	}
+}
+
+// noteReadError marks the current reading as missing when the source file
+// does not exist (e.g. mid-rotation)
+func noteReadError(err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		current.MarkMissing()
+	}
+}
+
+// ------------------------- CURRENT ------------------------------
+func currentHandler(w http.ResponseWriter, r *http.Request) {
+	conditions := current.Get(now())
+	if conditions == nil {
+		writeError(w, http.StatusServiceUnavailable, "no current reading available")
+		return
+	}
+	writeJSON(w, http.StatusOK, conditions)
+}
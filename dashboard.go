@@ -0,0 +1,18 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// dashboardHTML is a self-contained page rendering /api/current and the
+// last 24 hours from /api/since
+//
+//go:embed dashboard/index.html
+var dashboardHTML []byte
+
+// ------------------------- DASHBOARD ------------------------------
+func dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
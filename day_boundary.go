@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// An observation day runs from DAY_BOUNDARY_HOUR to DAY_BOUNDARY_HOUR the
+// next day and is named after the calendar day on which it starts. With the
+// boundary at 09:00, a reading taken at 08:00 belongs to the previous day.
+// Hourly aggregates always follow calendar hours.
+
+// observationDay is the SQL expression for the observation day of a reading
+func observationDay() string {
+	if config.DayBoundaryHour == 0 {
+		return "DATE(measured_at)"
+	}
+	return fmt.Sprintf("DATE(measured_at - INTERVAL %d HOUR)", config.DayBoundaryHour)
+}
+
+// observationDate is the observation day of t in YYYY-MM-DD. The boundary is
+// applied to the wall clock, like the INTERVAL arithmetic in observationDay.
+func observationDate(t time.Time) string {
+	shifted := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()-config.DayBoundaryHour,
+		t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	return shifted.Format("2006-01-02")
+}
+
+// observationDayStart returns the start of the observation day containing t,
+// the last DAY_BOUNDARY_HOUR on the wall clock at or before t. Its calendar
+// date is the observation date of t.
+func observationDayStart(t time.Time) time.Time {
+	start := time.Date(t.Year(), t.Month(), t.Day(), config.DayBoundaryHour, 0, 0, 0, t.Location())
+	if t.Before(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start
+}
+
+// observationDayBounds returns the start and end of the observation day
+// date (YYYY-MM-DD) in station time, honouring DAY_BOUNDARY_HOUR
+func observationDayBounds(date string) (time.Time, time.Time, error) {
+	day, err := time.ParseInLocation("2006-01-02", date, config.Location)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	start := time.Date(day.Year(), day.Month(), day.Day(), config.DayBoundaryHour, 0, 0, 0, config.Location)
+	return start, start.AddDate(0, 0, 1), nil
+}
+
+// observationDayHours returns a WHERE clause fragment selecting the
+// weather_hourly rows of the observation day date and its arguments. The
+// rows are keyed on the calendar date, so with DAY_BOUNDARY_HOUR the day
+// takes the hours from the boundary on and those before it on the next date.
+func observationDayHours(date string) (string, []any, error) {
+	start, end, err := observationDayBounds(date)
+	if err != nil {
+		return "", nil, err
+	}
+	if config.DayBoundaryHour == 0 {
+		return "date = ?", []any{date}, nil
+	}
+	return "((date = ? AND hour >= ?) OR (date = ? AND hour < ?))",
+		[]any{start.Format("2006-01-02"), config.DayBoundaryHour, end.Format("2006-01-02"), config.DayBoundaryHour}, nil
+}
+
+// statisticsSchedule returns the cron expression of a statistics job that
+// runs minute past the day boundary, once the observation day is complete
+func statisticsSchedule(minute int, dayOfMonth, month, dayOfWeek string) string {
+	return fmt.Sprintf("%d %d %s %s %s", minute, config.DayBoundaryHour, dayOfMonth, month, dayOfWeek)
+}
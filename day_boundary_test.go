@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestObservationDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		boundary int
+		at       time.Time
+		want     string
+	}{
+		{"midnight boundary", 0, time.Date(2024, 6, 2, 8, 0, 0, 0, time.UTC), "2024-06-02"},
+		{"08:00 before a 09:00 boundary", 9, time.Date(2024, 6, 2, 8, 0, 0, 0, time.UTC), "2024-06-01"},
+		{"08:59:59 before a 09:00 boundary", 9, time.Date(2024, 6, 2, 8, 59, 59, 0, time.UTC), "2024-06-01"},
+		{"09:00 at a 09:00 boundary", 9, time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC), "2024-06-02"},
+		{"23:00 after a 09:00 boundary", 9, time.Date(2024, 6, 2, 23, 0, 0, 0, time.UTC), "2024-06-02"},
+		{"across a month", 9, time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC), "2024-06-30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestConfig(t, map[string]string{"DAY_BOUNDARY_HOUR": fmt.Sprint(tt.boundary)})
+			if got := observationDate(tt.at); got != tt.want {
+				t.Errorf("observationDate() = %s, want %s", got, tt.want)
+			}
+			start := observationDayStart(tt.at)
+			if got := start.Format("2006-01-02"); got != tt.want {
+				t.Errorf("observationDayStart() = %s, want a start on %s", start, tt.want)
+			}
+			if start.Hour() != tt.boundary || start.After(tt.at) || !tt.at.Before(start.AddDate(0, 0, 1)) {
+				t.Errorf("observationDayStart() = %s does not start the day containing %s", start, tt.at)
+			}
+		})
+	}
+}
+
+func TestDayBoundaryAggregates(t *testing.T) {
+	setupTestConfig(t, map[string]string{"DAY_BOUNDARY_HOUR": "9", "ENABLE_RAINFALL": "true"})
+	db := newTestStore(t)
+
+	readings := []struct {
+		at     time.Time
+		rainMM float64
+	}{
+		{time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC), 1.0}, // observation day 2024-05-31
+		{time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC), 3.0},
+		{time.Date(2024, 6, 2, 8, 0, 0, 0, time.UTC), 5.0}, // still 2024-06-01
+		{time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC), 6.0}, // 2024-06-02
+	}
+	for _, r := range readings {
+		processFixture(t, db, []byte(fmt.Sprintf(
+			`{"timestamp": %d, "temperature": 15, "pressure": 1013, "humidity": 70, "rain_mm": %g}`, r.at.Unix(), r.rainMM)))
+	}
+	for _, date := range []string{"2024-06-01", "2024-06-02"} {
+		day, _ := time.ParseInLocation("2006-01-02", date, config.Location)
+		if err := updateDailyStatisticsFor(db, day); err != nil {
+			t.Fatalf("updateDailyStatisticsFor(%s): %v", date, err)
+		}
+	}
+
+	tests := []struct {
+		date          string
+		samplesCount  string
+		totalRain     string
+		calendarTotal string // what the calendar-midnight baseline gave
+	}{
+		// The baseline is the 08:30 reading before the 09:00 boundary
+		{"2024-06-01", "2", "4", "2"},
+		// The 08:00 reading of June 2 is the baseline, not a sample
+		{"2024-06-02", "1", "1", "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			rows := queryRows(t, db, `SELECT samples_count, total_rain FROM weather_daily WHERE date = ?`, tt.date)
+			if len(rows) != 1 {
+				t.Fatalf("got %d rows, want 1", len(rows))
+			}
+			if got := rows[0]["samples_count"]; got != tt.samplesCount {
+				t.Errorf("samples_count = %s, want %s", got, tt.samplesCount)
+			}
+			if got := rows[0]["total_rain"]; got != tt.totalRain {
+				t.Errorf("total_rain = %s, want %s (calendar midnight baseline gives %s)", got, tt.totalRain, tt.calendarTotal)
+			}
+		})
+	}
+}
+
+func TestLateReadingUsesObservationDay(t *testing.T) {
+	tests := []struct {
+		name      string
+		now       time.Time
+		reading   time.Time
+		wantDaily int
+	}{
+		{
+			// 23:00 on June 1 is after midnight-based "yesterday" but the
+			// observation day of June 1 runs until 09:00 and is not over
+			name:      "reading of the ongoing observation day",
+			now:       time.Date(2024, 6, 2, 8, 30, 0, 0, time.UTC),
+			reading:   time.Date(2024, 6, 1, 23, 0, 0, 0, time.UTC),
+			wantDaily: 0,
+		},
+		{
+			name:      "08:00 reading arriving after the 09:00 boundary",
+			now:       time.Date(2024, 6, 2, 10, 0, 0, 0, time.UTC),
+			reading:   time.Date(2024, 6, 2, 8, 0, 0, 0, time.UTC),
+			wantDaily: 1,
+		},
+		{
+			name:      "09:00 reading of the current observation day",
+			now:       time.Date(2024, 6, 2, 10, 0, 0, 0, time.UTC),
+			reading:   time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC),
+			wantDaily: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestConfig(t, map[string]string{"DAY_BOUNDARY_HOUR": "9", "RECOMPUTE_LATE_DATA": "true"})
+			setTestNow(t, tt.now)
+			db := newTestStore(t)
+
+			processFixture(t, db, []byte(fmt.Sprintf(
+				`{"timestamp": %d, "temperature": 15, "pressure": 1013, "humidity": 70}`, tt.reading.Unix())))
+
+			rows := queryRows(t, db, `SELECT date, samples_count FROM weather_daily`)
+			if len(rows) != tt.wantDaily {
+				t.Fatalf("got daily rows %v, want %d", rows, tt.wantDaily)
+			}
+			if tt.wantDaily > 0 && rows[0]["date"] != "2024-06-01" {
+				t.Errorf("recomputed %s, want 2024-06-01", rows[0]["date"])
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Derived columns are defined as DERIVED_EXPR_<column>=<expression>, e.g.
+// DERIVED_EXPR_feels_like=temperature - 0.2*humidity. Expressions may use
+// numbers, the reading's numeric fields, + - * / ^, parentheses and the
+// functions in derivedFunctions; anything else is rejected at startup.
+
+const derivedExprPrefix = "DERIVED_EXPR_"
+
+// DerivedColumn is a validated derived column definition
+type DerivedColumn struct {
+	Name string
+	Expr string
+	root exprNode
+}
+
+// derivedColumns holds the DERIVED_EXPR_* definitions, sorted by name
+var derivedColumns []DerivedColumn
+
+// derivedFunctions are the functions available in expressions, with arity
+var derivedFunctions = map[string]struct {
+	arity int
+	fn    func(args []float64) float64
+}{
+	"abs":   {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"sqrt":  {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"exp":   {1, func(a []float64) float64 { return math.Exp(a[0]) }},
+	"ln":    {1, func(a []float64) float64 { return math.Log(a[0]) }},
+	"log10": {1, func(a []float64) float64 { return math.Log10(a[0]) }},
+	"round": {1, func(a []float64) float64 { return math.Round(a[0]) }},
+	"pow":   {2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"min":   {2, func(a []float64) float64 { return math.Min(a[0], a[1]) }},
+	"max":   {2, func(a []float64) float64 { return math.Max(a[0], a[1]) }},
+}
+
+// errMissingField means a field used by an expression is absent from the
+// reading; the derived value is then stored as NULL
+var errMissingField = errors.New("field missing in reading")
+
+// readingFields returns the numeric fields of a reading available to
+// expressions; optional fields the reading lacks and fields marked missing
+// are nil
+func readingFields(d WeatherData) map[string]*float64 {
+	fields := map[string]*float64{
+		"temperature":          &d.Temperature,
+		"pressure":             &d.Pressure,
+		"humidity":             &d.Humidity,
+		"temperature_elevated": d.TemperatureElevated,
+		"wind_speed":           &d.WindSpeed,
+		"wind_direction":       &d.WindDirection,
+		"solar_radiation":      d.SolarRadiation,
+		"rain_mm":              d.RainMM,
+		"rain_rate":            d.RainRate,
+		"co2":                  d.CO2,
+		"pm25":                 d.PM25,
+		"pm10":                 d.PM10,
+	}
+	for _, name := range extraMetrics {
+		fields[name] = d.Extra[name]
+	}
+	for name := range d.Missing {
+		fields[name] = nil
+	}
+	return fields
+}
+
+// parseDerivedColumns collects and validates the DERIVED_EXPR_* variables
+// from environ (as returned by os.Environ)
+func parseDerivedColumns(environ []string) ([]DerivedColumn, error) {
+	known := readingFields(WeatherData{})
+	var columns []DerivedColumn
+	for _, kv := range environ {
+		key, expr, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, derivedExprPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(key, derivedExprPrefix)
+		if !extraMetricName.MatchString(name) {
+			return nil, fmt.Errorf("invalid derived column name %q, use lowercase letters, digits and underscores", name)
+		}
+		if _, clash := known[name]; clash || reservedMetricNames[name] {
+			return nil, fmt.Errorf("derived column %q clashes with a reading field", name)
+		}
+
+		root, err := parseExpr(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if err := root.check(known); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		columns = append(columns, DerivedColumn{Name: name, Expr: expr, root: root})
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].Name < columns[j].Name })
+	return columns, nil
+}
+
+// Value evaluates the column for a reading; nil when a referenced field is
+// missing or the result is not a finite number
+func (c DerivedColumn) Value(d WeatherData) *float64 {
+	v, err := c.root.eval(readingFields(d))
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// exprNode is a node of a parsed expression
+type exprNode interface {
+	eval(fields map[string]*float64) (float64, error)
+	// check reports references to unknown fields
+	check(fields map[string]*float64) error
+}
+
+type numberNode float64
+
+func (n numberNode) eval(map[string]*float64) (float64, error) { return float64(n), nil }
+func (n numberNode) check(map[string]*float64) error           { return nil }
+
+type fieldNode string
+
+func (n fieldNode) eval(fields map[string]*float64) (float64, error) {
+	v := fields[string(n)]
+	if v == nil {
+		return 0, fmt.Errorf("%w: %s", errMissingField, n)
+	}
+	return *v, nil
+}
+
+func (n fieldNode) check(fields map[string]*float64) error {
+	if _, ok := fields[string(n)]; !ok {
+		return fmt.Errorf("unknown field %q", string(n))
+	}
+	return nil
+}
+
+type negNode struct{ x exprNode }
+
+func (n negNode) eval(fields map[string]*float64) (float64, error) {
+	v, err := n.x.eval(fields)
+	return -v, err
+}
+
+func (n negNode) check(fields map[string]*float64) error { return n.x.check(fields) }
+
+type binaryNode struct {
+	op   byte
+	l, r exprNode
+}
+
+func (n binaryNode) eval(fields map[string]*float64) (float64, error) {
+	l, err := n.l.eval(fields)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.r.eval(fields)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	case '/':
+		return l / r, nil
+	default:
+		return math.Pow(l, r), nil
+	}
+}
+
+func (n binaryNode) check(fields map[string]*float64) error {
+	if err := n.l.check(fields); err != nil {
+		return err
+	}
+	return n.r.check(fields)
+}
+
+type callNode struct {
+	name string
+	args []exprNode
+}
+
+func (n callNode) eval(fields map[string]*float64) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(fields)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	return derivedFunctions[n.name].fn(args), nil
+}
+
+func (n callNode) check(fields map[string]*float64) error {
+	for _, arg := range n.args {
+		if err := arg.check(fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exprParser is a recursive descent parser over the grammar
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | power
+//	power   = primary [ "^" unary ]
+//	primary = number | field | function "(" expr { "," expr } ")" | "(" expr ")"
+type exprParser struct {
+	src string
+	pos int
+}
+
+// parseExpr parses an expression; function names and arities are checked,
+// field names are left to exprNode.check
+func parseExpr(src string) (exprNode, error) {
+	p := &exprParser{src: src}
+	node, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.src[p.pos], p.pos+1)
+	}
+	return node, nil
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// accept consumes c if it is the next non-space character
+func (p *exprParser) accept(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expr() (exprNode, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		switch {
+		case p.accept('+'):
+			op = '+'
+		case p.accept('-'):
+			op = '-'
+		default:
+			return left, nil
+		}
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, l: left, r: right}
+	}
+}
+
+func (p *exprParser) term() (exprNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		switch {
+		case p.accept('*'):
+			op = '*'
+		case p.accept('/'):
+			op = '/'
+		default:
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, l: left, r: right}
+	}
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	if p.accept('-') {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{x}, nil
+	}
+	return p.power()
+}
+
+func (p *exprParser) power() (exprNode, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept('^') {
+		exp, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: '^', l: base, r: exp}, nil
+	}
+	return base, nil
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, errors.New("unexpected end of expression")
+	}
+
+	if p.accept('(') {
+		node, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(')') {
+			return nil, fmt.Errorf("missing ')' at position %d", p.pos+1)
+		}
+		return node, nil
+	}
+
+	start := p.pos
+	c := rune(p.src[p.pos])
+	switch {
+	case unicode.IsDigit(c) || c == '.':
+		for p.pos < len(p.src) && (unicode.IsDigit(rune(p.src[p.pos])) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", p.src[start:p.pos])
+		}
+		return numberNode(v), nil
+
+	case unicode.IsLetter(c) || c == '_':
+		for p.pos < len(p.src) && (unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos])) || p.src[p.pos] == '_') {
+			p.pos++
+		}
+		name := p.src[start:p.pos]
+		if !p.accept('(') {
+			return fieldNode(name), nil
+		}
+		return p.call(name)
+	}
+
+	return nil, fmt.Errorf("unexpected %q at position %d", p.src[p.pos], p.pos+1)
+}
+
+// call parses the arguments of a function call after the opening parenthesis
+func (p *exprParser) call(name string) (exprNode, error) {
+	f, ok := derivedFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+
+	var args []exprNode
+	for {
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(')') {
+			break
+		}
+		if !p.accept(',') {
+			return nil, fmt.Errorf("expected ',' or ')' at position %d", p.pos+1)
+		}
+	}
+	if len(args) != f.arity {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", name, f.arity, len(args))
+	}
+	return callNode{name: name, args: args}, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseExprEval(t *testing.T) {
+	reading := WeatherData{Temperature: 20, Pressure: 1013, Humidity: 50}
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		{"42", 42},
+		{"0.5", 0.5},
+		{"temperature - 0.2*humidity", 10},
+		{"2 + 3 * 4", 14},
+		{"(2 + 3) * 4", 20},
+		{"10 - 4 - 3", 3},
+		{"12 / 4 / 3", 1},
+		{"10 / 4", 2.5},
+		{"-2^2", -4},
+		{"2^3^2", 512},
+		{"2^-1", 0.5},
+		{"--temperature", 20},
+		{"sqrt(16) + abs(-3)", 7},
+		{"pow(2, 10)", 1024},
+		{"max(temperature, pressure / 100)", 20},
+		{"min(temperature, pressure / 100)", 10.13},
+		{"round(2.5)", 3},
+		{"ln(exp(1))", 1},
+		{"log10(1000)", 3},
+		{"  temperature\t*2 ", 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			root, err := parseExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("parseExpr(%q): %v", tt.expr, err)
+			}
+			got, err := root.eval(readingFields(reading))
+			if err != nil {
+				t.Fatalf("eval(%q): %v", tt.expr, err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("eval(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"", "unexpected end of expression"},
+		{"1 +", "unexpected end of expression"},
+		{"(1 + 2", "missing ')'"},
+		{"1 2", "unexpected '2' at position 3"},
+		{"temperature $ 2", "unexpected '$'"},
+		{"1..2", "invalid number"},
+		{"foo(1)", `unknown function "foo"`},
+		{"pow(1)", "pow takes 2 argument(s), got 1"},
+		{"sqrt(1, 2)", "sqrt takes 1 argument(s), got 2"},
+		{"max(1 2)", "expected ',' or ')'"},
+		{"sqrt()", "unexpected ')'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := parseExpr(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseExpr(%q) error = %v, want %q", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseDerivedColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		want    []string
+		wantErr string
+	}{
+		{"sorted by name", []string{"DERIVED_EXPR_b=temperature", "PATH=/bin", "DERIVED_EXPR_a=humidity / 100"}, []string{"a", "b"}, ""},
+		{"unknown field", []string{"DERIVED_EXPR_x=temperatur * 2"}, nil, `unknown field "temperatur"`},
+		{"unknown field in a call", []string{"DERIVED_EXPR_x=max(1, windspeed)"}, nil, `unknown field "windspeed"`},
+		{"clash with a field", []string{"DERIVED_EXPR_humidity=humidity * 2"}, nil, "clashes with a reading field"},
+		{"invalid name", []string{"DERIVED_EXPR_Feels=temperature"}, nil, "invalid derived column name"},
+		{"syntax error", []string{"DERIVED_EXPR_x=temperature *"}, nil, "DERIVED_EXPR_x: unexpected end"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := parseDerivedColumns(tt.environ)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseDerivedColumns() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, c := range columns {
+				names = append(names, c.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("columns = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestDerivedColumnValue(t *testing.T) {
+	co2 := 420.0
+	tests := []struct {
+		name    string
+		expr    string
+		reading WeatherData
+		want    *float64
+	}{
+		{"present fields", "co2 / 10 + temperature", WeatherData{Temperature: 20, CO2: &co2}, ptr(62.0)},
+		{"optional field absent", "co2 / 10", WeatherData{Temperature: 20}, nil},
+		{"field marked missing", "temperature * 2", WeatherData{Temperature: 20, Missing: map[string]bool{"temperature": true}}, nil},
+		{"division by zero", "temperature / 0", WeatherData{Temperature: 20}, nil},
+		{"not a number", "sqrt(-1)", WeatherData{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := parseDerivedColumns([]string{"DERIVED_EXPR_x=" + tt.expr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := columns[0].Value(tt.reading)
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil || math.Abs(*got-*tt.want) > 1e-9:
+				t.Errorf("Value() = %v, want %v", fmtPtr(got), fmtPtr(tt.want))
+			}
+		})
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
+
+func fmtPtr(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,21 @@
+package main
+
+import "math"
+
+// Magnus formula coefficients (Sonntag 1990), valid for -45..60 °C
+const (
+	magnusA = 17.62
+	magnusB = 243.12
+)
+
+// minDewPointHumidity is the humidity floor for the dew point; at 0 % the
+// logarithm would be -Inf
+const minDewPointHumidity = 1.0
+
+// dewPoint returns the dew point in °C for a temperature in °C and relative
+// humidity in percent, clamped to 1..100 %
+func dewPoint(tempC, humidity float64) float64 {
+	rh := math.Max(minDewPointHumidity, math.Min(humidity, 100))
+	gamma := math.Log(rh/100) + magnusA*tempC/(magnusB+tempC)
+	return magnusB * gamma / (magnusA - gamma)
+}
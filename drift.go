@@ -0,0 +1,162 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// Sensor drift is tracked as the anomaly of each monthly average against the
+// climatological normal of that calendar month (the mean of the same month in
+// all previous years). A steady trend in the anomalies over recent months
+// points to a drifting sensor rather than to weather.
+
+// driftMetric is a weather_monthly column checked for drift
+type driftMetric struct {
+	Name      string
+	Column    string
+	Threshold float64 // absolute trend per month that triggers an alert
+}
+
+// driftMetrics lists the checked columns; only these names reach SQL
+func driftMetrics() []driftMetric {
+	return []driftMetric{
+		{"temperature", "avg_temperature", config.DriftThresholdTemperature},
+		{"pressure", "avg_pressure", config.DriftThresholdPressure},
+		{"humidity", "avg_humidity", config.DriftThresholdHumidity},
+	}
+}
+
+// linearTrend returns the least-squares slope of equally spaced values
+func linearTrend(values []float64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return 0
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range values {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	return (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+}
+
+// updateMonthlyDrift stores the anomaly of one month against its normal and
+// the trend of the anomalies over the last DRIFT_TREND_MONTHS months
+func updateMonthlyDrift(db dbExecutor, year int, m time.Month) error {
+	for _, metric := range driftMetrics() {
+		if err := updateMetricDrift(db, year, int(m), metric); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func updateMetricDrift(db dbExecutor, year, month int, metric driftMetric) error {
+	var normal sql.NullFloat64
+	var normalYears int
+
+	// metric.Column comes from driftMetrics, never from user input
+	normalQuery := fmt.Sprintf(`
+		SELECT AVG(%s), COUNT(%s)
+		FROM weather_monthly
+		WHERE month = ? AND year < ?%s
+	`, metric.Column, metric.Column, stationFilter(db))
+	if err := db.QueryRow(normalQuery, month, year).Scan(&normal, &normalYears); err != nil {
+		return fmt.Errorf("failed to query %s normal: %w", metric.Name, err)
+	}
+	if !normal.Valid {
+		slog.Info("No normal for the month yet, skipping drift", "component", "stats",
+			"metric", metric.Name, "month", month)
+		return nil
+	}
+
+	var value sql.NullFloat64
+	valueQuery := fmt.Sprintf(`SELECT %s FROM weather_monthly WHERE year = ? AND month = ?%s`, metric.Column, stationFilter(db))
+	if err := db.QueryRow(valueQuery, year, month).Scan(&value); err != nil {
+		return fmt.Errorf("failed to query %s monthly average: %w", metric.Name, err)
+	}
+	if !value.Valid {
+		slog.Info("No samples, skipping drift", "component", "stats",
+			"metric", metric.Name, "year", year, "month", month)
+		return nil
+	}
+
+	anomaly := roundAggregate(value.Float64 - normal.Float64)
+
+	upsert := `
+		INSERT INTO weather_drift (station_id, year, month, metric, normal, anomaly, normal_years)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			normal = VALUES(normal),
+			anomaly = VALUES(anomaly),
+			normal_years = VALUES(normal_years),
+			updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := db.Exec(upsert, stationOf(db), year, month, metric.Name, roundAggregate(normal.Float64), anomaly, normalYears)
+	if err != nil {
+		return fmt.Errorf("failed to upsert %s drift: %w", metric.Name, err)
+	}
+
+	anomalies, err := recentAnomalies(db, year, month, metric.Name)
+	if err != nil {
+		return err
+	}
+	if len(anomalies) < 3 {
+		return nil
+	}
+
+	trend := linearTrend(anomalies)
+	_, err = db.Exec(`UPDATE weather_drift SET trend_per_month = ? WHERE year = ? AND month = ? AND metric = ?`+stationFilter(db),
+		math.Round(trend*1000)/1000, year, month, metric.Name)
+	if err != nil {
+		return fmt.Errorf("failed to store %s drift trend: %w", metric.Name, err)
+	}
+
+	if math.Abs(trend) > metric.Threshold {
+		slog.Warn("Possible sensor drift", "component", "stats", "station_id", stationOf(db),
+			"metric", metric.Name, "trend_per_month", math.Round(trend*1000)/1000,
+			"months", len(anomalies), "threshold", metric.Threshold)
+	}
+	return nil
+}
+
+// recentAnomalies returns the stored anomalies of a metric for the months up
+// to and including year/month, oldest first
+func recentAnomalies(db dbExecutor, year, month int, metric string) ([]float64, error) {
+	query := `
+		SELECT anomaly FROM weather_drift
+		WHERE metric = ? AND year * 12 + month <= ?` + stationFilter(db) + `
+		ORDER BY year DESC, month DESC
+		LIMIT ?
+	`
+
+	rows, err := db.Query(query, metric, year*12+month, config.DriftTrendMonths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s anomalies: %w", metric, err)
+	}
+	defer rows.Close()
+
+	var anomalies []float64
+	for rows.Next() {
+		var a float64
+		if err := rows.Scan(&a); err != nil {
+			return nil, fmt.Errorf("failed to scan anomaly: %w", err)
+		}
+		anomalies = append(anomalies, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate anomalies: %w", err)
+	}
+
+	for i, j := 0, len(anomalies)-1; i < j; i, j = i+1, j-1 {
+		anomalies[i], anomalies[j] = anomalies[j], anomalies[i]
+	}
+	return anomalies, nil
+}
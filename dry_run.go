@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// With DRY_RUN the pool is built on dryRunConnector: reads, transactions and
+// pings reach the database as usual, while every Exec is logged with its
+// arguments and reported as done without touching the database. The sinks
+// outside the database check dryRunSkip before writing.
+
+// dryRunSkip reports whether a write to sink must be skipped because of
+// DRY_RUN, and then logs what would have been written
+func dryRunSkip(sink string, attrs ...any) bool {
+	if !config.DryRun {
+		return false
+	}
+	slog.Info("Dry run, write skipped", append([]any{"component", sink}, attrs...)...)
+	return true
+}
+
+// dryRunConnector opens connections of base wrapped in dryRunConn
+type dryRunConnector struct {
+	base driver.Driver
+	dsn  string
+}
+
+func (c dryRunConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.base.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return dryRunConn{conn}, nil
+}
+
+func (c dryRunConnector) Driver() driver.Driver {
+	return c.base
+}
+
+// dryRunResult is the result of a statement that was not executed
+type dryRunResult struct{}
+
+func (dryRunResult) LastInsertId() (int64, error) { return 0, nil }
+func (dryRunResult) RowsAffected() (int64, error) { return 0, nil }
+
+// errDryRunWrite rejects a query that would write, such as INSERT ...
+// RETURNING; callers use Exec instead in dry-run mode
+var errDryRunWrite = errors.New("write query attempted in dry-run mode")
+
+// dryRunConn passes reads through to the driver connection and only logs
+// writes
+type dryRunConn struct {
+	driver.Conn
+}
+
+func (c dryRunConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	values := make([]any, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	slog.Info("Dry run, statement not executed", "component", "db",
+		"query", strings.Join(strings.Fields(query), " "), "args", values)
+	return dryRunResult{}, nil
+}
+
+func (c dryRunConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if isWriteQuery(query) {
+		return nil, errDryRunWrite
+	}
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c dryRunConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if isWriteQuery(query) {
+		return nil, errDryRunWrite
+	}
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c dryRunConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c dryRunConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c dryRunConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c dryRunConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// isWriteQuery reports whether query starts with a data-modifying keyword
+func isWriteQuery(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "INSERT", "UPDATE", "DELETE", "REPLACE", "CREATE", "ALTER", "DROP", "TRUNCATE":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestDryRunSinks(t *testing.T) {
+	reading := WeatherData{Timestamp: 1717228800, Temperature: 18.5, Pressure: 1013, Humidity: 60}
+
+	// Each sink writes once and reports how many writes reached it
+	sinks := []struct {
+		name  string
+		write func(t *testing.T) int
+	}{
+		{"static json", func(t *testing.T) int {
+			path := filepath.Join(t.TempDir(), "current.json")
+			if err := writeFileAtomic(path, []byte("{}")); err != nil {
+				t.Fatal(err)
+			}
+			return fileCount(path)
+		}},
+		{"textfile", func(t *testing.T) int {
+			config.TextfilePath = filepath.Join(t.TempDir(), "weather.prom")
+			writeTextfile()
+			return fileCount(config.TextfilePath)
+		}},
+		{"sqlite mirror", func(t *testing.T) int {
+			m, err := openSQLiteMirror(filepath.Join(t.TempDir(), "mirror.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+			if _, err := m.Save(reading); err != nil {
+				t.Fatal(err)
+			}
+			var n int
+			if err := m.db.QueryRow(`SELECT COUNT(*) FROM readings`).Scan(&n); err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}},
+		{"kafka", func(t *testing.T) int {
+			p := &KafkaPublisher{queue: make(chan kafka.Message, 1)}
+			p.Publish(StoredReading{MeasuredAt: measurementTime(reading.Timestamp)})
+			return len(p.queue)
+		}},
+		{"webhook", func(t *testing.T) int {
+			var posts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				posts.Add(1)
+			}))
+			defer server.Close()
+			if err := newThresholdAlerter(server.URL).post(ThresholdAlert{Breach: breachAboveMax}); err != nil {
+				t.Fatal(err)
+			}
+			return int(posts.Load())
+		}},
+		{"telegram", func(t *testing.T) int {
+			n := &TelegramNotifier{queue: make(chan string, 1)}
+			if err := n.Notify("Database unreachable"); err != nil {
+				t.Fatal(err)
+			}
+			return len(n.queue)
+		}},
+	}
+	for _, dryRun := range []bool{false, true} {
+		for _, s := range sinks {
+			t.Run(s.name+"/dry_run="+strconv.FormatBool(dryRun), func(t *testing.T) {
+				setupTestConfig(t, map[string]string{"DRY_RUN": strconv.FormatBool(dryRun)})
+				setTestNow(t, time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC))
+				want := 1
+				if dryRun {
+					want = 0
+				}
+				if got := s.write(t); got != want {
+					t.Errorf("%d writes, want %d", got, want)
+				}
+			})
+		}
+	}
+}
+
+// fileCount is 1 when path exists, 0 otherwise
+func fileCount(path string) int {
+	if _, err := os.Stat(path); err != nil {
+		return 0
+	}
+	return 1
+}
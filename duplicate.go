@@ -0,0 +1,62 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// Error codes of a unique index violation: MySQL ER_DUP_ENTRY and
+// PostgreSQL unique_violation
+const (
+	mysqlErrDuplicateEntry  = 1062
+	postgresUniqueViolation = "23505"
+)
+
+// readingExists reports whether a reading measured at the given Unix time is
+// already stored in the weather table. With a station ID only that station's
+// readings are considered.
+func readingExists(db dbExecutor, timestamp int64, stationID string) (bool, error) {
+	id, err := storedReadingID(db, timestamp, stationID)
+	return id != 0, err
+}
+
+// storedReadingID returns the ID of the stored reading measured at the given
+// Unix time, 0 when there is none
+func storedReadingID(db dbExecutor, timestamp int64, stationID string) (int64, error) {
+	// The local measured_at of the repeated DST hour matches the reading an
+	// hour earlier, so split hours compare the Unix time
+	column, value := "measured_at", any(measurementTime(timestamp))
+	if config.SplitDSTHours {
+		column, value = "measured_epoch", timestamp
+	}
+	query := `SELECT id FROM weather WHERE ` + column + ` = ?`
+	args := []any{value}
+	if stationID != "" {
+		query += ` AND station_id = ?`
+		args = append(args, stationID)
+	}
+
+	var id int64
+	err := db.QueryRow(query+` LIMIT 1`, args...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to check for duplicate reading: %w", err)
+	}
+	return id, nil
+}
+
+// isDuplicateEntry reports whether err is a unique index violation
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlErrDuplicateEntry
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == postgresUniqueViolation
+}
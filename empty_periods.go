@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hasEarlierData reports whether any reading exists before t. A period
+// without samples before the first reading ever (a newly installed station)
+// is expected; after that it indicates an outage.
+func hasEarlierData(db dbExecutor, t time.Time) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM weather WHERE measured_at < ?`+stationFilter(db)+`)`, t).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for earlier readings: %w", err)
+	}
+	return exists, nil
+}
+
+// alertEmptyDay fires the error notifier for a completed day without samples
+// unless the station had no data before it
+func alertEmptyDay(db dbExecutor, day time.Time) error {
+	start, _, err := observationDayBounds(day.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+	expected, err := hasEarlierData(db, start)
+	if err != nil {
+		return err
+	}
+	if expected {
+		notifyError(fmt.Sprintf("No readings at all on %s, possible extended outage", day.Format("2006-01-02")))
+	}
+	return nil
+}
+
+// alertEmptyHours fires the error notifier when some hours of a completed
+// day that otherwise has data have no samples
+func alertEmptyHours(db dbExecutor, date string) error {
+	rows, err := db.Query(`
+		SELECT DISTINCT HOUR(measured_at) FROM weather
+		WHERE `+observationDay()+` = ?`+stationFilter(db)+`
+	`, date)
+	if err != nil {
+		return fmt.Errorf("failed to query hours with samples: %w", err)
+	}
+	defer rows.Close()
+
+	seen := make(map[int]bool)
+	for rows.Next() {
+		var hour int
+		if err := rows.Scan(&hour); err != nil {
+			return fmt.Errorf("failed to scan hour: %w", err)
+		}
+		seen[hour] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate hours: %w", err)
+	}
+
+	var missing []string
+	for h := 0; h < 24; h++ {
+		if !seen[h] {
+			missing = append(missing, strconv.Itoa(h))
+		}
+	}
+	if len(missing) > 0 {
+		notifyError(fmt.Sprintf("No readings on %s in hours %s", date, strings.Join(missing, ", ")))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// utf8BOM is written by some Windows tools in front of UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// gzipMagic starts every gzip stream, e.g. weather.json.gz snapshots
+var gzipMagic = []byte{0x1F, 0x8B}
+
+// inputEncoding converts the source to UTF-8, nil when it already is UTF-8
+var inputEncoding encoding.Encoding
+
+// parseInputEncoding resolves INPUT_ENCODING (e.g. "latin1", "windows-1250").
+// UTF-8 needs no conversion and returns nil.
+func parseInputEncoding(name string) (encoding.Encoding, error) {
+	switch strings.ToLower(name) {
+	case "", "utf-8", "utf8":
+		return nil, nil
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	return enc, nil
+}
+
+// decodeInput decompresses gzip input, strips a leading BOM and transcodes
+// the raw JSON to UTF-8
+func decodeInput(data []byte) ([]byte, error) {
+	data, err := decompressInput(data)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if inputEncoding == nil {
+		return data, nil
+	}
+	decoded, err := inputEncoding.NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode input: %w", err)
+	}
+	return decoded, nil
+}
+
+// decompressInput inflates data that starts with the gzip magic header,
+// whatever the file is called; anything else is returned unchanged
+func decompressInput(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("corrupt gzip input: %w", err)
+	}
+	defer zr.Close()
+	inflated, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt gzip input: %w", err)
+	}
+	return inflated, nil
+}
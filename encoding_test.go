@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBOMFixtureIsStored(t *testing.T) {
+	setupTestConfig(t, nil)
+	db := newTestStore(t)
+
+	payload, err := os.ReadFile(filepath.Join("testdata", "weather_bom.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(payload, []byte("\xEF\xBB\xBF")) {
+		t.Fatal("weather_bom.json lost its byte order mark")
+	}
+
+	reading, err := parseReading(payload, config.StationID)
+	if err != nil {
+		t.Fatalf("parseReading: %v", err)
+	}
+	if reading.Timestamp != 1717236000 || reading.Temperature != 18.3 {
+		t.Errorf("parsed timestamp %d, temperature %v, want 1717236000, 18.3", reading.Timestamp, reading.Temperature)
+	}
+
+	processFixture(t, db, payload)
+	rows := queryRows(t, db, `SELECT temperature, pressure, humidity FROM weather`)
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	want := map[string]string{"temperature": "18.3", "pressure": "1011.2", "humidity": "72.5"}
+	for column, value := range want {
+		if rows[0][column] != value {
+			t.Errorf("%s = %q, want %q", column, rows[0][column], value)
+		}
+	}
+}
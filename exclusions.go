@@ -0,0 +1,141 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// exclusionFilter returns a WHERE clause fragment that drops readings inside
+// any range in weather_exclusions, or an empty string when exclusions are
+// disabled. It refers to the raw table as "weather".
+func exclusionFilter() string {
+	if !config.EnableExclusions {
+		return ""
+	}
+	return ` AND NOT EXISTS (
+			SELECT 1 FROM weather_exclusions e
+			WHERE weather.measured_at BETWEEN e.start_at AND e.end_at
+		)`
+}
+
+// parseExclusionRange parses "START/END" with both ends in RFC3339
+func parseExclusionRange(value string) (time.Time, time.Time, error) {
+	startStr, endStr, ok := strings.Cut(value, "/")
+	if !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("expected START/END, got %q", value)
+	}
+	start, err := time.Parse(time.RFC3339, startStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, endStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end: %w", err)
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end %s is before start %s", endStr, startStr)
+	}
+	return start, end, nil
+}
+
+// addExclusion stores an excluded time range
+func addExclusion(db *sql.DB, start, end time.Time, reason string) error {
+	_, err := db.Exec(`INSERT INTO weather_exclusions (start_at, end_at, reason) VALUES (?, ?, ?)`,
+		start, end, reason)
+	if err != nil {
+		return fmt.Errorf("failed to add exclusion: %w", err)
+	}
+	return nil
+}
+
+// recomputeRange recomputes every aggregate touched by the given time range
+// after readings in it were excluded, including the running totals
+func recomputeRange(db *sql.DB, start, end time.Time) error {
+	// Daily statistics may be read from the running totals
+	if config.UseRunningTotals {
+		if _, err := rebuildRunningTotals(db); err != nil {
+			return err
+		}
+	}
+	return forEachStation(db, func(db Store) error {
+		return recomputePeriods(db, start, end)
+	})
+}
+
+// recomputePeriods recomputes the aggregates of every period touched by the
+// given time range. Hourly rows are always recomputed, daily, weekly and
+// monthly rows only for periods that have already ended. Days are
+// observation days, starting at DAY_BOUNDARY_HOUR.
+func recomputePeriods(db Store, start, end time.Time) error {
+	start = start.In(config.Location)
+	end = end.In(config.Location)
+	today := observationDayStart(now().In(config.Location))
+	firstDay := observationDayStart(start)
+
+	// Hours and statistics of one day are committed together
+	for day := firstDay; !day.After(end); day = day.AddDate(0, 0, 1) {
+		err := withAggregateTx(db, func(db dbExecutor) error {
+			return recomputeDay(db, day, start, end, today)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	thisMonday := midnight(today).AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	firstMonday := midnight(firstDay).AddDate(0, 0, -(int(firstDay.Weekday())+6)%7)
+	for monday := firstMonday; !monday.After(end) && monday.Before(thisMonday); monday = monday.AddDate(0, 0, 7) {
+		err := withAggregateTx(db, func(db dbExecutor) error {
+			return updateWeeklyStatisticsFor(db, monday)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to recompute weekly statistics for %s: %w", monday.Format("2006-01-02"), err)
+		}
+	}
+
+	thisMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+	firstMonth := time.Date(firstDay.Year(), firstDay.Month(), 1, 0, 0, 0, 0, firstDay.Location())
+	for month := firstMonth; !month.After(end) && month.Before(thisMonth); month = month.AddDate(0, 1, 0) {
+		err := withAggregateTx(db, func(db dbExecutor) error {
+			return updateMonthlyStatisticsFor(db, month.Year(), month.Month())
+		})
+		if err != nil {
+			return fmt.Errorf("failed to recompute monthly statistics for %s: %w", month.Format("2006-01"), err)
+		}
+	}
+
+	slog.Info("Recomputed aggregates", "component", "stats", "from", start, "to", end)
+	return nil
+}
+
+// recomputeDay recomputes the hours of the observation day starting at day
+// that fall within [start, end] and, once the day is over, its daily
+// statistics
+func recomputeDay(db dbExecutor, day, start, end, today time.Time) error {
+	from := day
+	if from.Before(start) {
+		from = start.Truncate(time.Hour)
+	}
+	next := day.AddDate(0, 0, 1)
+	for hour := from; !hour.After(end) && hour.Before(next); hour = hour.Add(time.Hour) {
+		if err := updateHourlyAverages(db, hour.In(config.Location)); err != nil {
+			return err
+		}
+	}
+
+	if !day.Before(today) {
+		return nil
+	}
+	if err := updateDailyStatisticsFor(db, day); err != nil {
+		return fmt.Errorf("failed to recompute daily statistics for %s: %w", day.Format("2006-01-02"), err)
+	}
+	return nil
+}
+
+// midnight returns the start of t's day in t's location
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestExcludedSpikeNoLongerSetsDailyMax(t *testing.T) {
+	setupTestConfig(t, map[string]string{"ENABLE_EXCLUSIONS": "true"})
+	db := newTestStore(t)
+
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, config.Location)
+	readings := []struct {
+		hour        int
+		temperature float64
+	}{
+		{10, 20.0},
+		{12, 45.0}, // the sensor is being calibrated
+		{14, 22.5},
+	}
+	for _, r := range readings {
+		processFixture(t, db, []byte(fmt.Sprintf(
+			`{"timestamp": %d, "temperature": %.1f, "pressure": 1012.0, "humidity": 60.0}`,
+			day.Add(time.Duration(r.hour)*time.Hour).Unix(), r.temperature)))
+	}
+	if err := updateDailyStatisticsFor(db, day); err != nil {
+		t.Fatalf("updateDailyStatisticsFor: %v", err)
+	}
+	assertDailyMax(t, db, "45")
+
+	setTestNow(t, day.AddDate(0, 0, 1).Add(time.Hour))
+	start, end := day.Add(11*time.Hour+55*time.Minute), day.Add(12*time.Hour+5*time.Minute)
+	if err := addExclusion(db, start, end, "calibration"); err != nil {
+		t.Fatal(err)
+	}
+	if err := recomputeRange(db, start, end); err != nil {
+		t.Fatalf("recomputeRange: %v", err)
+	}
+	assertDailyMax(t, db, "22.5")
+}
+
+func assertDailyMax(t *testing.T, db dbExecutor, want string) {
+	t.Helper()
+	rows := queryRows(t, db, `SELECT max_temperature FROM weather_daily`)
+	if len(rows) != 1 {
+		t.Fatalf("got %d daily rows, want 1", len(rows))
+	}
+	if got := rows[0]["max_temperature"]; got != want {
+		t.Errorf("max_temperature = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+// The -export flag writes the rows of one table within a date range to CSV
+// for spreadsheets. Rows are streamed from the database to the file, so the
+// size of an export is not limited by memory.
+
+// exportQuery returns the query selecting the rows of table (raw, hourly,
+// daily, weekly or monthly) of the station of db between from and to,
+// inclusive, together with its arguments. Weekly and monthly rows are
+// included when their period overlaps the range.
+func exportQuery(db dbExecutor, table string, from, to time.Time) (string, []any, error) {
+	fromDate := from.Format("2006-01-02")
+	toDate := to.Format("2006-01-02")
+
+	switch table {
+	case "raw":
+		return `SELECT * FROM weather WHERE measured_at >= ? AND measured_at < ?` +
+				stationFilter(db) + ` ORDER BY measured_at, id`,
+			[]any{from, to.AddDate(0, 0, 1)}, nil
+	case "hourly":
+		order := "date, hour"
+		if config.SplitDSTHours {
+			order += ", utc_offset DESC"
+		}
+		return `SELECT * FROM weather_hourly WHERE date BETWEEN ? AND ?` + stationFilter(db) + ` ORDER BY ` + order,
+			[]any{fromDate, toDate}, nil
+	case "daily":
+		return `SELECT * FROM weather_daily WHERE date BETWEEN ? AND ?` + stationFilter(db) + ` ORDER BY date`,
+			[]any{fromDate, toDate}, nil
+	case "weekly":
+		return `SELECT * FROM weather_weekly WHERE week_end >= ? AND week_start <= ?` + stationFilter(db) + ` ORDER BY week_start`,
+			[]any{fromDate, toDate}, nil
+	case "monthly":
+		return `SELECT * FROM weather_monthly WHERE year * 100 + month BETWEEN ? AND ?` + stationFilter(db) + ` ORDER BY year, month`,
+			[]any{from.Year()*100 + int(from.Month()), to.Year()*100 + int(to.Month())}, nil
+	}
+	return "", nil, fmt.Errorf("unknown table %q, expected raw, hourly, daily, weekly or monthly", table)
+}
+
+// exportCSV writes the rows of table between from and to to out as CSV with
+// a header row of the column names; an empty out writes to stdout. It
+// returns the number of rows written.
+func exportCSV(db *sql.DB, table string, from, to time.Time, out string) (int, error) {
+	query, args, err := exportQuery(db, table, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s rows: %w", table, err)
+	}
+	defer rows.Close()
+
+	if out == "" {
+		return writeCSVRows(csv.NewWriter(os.Stdout), rows)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", out, err)
+	}
+	n, err := writeCSVRows(csv.NewWriter(f), rows)
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("failed to close %s: %w", out, cerr)
+	}
+	if err != nil {
+		// Do not leave a truncated file that looks like a complete export
+		os.Remove(out)
+	}
+	return n, err
+}
+
+// writeCSVRows writes the header and every row of rows to w
+func writeCSVRows(w *csv.Writer, rows *sql.Rows) (int, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read columns: %w", err)
+	}
+	header := make([]string, len(types))
+	for i, t := range types {
+		header[i] = t.Name()
+	}
+	if err := w.Write(header); err != nil {
+		return 0, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	values := make([]any, len(types))
+	dest := make([]any, len(types))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	record := make([]string, len(types))
+
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("failed to scan row: %w", err)
+		}
+		for i, v := range values {
+			record[i] = csvField(v, types[i].DatabaseTypeName())
+		}
+		if err := w.Write(record); err != nil {
+			return n, fmt.Errorf("failed to write CSV row: %w", err)
+		}
+		n++
+		if n%10000 == 0 {
+			slog.Info("Export progress", "component", "export", "rows", n)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return n, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return n, nil
+}
+
+// csvField formats a scanned value for a spreadsheet: NULL as an empty
+// field, DATE columns as YYYY-MM-DD and other times as YYYY-MM-DD HH:MM:SS
+// in TIMEZONE
+func csvField(v any, dbType string) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case time.Time:
+		if dbType == "DATE" {
+			return v.Format("2006-01-02")
+		}
+		return v.In(config.Location).Format("2006-01-02 15:04:05")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
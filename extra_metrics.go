@@ -0,0 +1,127 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Extra metrics are additional numeric fields of the weather JSON listed in
+// EXTRA_METRICS. Each metric m is stored in weather.m and aggregated into
+// avg_m in weather_hourly and avg_m/min_m/max_m in the daily, weekly and
+// monthly tables, so a new sensor only needs the columns, not code changes.
+
+var extraMetricName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// reservedMetricNames are fields and columns the processor already handles
+var reservedMetricNames = map[string]bool{
+	"id": true, "timestamp": true, "time": true, "ts": true, "measured_at": true, "source": true,
+	"temperature": true, "pressure": true, "humidity": true, "solar_radiation": true,
+	"co2": true, "pm25": true, "pm10": true, "rain_mm": true, "rain_rate": true,
+	"wind_speed": true, "wind_direction": true, "temperature_elevated": true, "dew_point": true,
+}
+
+// extraMetrics holds the validated EXTRA_METRICS names
+var extraMetrics []string
+
+// parseExtraMetrics parses a comma-separated metric list. Names double as
+// column names, so only lowercase identifiers are accepted.
+func parseExtraMetrics(value string) ([]string, error) {
+	var metrics []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !extraMetricName.MatchString(name) {
+			return nil, fmt.Errorf("invalid metric name %q, use lowercase letters, digits and underscores", name)
+		}
+		if reservedMetricNames[name] {
+			return nil, fmt.Errorf("metric %q is built in", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("metric %q listed twice", name)
+		}
+		seen[name] = true
+		metrics = append(metrics, name)
+	}
+	return metrics, nil
+}
+
+// extractExtraMetrics picks the configured metrics from a raw reading.
+// Missing or non-numeric fields are nil and stored as NULL.
+func extractExtraMetrics(raw []byte) map[string]*float64 {
+	values := make(map[string]*float64, len(extraMetrics))
+	if len(extraMetrics) == 0 {
+		return values
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return values
+	}
+	for _, m := range extraMetrics {
+		var v float64
+		if field, ok := fields[m]; ok && json.Unmarshal(field, &v) == nil {
+			values[m] = &v
+		}
+	}
+	return values
+}
+
+// updateExtraAggregates computes the extra metrics over the raw readings
+// matching rangeWhere and stores them in the row of table matching keyWhere.
+// withMinMax selects avg/min/max columns instead of avg only.
+func updateExtraAggregates(db dbExecutor, table string, withMinMax bool,
+	rangeWhere string, rangeArgs []any, keyWhere string, keyArgs []any) error {
+	if len(extraMetrics) == 0 {
+		return nil
+	}
+
+	// Metric names are validated by parseExtraMetrics, table and clauses are
+	// constants of the callers
+	var selects, sets []string
+	for _, m := range extraMetrics {
+		selects = append(selects, fmt.Sprintf("AVG(%s)", m))
+		sets = append(sets, fmt.Sprintf("avg_%s = ?", m))
+		if withMinMax {
+			selects = append(selects, fmt.Sprintf("MIN(%s)", m), fmt.Sprintf("MAX(%s)", m))
+			sets = append(sets, fmt.Sprintf("min_%s = ?", m), fmt.Sprintf("max_%s = ?", m))
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM weather WHERE %s%s%s",
+		strings.Join(selects, ", "), rangeWhere, exclusionFilter(), stationFilter(db))
+
+	results := make([]sql.NullFloat64, len(selects))
+	dest := make([]any, len(selects))
+	for i := range results {
+		dest[i] = &results[i]
+	}
+	if err := db.QueryRow(query, rangeArgs...).Scan(dest...); err != nil {
+		return fmt.Errorf("failed to calculate extra metrics: %w", err)
+	}
+
+	args := make([]any, 0, len(results)+len(keyArgs))
+	for _, r := range results {
+		args = append(args, roundNullAggregate(r))
+	}
+	args = append(args, keyArgs...)
+
+	update := fmt.Sprintf("UPDATE %s SET %s WHERE %s%s", table, strings.Join(sets, ", "), keyWhere, stationFilter(db))
+	if _, err := db.Exec(update, args...); err != nil {
+		return fmt.Errorf("failed to store extra metrics in %s: %w", table, err)
+	}
+	return nil
+}
+
+// roundNullAggregate rounds a nullable aggregate, keeping NULL as nil
+func roundNullAggregate(v sql.NullFloat64) any {
+	if !v.Valid {
+		return nil
+	}
+	return roundAggregate(v.Float64)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ReconnectingDB holds the shared connection pool and rebuilds it after
+// repeated connection failures. A managed MySQL fails over by moving its DNS
+// name to the new primary; rebuilding the pool with sql.Open drops all
+// connections to the old address and resolves the name again.
+type ReconnectingDB struct {
+	mu        sync.Mutex
+	db        *sql.DB
+	failures  int
+	threshold int
+	open      func() (*sql.DB, error) // builds the replacement pool
+}
+
+// dbPool is the pool the service runs on. withRetry reports every database
+// result to it, so connection errors of any job count towards a rebuild.
+var dbPool *ReconnectingDB
+
+// newReconnectingDB wraps db so that it is rebuilt after threshold
+// consecutive connection errors (0 never rebuilds it)
+func newReconnectingDB(db *sql.DB, threshold int) *ReconnectingDB {
+	return &ReconnectingDB{db: db, threshold: threshold, open: openDB}
+}
+
+// DB returns the current pool
+func (r *ReconnectingDB) DB() *sql.DB {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.db
+}
+
+// Close closes the current pool
+func (r *ReconnectingDB) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.db.Close()
+}
+
+// Observe records the result of a query made through the pool. Any other
+// result resets the failure count; a connection error that reaches the
+// threshold replaces the pool. Observing on a nil pool does nothing.
+func (r *ReconnectingDB) Observe(err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !isConnectionError(err) {
+		r.failures = 0
+		return
+	}
+
+	r.failures++
+	if r.threshold <= 0 || r.failures < r.threshold {
+		return
+	}
+
+	slog.Warn("Consecutive database connection errors, reconnecting", "component", "db", "failures", r.failures, "error", err)
+	fresh, err := r.open()
+	if err != nil {
+		slog.Warn("Failed to rebuild database pool", "component", "db", "error", err)
+		return
+	}
+	stale := r.db
+	r.db = fresh
+	r.failures = 0
+
+	// Close waits for queries still running on the stale pool
+	go stale.Close()
+}
+
+// isConnectionError reports whether err means the database could not be
+// reached, as opposed to an error in the query itself
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
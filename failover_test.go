@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// failingConnector never reaches the database, like a pool still pointing
+// at the old primary after a failover
+type failingConnector struct{}
+
+func (failingConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, driver.ErrBadConn
+}
+
+func (failingConnector) Driver() driver.Driver { return failingDriver{} }
+
+type failingDriver struct{}
+
+func (failingDriver) Open(string) (driver.Conn, error) { return nil, driver.ErrBadConn }
+
+func TestWithRetryRebuildsPoolAfterConnectionErrors(t *testing.T) {
+	stale := sql.OpenDB(failingConnector{})
+	t.Cleanup(func() { stale.Close() })
+
+	var rebuilt *sql.DB
+	pool := newReconnectingDB(stale, 3)
+	pool.open = func() (*sql.DB, error) {
+		rebuilt = sql.OpenDB(failingConnector{})
+		return rebuilt, nil
+	}
+	saved := dbPool
+	dbPool = pool
+	t.Cleanup(func() { dbPool = saved })
+
+	// Two failed attempts stay below the threshold
+	if err := withRetry(2, 0, stale.Ping); !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("withRetry() = %v, want driver.ErrBadConn", err)
+	}
+	if pool.DB() != stale {
+		t.Fatal("pool rebuilt below the threshold")
+	}
+
+	// A query error in between resets the count
+	withRetry(1, 0, func() error { return errors.New("syntax error") })
+	withRetry(2, 0, stale.Ping)
+	if pool.DB() != stale {
+		t.Fatal("pool rebuilt although the failures were not consecutive")
+	}
+
+	withRetry(1, 0, stale.Ping)
+	if pool.DB() != rebuilt || rebuilt == nil {
+		t.Fatal("pool not rebuilt after three consecutive connection errors")
+	}
+	t.Cleanup(func() { rebuilt.Close() })
+}
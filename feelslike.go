@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+)
+
+// heatIndex returns the NOAA heat index in °C for a temperature in °C and
+// relative humidity in percent. The formulas are defined in °F, so the
+// temperature is converted in and the result back out. Steadman's simple
+// formula is used while its average with the temperature stays below 80 °F,
+// above that the Rothfusz regression with its low and high humidity
+// adjustments. Humidity is clamped to 0..100 %.
+func heatIndex(tempC, humidity float64) float64 {
+	t := tempC*9/5 + 32
+	rh := math.Max(0, math.Min(humidity, 100))
+
+	hi := 0.5 * (t + 61 + (t-68)*1.2 + rh*0.094)
+	if (hi+t)/2 >= 80 {
+		hi = -42.379 + 2.04901523*t + 10.14333127*rh -
+			0.22475541*t*rh - 0.00683783*t*t - 0.05481717*rh*rh +
+			0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+		switch {
+		case rh < 13 && t >= 80 && t <= 112:
+			hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+		case rh > 85 && t >= 80 && t <= 87:
+			hi += (rh - 85) / 10 * (87 - t) / 5
+		}
+	}
+	return (hi - 32) * 5 / 9
+}
+
+// feelsLike returns the apparent temperature in °C. Without wind data only
+// the effect of humidity is accounted for.
+func feelsLike(temperature, humidity float64) float64 {
+	return heatIndex(temperature, humidity)
+}
+
+// updateDailyFeelsLike stores the daily average feels-like temperature of a
+// date
+func updateDailyFeelsLike(db dbExecutor, date string) error {
+	var avg sql.NullFloat64
+	query := `
+		SELECT AVG(feels_like)
+		FROM weather
+		WHERE ` + observationDay() + ` = ?` + exclusionFilter() + stationFilter(db)
+	if err := db.QueryRow(query, date).Scan(&avg); err != nil {
+		return fmt.Errorf("failed to calculate daily feels-like temperature: %w", err)
+	}
+	if avg.Valid {
+		avg.Float64 = roundAggregate(avg.Float64)
+	}
+
+	_, err := db.Exec(`UPDATE weather_daily SET avg_feels_like = ? WHERE date = ?`+stationFilter(db), avg, date)
+	if err != nil {
+		return fmt.Errorf("failed to store daily feels-like temperature: %w", err)
+	}
+	return nil
+}
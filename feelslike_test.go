@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHeatIndex(t *testing.T) {
+	fahrenheit := func(c float64) float64 { return c*9/5 + 32 }
+	celsius := func(f float64) float64 { return (f - 32) * 5 / 9 }
+	tests := []struct {
+		name     string
+		tempF    float64
+		humidity float64
+		wantF    float64
+		tolF     float64
+	}{
+		// NWS heat index chart, rounded to whole °F
+		{"chart 80 °F 40 %", 80, 40, 80, 1},
+		{"chart 90 °F 50 %", 90, 50, 95, 1},
+		{"chart 100 °F 40 %", 100, 40, 109, 1},
+		{"chart 96 °F 65 %", 96, 65, 121, 1},
+		{"chart 86 °F 90 %", 86, 90, 105, 1},
+		// Below 80 °F Steadman's formula, close to the air temperature
+		{"simple formula", 70, 50, 69.05, 0.01},
+		{"cold air", 20, 50, 14.05, 0.01},
+		// Rothfusz adjustments
+		{"dry adjustment", 95, 5, 88.18, 0.01},
+		{"humid adjustment", 84, 100, 103.56, 0.01},
+		{"humidity clamped to 100 %", 84, 120, 103.56, 0.01},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fahrenheit(heatIndex(celsius(tt.tempF), tt.humidity))
+			if math.Abs(got-tt.wantF) > tt.tolF {
+				t.Errorf("heatIndex(%v °F, %v %%) = %.2f °F, want %v ± %v", tt.tempF, tt.humidity, got, tt.wantF, tt.tolF)
+			}
+		})
+	}
+}
+
+func TestComfortBand(t *testing.T) {
+	thresholds := ComfortThresholds{ColdMax: 5, CoolMax: 15, ComfortableMax: 24, WarmMax: 30}
+	tests := []struct {
+		apparent float64
+		want     string
+	}{
+		{-20, ComfortCold},
+		{4.99, ComfortCold},
+		{5, ComfortCool},
+		{14.99, ComfortCool},
+		{15, ComfortComfortable},
+		{23.99, ComfortComfortable},
+		{24, ComfortWarm},
+		{29.99, ComfortWarm},
+		{30, ComfortHot},
+		{45, ComfortHot},
+	}
+	for _, tt := range tests {
+		if got := comfortBand(tt.apparent, thresholds); got != tt.want {
+			t.Errorf("comfortBand(%v) = %s, want %s", tt.apparent, got, tt.want)
+		}
+	}
+}
+
+func TestDailyComfortBandsFollowObservationDay(t *testing.T) {
+	setupTestConfig(t, map[string]string{"ENABLE_COMFORT_BANDS": "true", "DAY_BOUNDARY_HOUR": "9"})
+	db := newTestStore(t)
+
+	// The observation day 2024-06-01 runs from 09:00 to 09:00 the next
+	// morning: 8 comfortable hours, 16 cold ones. The hot morning before and
+	// after it belongs to the neighbouring days.
+	midnight := time.Date(2024, 6, 1, 0, 0, 0, 0, config.Location)
+	temperature := func(hour int) float64 {
+		switch {
+		case hour < 9 || hour >= 33:
+			return 35
+		case hour < 17:
+			return 20
+		}
+		return 3
+	}
+	for hour := 6; hour <= 35; hour++ {
+		processFixture(t, db, []byte(fmt.Sprintf(
+			`{"timestamp": %d, "temperature": %.1f, "pressure": 1012.0, "humidity": 40.0}`,
+			midnight.Add(time.Duration(hour)*time.Hour).Unix(), temperature(hour))))
+	}
+	if err := updateDailyStatisticsFor(db, midnight); err != nil {
+		t.Fatalf("updateDailyStatisticsFor: %v", err)
+	}
+
+	rows := queryRows(t, db, `SELECT comfort_cold_hours, comfort_cool_hours, comfort_comfortable_hours,
+		comfort_warm_hours, comfort_hot_hours FROM weather_daily WHERE date = '2024-06-01'`)
+	want := []map[string]string{{
+		"comfort_cold_hours": "16", "comfort_cool_hours": "0", "comfort_comfortable_hours": "8",
+		"comfort_warm_hours": "0", "comfort_hot_hours": "0",
+	}}
+	if fmt.Sprint(rows) != fmt.Sprint(want) {
+		t.Errorf("weather_daily = %v, want %v", rows, want)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DailyMin is the minimum temperature of one day
+type DailyMin struct {
+	Date           time.Time
+	MinTemperature float64
+}
+
+// FrostFreePeriod is the span between the last spring frost and the first
+// autumn frost of a year. The frost dates are invalid when the year had no
+// frost in that half; the period then extends to the start or end of the year.
+type FrostFreePeriod struct {
+	LastSpringFrost  sql.NullTime
+	FirstAutumnFrost sql.NullTime
+	Days             int
+}
+
+// frostFreePeriod finds the frost-free period of a year from its daily
+// minimums. A frost day has a minimum at or below threshold. Spring is the
+// first half of the year (before July 1), autumn the second. Days without
+// data count as frost-free.
+func frostFreePeriod(year int, days []DailyMin, threshold float64) FrostFreePeriod {
+	yearStart := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	midYear := time.Date(year, time.July, 1, 0, 0, 0, 0, time.UTC)
+	yearEnd := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	var period FrostFreePeriod
+	for _, d := range days {
+		if d.MinTemperature > threshold {
+			continue
+		}
+		date := time.Date(d.Date.Year(), d.Date.Month(), d.Date.Day(), 0, 0, 0, 0, time.UTC)
+		if date.Before(midYear) {
+			if !period.LastSpringFrost.Valid || date.After(period.LastSpringFrost.Time) {
+				period.LastSpringFrost = sql.NullTime{Time: date, Valid: true}
+			}
+		} else if !period.FirstAutumnFrost.Valid || date.Before(period.FirstAutumnFrost.Time) {
+			period.FirstAutumnFrost = sql.NullTime{Time: date, Valid: true}
+		}
+	}
+
+	// First and last frost-free day of the period, inclusive
+	first := yearStart
+	if period.LastSpringFrost.Valid {
+		first = period.LastSpringFrost.Time.AddDate(0, 0, 1)
+	}
+	last := yearEnd
+	if period.FirstAutumnFrost.Valid {
+		last = period.FirstAutumnFrost.Time.AddDate(0, 0, -1)
+	}
+
+	if !last.Before(first) {
+		period.Days = int(last.Sub(first).Hours()/24) + 1
+	}
+	return period
+}
+
+// updateFrostFreePeriod computes and stores the frost-free period of a year
+func updateFrostFreePeriod(db dbExecutor, year int) error {
+	query := `
+		SELECT date, min_temperature
+		FROM weather_daily
+		WHERE date >= ? AND date <= ? AND min_temperature IS NOT NULL` + stationFilter(db) + `
+		ORDER BY date
+	`
+
+	rows, err := db.Query(query, fmt.Sprintf("%d-01-01", year), fmt.Sprintf("%d-12-31", year))
+	if err != nil {
+		return fmt.Errorf("failed to query daily minimums: %w", err)
+	}
+	defer rows.Close()
+
+	var days []DailyMin
+	for rows.Next() {
+		var d DailyMin
+		if err := rows.Scan(&d.Date, &d.MinTemperature); err != nil {
+			return fmt.Errorf("failed to scan daily minimum: %w", err)
+		}
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate daily minimums: %w", err)
+	}
+
+	if len(days) == 0 {
+		slog.Info("No daily statistics found, skipping frost-free period", "component", "stats", "year", year)
+		return nil
+	}
+
+	period := frostFreePeriod(year, days, config.FrostThreshold)
+	slog.Info("Frost-free period", "component", "stats", "year", year, "days", period.Days)
+
+	upsert := `
+		INSERT INTO weather_yearly (station_id, year, last_spring_frost, first_autumn_frost, frost_free_days)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			last_spring_frost = VALUES(last_spring_frost),
+			first_autumn_frost = VALUES(first_autumn_frost),
+			frost_free_days = VALUES(frost_free_days),
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err = db.Exec(upsert, stationOf(db), year, period.LastSpringFrost, period.FirstAutumnFrost, period.Days)
+	if err != nil {
+		return fmt.Errorf("failed to upsert frost-free period: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFrostFreePeriod(t *testing.T) {
+	day := func(month time.Month, d int, min float64) DailyMin {
+		return DailyMin{Date: time.Date(2023, month, d, 0, 0, 0, 0, time.UTC), MinTemperature: min}
+	}
+	var allFrost []DailyMin
+	for d := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC); d.Year() == 2023; d = d.AddDate(0, 0, 1) {
+		allFrost = append(allFrost, DailyMin{Date: d, MinTemperature: -5})
+	}
+
+	tests := []struct {
+		name       string
+		days       []DailyMin
+		wantSpring string // "" for no frost
+		wantAutumn string
+		wantDays   int
+	}{
+		{
+			name:     "no frost",
+			days:     []DailyMin{day(time.March, 1, 3), day(time.November, 20, 0.5)},
+			wantDays: 365,
+		},
+		{
+			name: "frost exactly at threshold",
+			days: []DailyMin{
+				day(time.April, 10, 0), day(time.April, 20, 0.1),
+				day(time.October, 14, 0.1), day(time.October, 15, 0),
+			},
+			wantSpring: "2023-04-10",
+			wantAutumn: "2023-10-15",
+			wantDays:   187,
+		},
+		{
+			name:       "latest spring and earliest autumn frost win",
+			days:       []DailyMin{day(time.May, 2, -1), day(time.March, 5, -4), day(time.November, 3, -2), day(time.September, 30, -1)},
+			wantSpring: "2023-05-02",
+			wantAutumn: "2023-09-30",
+			wantDays:   150,
+		},
+		{
+			name:       "spring frost only",
+			days:       []DailyMin{day(time.June, 30, -0.5)},
+			wantSpring: "2023-06-30",
+			wantDays:   184,
+		},
+		{
+			name:       "all frost",
+			days:       allFrost,
+			wantSpring: "2023-06-30",
+			wantAutumn: "2023-07-01",
+			wantDays:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := frostFreePeriod(2023, tt.days, 0)
+
+			if spring := formatNullDate(got.LastSpringFrost.Valid, got.LastSpringFrost.Time); spring != tt.wantSpring {
+				t.Errorf("last spring frost = %q, want %q", spring, tt.wantSpring)
+			}
+			if autumn := formatNullDate(got.FirstAutumnFrost.Valid, got.FirstAutumnFrost.Time); autumn != tt.wantAutumn {
+				t.Errorf("first autumn frost = %q, want %q", autumn, tt.wantAutumn)
+			}
+			if got.Days != tt.wantDays {
+				t.Errorf("frost-free days = %d, want %d", got.Days, tt.wantDays)
+			}
+		})
+	}
+}
+
+func formatNullDate(valid bool, t time.Time) string {
+	if !valid {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
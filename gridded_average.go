@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// gridSample is one reading of the value being gridded
+type gridSample struct {
+	At    time.Time
+	Value float64
+}
+
+// griddedMean resamples the ordered samples onto a regular grid of points
+// start, start+interval, ... before end and averages the grid values, so
+// clustered readings do not outweigh sparse ones. Each grid point takes the
+// value linearly interpolated between the samples around it; points before
+// the first or after the last sample are left out rather than extrapolated.
+// When the samples fall between two grid points their plain mean is used.
+// ok is false when there are no samples.
+func griddedMean(samples []gridSample, start, end time.Time, interval time.Duration) (mean float64, ok bool) {
+	if len(samples) == 0 || interval <= 0 {
+		return 0, false
+	}
+
+	var sum float64
+	var points int
+	next := 0 // first sample at or after the grid point
+	for t := start; t.Before(end); t = t.Add(interval) {
+		for next < len(samples) && samples[next].At.Before(t) {
+			next++
+		}
+		var v float64
+		switch {
+		case next == len(samples):
+			// Past the last sample, and so is every later point
+			t = end
+			continue
+		case samples[next].At.Equal(t):
+			v = samples[next].Value
+		case next == 0:
+			continue
+		default:
+			prev, after := samples[next-1], samples[next]
+			frac := float64(t.Sub(prev.At)) / float64(after.At.Sub(prev.At))
+			v = prev.Value + frac*(after.Value-prev.Value)
+		}
+		sum += v
+		points++
+	}
+	if points == 0 {
+		for _, s := range samples {
+			sum += s.Value
+		}
+		return sum / float64(len(samples)), true
+	}
+	return sum / float64(points), true
+}
+
+// updateDailyGriddedAverage stores the grid-weighted daily mean temperature
+// in avg_temperature_gridded, NULL when the day has no readings
+func updateDailyGriddedAverage(db dbExecutor, date string) error {
+	start, end, err := observationDayBounds(date)
+	if err != nil {
+		return fmt.Errorf("invalid date %q: %w", date, err)
+	}
+
+	rows, err := db.Query(`
+		SELECT measured_at, temperature
+		FROM weather
+		WHERE `+observationDay()+` = ? AND temperature IS NOT NULL`+exclusionFilter()+stationFilter(db)+`
+		ORDER BY measured_at
+	`, date)
+	if err != nil {
+		return fmt.Errorf("failed to query temperatures: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []gridSample
+	for rows.Next() {
+		var s gridSample
+		if err := rows.Scan(&s.At, &s.Value); err != nil {
+			return fmt.Errorf("failed to scan temperature: %w", err)
+		}
+		samples = append(samples, s)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate temperatures: %w", err)
+	}
+
+	var gridded sql.NullFloat64
+	interval := time.Duration(config.GridIntervalMinutes) * time.Minute
+	if mean, ok := griddedMean(samples, start, end, interval); ok {
+		gridded = sql.NullFloat64{Float64: roundAggregate(mean), Valid: true}
+	}
+
+	_, err = db.Exec(`UPDATE weather_daily SET avg_temperature_gridded = ? WHERE date = ?`+stationFilter(db), gridded, date)
+	if err != nil {
+		return fmt.Errorf("failed to store gridded average: %w", err)
+	}
+	return nil
+}
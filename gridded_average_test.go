@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// clusteredDay is a day sampled every six hours, with a burst of twelve
+// readings at noon while the sensor reported every five minutes
+func clusteredDay(day time.Time) []gridSample {
+	samples := []gridSample{{day, 10}, {day.Add(6 * time.Hour), 10}}
+	for i := 0; i < 12; i++ {
+		samples = append(samples, gridSample{day.Add(12*time.Hour + time.Duration(5*i)*time.Minute), 20})
+	}
+	return append(samples, gridSample{day.Add(18 * time.Hour), 10}, gridSample{day.Add(23 * time.Hour), 10})
+}
+
+func TestGriddedMean(t *testing.T) {
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	tests := []struct {
+		name    string
+		samples []gridSample
+		want    float64
+		wantOK  bool
+	}{
+		{"no samples", nil, 0, false},
+		{"clustered readings", clusteredDay(day), 12.688, true},
+		{"linear ramp", []gridSample{{at(0, 0), 0}, {at(23, 0), 23}}, 11.5, true},
+		{"points outside the samples are left out", []gridSample{{at(10, 0), 4}, {at(12, 0), 8}}, 6, true},
+		{"samples between two grid points", []gridSample{{at(10, 10), 4}, {at(10, 50), 8}}, 6, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := griddedMean(tt.samples, day, day.AddDate(0, 0, 1), time.Hour)
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("griddedMean() = %.4f, %v, want %.4f, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDailyGriddedAverageDiffersFromPlainMean(t *testing.T) {
+	setupTestConfig(t, map[string]string{"ENABLE_GRIDDED_AVERAGE": "true", "GRID_INTERVAL_MINUTES": "60"})
+	db := newTestStore(t)
+
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, config.Location)
+	for _, s := range clusteredDay(day) {
+		processFixture(t, db, []byte(fmt.Sprintf(
+			`{"timestamp": %d, "temperature": %.1f, "pressure": 1012.0, "humidity": 60.0}`, s.At.Unix(), s.Value)))
+	}
+	if err := updateDailyStatisticsFor(db, day); err != nil {
+		t.Fatalf("updateDailyStatisticsFor: %v", err)
+	}
+
+	// The noon burst pulls the plain mean up to 17.5 °C
+	rows := queryRows(t, db, `SELECT avg_temperature, avg_temperature_gridded FROM weather_daily`)
+	want := []map[string]string{{"avg_temperature": "17.5", "avg_temperature_gridded": "12.7"}}
+	if fmt.Sprint(rows) != fmt.Sprint(want) {
+		t.Errorf("weather_daily = %v, want %v", rows, want)
+	}
+}
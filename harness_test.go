@@ -0,0 +1,285 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// The integration harness runs the processing path against an in-memory
+// SQLite database behind the Store interface. sqliteCompatDriver rewrites
+// the MySQL dialect of the processor's queries the way compatDriver does for
+// PostgreSQL, and the MySQL functions SQLite lacks are registered below.
+// Tests run with TIMEZONE=UTC, so the times SQLite stores as text compare and
+// truncate the same way as DATETIME values in MySQL.
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// TestMain keeps the processor's logging out of the test output
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Verbose() {
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+	os.Exit(m.Run())
+}
+
+const sqliteCompatDriverName = "sqlite-compat"
+
+var sqliteIntervalPattern = regexp.MustCompile(`(?i)(\w+) - INTERVAL (\d+) HOUR`)
+
+func init() {
+	sql.Register(sqliteCompatDriverName, sqliteCompatDriver{})
+
+	// HOUR() of a DATETIME stored as "2006-01-02 15:04:05..." text
+	sqlite.MustRegisterDeterministicScalarFunction("HOUR", 1,
+		func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			s, ok := args[0].(string)
+			if !ok || len(s) < 13 {
+				return nil, nil
+			}
+			var hour int64
+			_, err := fmt.Sscanf(s[11:13], "%d", &hour)
+			return hour, err
+		})
+	sqlite.MustRegisterDeterministicScalarFunction("GREATEST", -1,
+		func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			return extremeValue(args, func(a, b float64) bool { return a > b }), nil
+		})
+	sqlite.MustRegisterDeterministicScalarFunction("LEAST", -1,
+		func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			return extremeValue(args, func(a, b float64) bool { return a < b }), nil
+		})
+}
+
+// extremeValue returns the argument preferred by better, NULL if any
+// argument is NULL as in MySQL
+func extremeValue(args []driver.Value, better func(a, b float64) bool) driver.Value {
+	var best driver.Value
+	var bestNum float64
+	for _, a := range args {
+		var n float64
+		switch v := a.(type) {
+		case int64:
+			n = float64(v)
+		case float64:
+			n = v
+		default:
+			return nil
+		}
+		if best == nil || better(n, bestNum) {
+			best, bestNum = a, n
+		}
+	}
+	return best
+}
+
+// translateSQLite rewrites a MySQL query for SQLite: ON DUPLICATE KEY UPDATE
+// becomes ON CONFLICT ... DO UPDATE SET with VALUES(col) as excluded.col, and
+// col - INTERVAL n HOUR the datetime() modifier
+func translateSQLite(query string) (string, error) {
+	if loc := upsertPattern.FindStringIndex(query); loc != nil {
+		m := insertTablePattern.FindStringSubmatch(query)
+		if m == nil {
+			return "", fmt.Errorf("upsert without INSERT INTO: %s", query)
+		}
+		keys := conflictKeys(m[1])
+		if keys == nil {
+			return "", fmt.Errorf("no conflict key known for table %s", m[1])
+		}
+		update := upsertValuePattern.ReplaceAllString(query[loc[1]:], "excluded.$1")
+		query = query[:loc[0]] + "ON CONFLICT (" + strings.Join(keys, ", ") + ") DO UPDATE SET" + update
+	}
+	return sqliteIntervalPattern.ReplaceAllString(query, "datetime($1, '-$2 hours')"), nil
+}
+
+// sqliteCompatDriver opens SQLite connections that translate every query
+type sqliteCompatDriver struct{}
+
+func (sqliteCompatDriver) Open(dsn string) (driver.Conn, error) {
+	// The registered driver, a zero sqlite.Driver lacks the functions above
+	base, err := sql.Open("sqlite", "")
+	if err != nil {
+		return nil, err
+	}
+	defer base.Close()
+	c, err := base.Driver().Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteCompatConn{c}, nil
+}
+
+// sqliteCompatConn only exposes Prepare, so database/sql sends every
+// statement through the translation
+type sqliteCompatConn struct {
+	driver.Conn
+}
+
+func (c sqliteCompatConn) Prepare(query string) (driver.Stmt, error) {
+	q, err := translateSQLite(query)
+	if err != nil {
+		return nil, err
+	}
+	return c.Conn.Prepare(q)
+}
+
+// newTestStore returns an empty in-memory database with the tables of
+// testdata/schema.sql
+func newTestStore(t *testing.T) *sql.DB {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := sql.Open(sqliteCompatDriverName, "file:"+name+"?mode=memory&cache=shared&_time_format=sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One connection, so transactions and the in-memory database agree
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	schema, err := os.ReadFile(filepath.Join("testdata", "schema.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range strings.Split(string(schema), ";") {
+		if strings.TrimSpace(stmt) == "" {
+			continue
+		}
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("failed to create schema: %v\n%s", err, stmt)
+		}
+	}
+	return db
+}
+
+// setupTestConfig loads the configuration from env on top of the defaults
+// and derives the globals main sets up from it. Everything is restored when
+// the test ends.
+func setupTestConfig(t *testing.T, env map[string]string) {
+	t.Helper()
+	t.Setenv("TIMEZONE", "UTC")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	saved := config
+	savedFields, savedSentinels, savedNow, savedSource := timestampFields, sensorSentinels, now, source
+	savedEdges := tempBandEdges
+	t.Cleanup(func() {
+		config = saved
+		timestampFields, sensorSentinels, now, source = savedFields, savedSentinels, savedNow, savedSource
+		tempBandEdges = savedEdges
+		resume.Clear()
+	})
+
+	config = loadConfig()
+	timestampFields = parseTimestampFieldList(config.TimestampFields)
+	sentinels, err := parseSensorSentinels(config.SensorSentinels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sensorSentinels = sentinels
+	if config.EnableTempBands {
+		if tempBandEdges, err = parseTempBandEdges(config.TempBandEdges); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// setTestNow pins the clock
+func setTestNow(t *testing.T, at time.Time) {
+	t.Helper()
+	now = func() time.Time { return at }
+}
+
+// processFixture writes payload to a temporary weather.json and runs
+// processWeatherData on it, as the scheduled job does
+func processFixture(t *testing.T, db Store, payload []byte) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "weather.json")
+	if err := os.WriteFile(path, payload, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	source = FileReader{Path: path, Strategy: ReadStrategySingle}
+	if err := processWeatherData(db); err != nil {
+		t.Fatalf("processWeatherData: %v", err)
+	}
+}
+
+// queryRows returns the rows of query as column -> formatted value, leaving
+// out the bookkeeping timestamps that change on every run
+func queryRows(t *testing.T, db dbExecutor, query string, args ...any) []map[string]string {
+	t.Helper()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []map[string]string
+	for rows.Next() {
+		values := make([]any, len(types))
+		dest := make([]any, len(types))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			t.Fatal(err)
+		}
+		row := make(map[string]string)
+		for i, ct := range types {
+			switch ct.Name() {
+			case "created_at", "updated_at":
+				continue
+			}
+			row[ct.Name()] = csvField(values[i], ct.DatabaseTypeName())
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// assertGolden compares got with testdata/golden/<name>.golden, or rewrites
+// the file with -update
+func assertGolden(t *testing.T, name string, got any) {
+	t.Helper()
+	encoded, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded = append(encoded, '\n')
+
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *updateGolden {
+		if err := os.WriteFile(path, encoded, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if string(want) != string(encoded) {
+		t.Errorf("%s does not match the golden file\ngot:\n%s\nwant:\n%s", name, encoded, want)
+	}
+}
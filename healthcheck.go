@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// healthcheckTimeout bounds the database ping of -healthcheck, so a hung
+// connection fails the probe instead of outliving its timeout
+const healthcheckTimeout = 5 * time.Second
+
+// runHealthcheck pings the database and checks that the JSON source can be
+// read, for exec liveness probes. It returns the exit code: 0 when healthy,
+// 1 otherwise. No reading is parsed or stored.
+func runHealthcheck(db *sql.DB) int {
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthcheckTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		slog.Error("Health check failed", "component", "healthcheck", "check", "database", "error", err)
+		return 1
+	}
+
+	if err := checkSourceReadable(); err != nil {
+		slog.Error("Health check failed", "component", "healthcheck", "check", "source", "error", err)
+		return 1
+	}
+
+	slog.Info("Health check passed", "component", "healthcheck")
+	return 0
+}
+
+// checkSourceReadable verifies that the configured source can be read
+// without going through the read strategy, whose marker and reread checks
+// are about a single reading, not the source itself
+func checkSourceReadable() error {
+	if len(stationSources) > 0 {
+		for _, s := range stationSources {
+			if _, err := s.Reader.Read(); err != nil {
+				return fmt.Errorf("station %s: %w", s.StationID, err)
+			}
+		}
+		return nil
+	}
+
+	switch r := source.(type) {
+	case FileReader:
+		f, err := os.Open(r.Path)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	case *S3Reader:
+		// A single attempt, the probe has its own retries
+		_, err := r.fetch()
+		return err
+	default:
+		_, err := source.Read()
+		return err
+	}
+}
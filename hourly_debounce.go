@@ -0,0 +1,126 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// HourlyDebouncer coalesces the hourly recompute triggered by every insert.
+// An hour is recomputed at most once per interval; inserts arriving sooner
+// schedule a single trailing recompute at the end of the interval, so the
+// last readings of an hour still reach weather_hourly.
+type HourlyDebouncer struct {
+	interval time.Duration
+	update   func(station string, hour time.Time) error
+
+	mu      sync.Mutex
+	last    map[hourKey]time.Time   // last recompute
+	pending map[hourKey]*time.Timer // trailing recompute
+	latest  map[hourKey]time.Time   // newest reading seen
+}
+
+// hourKey identifies the hour of one station, the hour by its start in Unix
+// seconds
+type hourKey struct {
+	station string
+	hour    int64
+}
+
+var hourlyDebouncer *HourlyDebouncer
+
+// newHourlyDebouncer creates a debouncer calling update at most once per
+// interval for each hour of each station
+func newHourlyDebouncer(interval time.Duration, update func(station string, hour time.Time) error) *HourlyDebouncer {
+	return &HourlyDebouncer{
+		interval: interval,
+		update:   update,
+		last:     make(map[hourKey]time.Time),
+		pending:  make(map[hourKey]*time.Timer),
+		latest:   make(map[hourKey]time.Time),
+	}
+}
+
+// hourStart returns the start of the hour containing t. Subtracting the
+// minutes keeps the repeated DST hour apart from the one before it.
+func hourStart(t time.Time) time.Time {
+	return t.Add(-time.Duration(t.Minute())*time.Minute -
+		time.Duration(t.Second())*time.Second -
+		time.Duration(t.Nanosecond()))
+}
+
+// Trigger recomputes the hour of measuredAt of station now if it was not
+// recomputed within the interval, otherwise makes sure a trailing recompute
+// is pending
+func (d *HourlyDebouncer) Trigger(station string, measuredAt time.Time) {
+	key := hourKey{station, hourStart(measuredAt).Unix()}
+	current := now()
+
+	d.mu.Lock()
+	if measuredAt.After(d.latest[key]) {
+		d.latest[key] = measuredAt
+	}
+	if last, ok := d.last[key]; ok && current.Sub(last) < d.interval {
+		if d.pending[key] == nil {
+			d.pending[key] = time.AfterFunc(d.interval-current.Sub(last), func() { d.fire(key) })
+		}
+		d.mu.Unlock()
+		slog.Debug("Hourly recompute coalesced", "component", "stats", "measured_at", measuredAt)
+		return
+	}
+	d.last[key] = current
+	d.prune(current)
+	d.mu.Unlock()
+
+	d.run(station, measuredAt)
+}
+
+// fire runs a trailing recompute scheduled by Trigger
+func (d *HourlyDebouncer) fire(key hourKey) {
+	d.mu.Lock()
+	if _, ok := d.pending[key]; !ok {
+		// Already run by Flush
+		d.mu.Unlock()
+		return
+	}
+	delete(d.pending, key)
+	d.last[key] = now()
+	measuredAt := d.latest[key]
+	d.mu.Unlock()
+
+	d.run(key.station, measuredAt)
+}
+
+func (d *HourlyDebouncer) run(station string, measuredAt time.Time) {
+	slog.Debug("Calculating hourly averages", "component", "stats", "station_id", station, "measured_at", measuredAt)
+	if err := d.update(station, measuredAt); err != nil {
+		slog.Warn("Failed to update hourly averages", "component", "stats", "station_id", station, "measured_at", measuredAt, "error", err)
+	}
+}
+
+// prune forgets hours that can no longer be coalesced. Called with d.mu held.
+func (d *HourlyDebouncer) prune(current time.Time) {
+	for key, last := range d.last {
+		if d.pending[key] == nil && current.Sub(last) > d.interval+time.Hour {
+			delete(d.last, key)
+			delete(d.latest, key)
+		}
+	}
+}
+
+// Flush runs the pending trailing recomputes right away, e.g. on shutdown
+func (d *HourlyDebouncer) Flush() {
+	d.mu.Lock()
+	hours := make(map[hourKey]time.Time)
+	for key, timer := range d.pending {
+		// A timer that already fired finds its entry gone and does nothing
+		timer.Stop()
+		hours[key] = d.latest[key]
+		delete(d.pending, key)
+	}
+	d.mu.Unlock()
+
+	for key, measuredAt := range hours {
+		d.run(key.station, measuredAt)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+// HTTPReader fetches the weather JSON from an HTTP endpoint. The client is
+// shared by all fetches so connections are reused.
+type HTTPReader struct {
+	URL    string
+	Client *http.Client
+}
+
+// newHTTPReader creates a reader for url with the given request timeout
+func newHTTPReader(url string, timeout time.Duration) HTTPReader {
+	return HTTPReader{URL: url, Client: &http.Client{Timeout: timeout}}
+}
+
+// Read fetches the reading. Any status other than 200 is an error; 404 is
+// reported as fs.ErrNotExist, like a missing local file.
+func (r HTTPReader) Read() ([]byte, error) {
+	resp, err := r.Client.Get(r.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", r.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", r.URL, fs.ErrNotExist)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: unexpected status %s", r.URL, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", r.URL, err)
+	}
+	return data, nil
+}
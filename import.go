@@ -0,0 +1,166 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The -import-dir flag loads archived JSON snapshots from before the service
+// was running. Readings are inserted in batched transactions and the
+// aggregates of the imported range are recomputed afterwards.
+
+// ImportResult counts the outcome of an import
+type ImportResult struct {
+	Files      int
+	Inserted   int
+	Duplicates int
+	Skipped    int
+	First      time.Time
+	Last       time.Time
+}
+
+// importFiles returns the .json and .json.gz files below dir in name order,
+// which for weather-<timestamp>.json snapshots is chronological
+func importFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := strings.ToLower(path)
+		if d.Type().IsRegular() && (strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".json.gz")) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// importDir inserts every reading found in dir, batchSize rows per
+// transaction, then recomputes the hourly, daily, weekly and monthly
+// aggregates across the imported range. Files that cannot be read, parsed
+// or that fail validation are skipped.
+func importDir(db *sql.DB, dir string, batchSize int) (ImportResult, error) {
+	var result ImportResult
+	files, err := importFiles(dir)
+	if err != nil {
+		return result, err
+	}
+	result.Files = len(files)
+	slog.Info("Importing files", "component", "import", "files", len(files), "dir", dir)
+
+	var batch []WeatherData
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		inserted, err := insertBatch(db, batch)
+		if err != nil {
+			return err
+		}
+		result.Inserted += inserted
+		result.Duplicates += len(batch) - inserted
+		batch = batch[:0]
+		slog.Info("Import progress", "component", "import",
+			"processed", result.Inserted+result.Duplicates+result.Skipped, "files", result.Files,
+			"inserted", result.Inserted, "duplicates", result.Duplicates, "skipped", result.Skipped)
+		return nil
+	}
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			var reading WeatherData
+			reading, err = parseReading(data, "")
+			if err == nil {
+				waitCatchup()
+				batch = append(batch, reading)
+				at := measurementTime(reading.Timestamp)
+				if result.First.IsZero() || at.Before(result.First) {
+					result.First = at
+				}
+				if at.After(result.Last) {
+					result.Last = at
+				}
+			}
+		}
+		if err != nil {
+			slog.Warn("Skipping import file", "component", "import", "path", path, "error", err)
+			result.Skipped++
+			continue
+		}
+
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	if result.Inserted == 0 {
+		return result, nil
+	}
+	slog.Info("Recomputing aggregates", "component", "import", "from", result.First, "to", result.Last)
+	if err := recomputeRange(db, result.First, result.Last); err != nil {
+		return result, fmt.Errorf("failed to recompute aggregates: %w", err)
+	}
+	return result, nil
+}
+
+// insertBatch inserts the readings in one transaction and returns how many
+// were new. Readings already stored, or repeated within the batch, are
+// skipped by the same duplicate check as live readings.
+func insertBatch(db *sql.DB, readings []WeatherData) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin import transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	inserted := 0
+	for _, reading := range readings {
+		duplicate, err := readingExists(tx, reading.Timestamp, reading.StationID)
+		if err != nil {
+			return 0, err
+		}
+		if duplicate {
+			continue
+		}
+
+		stored := roundedValues(reading)
+		columns, values := readingRow(tx, reading, stored)
+		query := fmt.Sprintf("INSERT INTO weather (%s) VALUES (%s)",
+			strings.Join(columns, ", "), placeholders(len(columns)))
+		if _, err := tx.Exec(query, values...); err != nil {
+			return 0, fmt.Errorf("failed to import reading from %s: %w",
+				measurementTime(reading.Timestamp).Format(time.RFC3339), err)
+		}
+		if config.TSDBEnabled {
+			if err := insertMetricRows(tx, reading, stored); err != nil {
+				return 0, fmt.Errorf("failed to import reading from %s: %w",
+					measurementTime(reading.Timestamp).Format(time.RFC3339), err)
+			}
+		}
+		inserted++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit import batch: %w", err)
+	}
+	insertsTotal.Add(float64(inserted))
+	return inserted, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// inversionStrength compares the elevated sensor with the ground sensor.
+// The strength is how much warmer the elevated air is (°C); it is an
+// inversion when the strength reaches threshold.
+func inversionStrength(ground, elevated, threshold float64) (strength float64, inversion bool) {
+	strength = roundTo(elevated-ground, config.RoundDecimals)
+	return strength, strength >= threshold
+}
+
+// inversionAlerted holds the stations whose persistent strong inversion has
+// already been alerted, so one episode alerts once
+var inversionAlerted = make(map[string]bool)
+
+// checkPersistentInversion alerts when every reading of the last
+// INVERSION_PERSIST_MINUTES up to measuredAt was a strong inversion, which
+// traps pollution near the ground
+func checkPersistentInversion(db dbExecutor, measuredAt time.Time) error {
+	window := time.Duration(config.InversionPersistMinutes) * time.Minute
+	from := measuredAt.Add(-window)
+
+	var samples, strong int
+	var first sql.NullTime
+	err := db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(CASE WHEN inversion_strength >= ? THEN 1 ELSE 0 END), 0), MIN(measured_at)
+		FROM weather
+		WHERE measured_at >= ? AND measured_at <= ? AND inversion_strength IS NOT NULL`+stationFilter(db)+`
+	`, config.InversionStrongThreshold, from, measuredAt).Scan(&samples, &strong, &first)
+	if err != nil {
+		return fmt.Errorf("failed to query inversion history: %w", err)
+	}
+
+	// The earliest reading must be close to the start of the window so a
+	// gap in the data does not count as persistence
+	persistent := samples > 0 && strong == samples &&
+		first.Valid && first.Time.Sub(from) <= window/4
+
+	station := stationOf(db)
+	if !persistent {
+		delete(inversionAlerted, station)
+		return nil
+	}
+	if !inversionAlerted[station] {
+		notifyError(fmt.Sprintf("Strong temperature inversion (>= %.1f °C) persisting for %d minutes at %s (station %s)",
+			config.InversionStrongThreshold, config.InversionPersistMinutes, measuredAt.Format(time.RFC3339), station))
+		inversionAlerted[station] = true
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestInversionStrength(t *testing.T) {
+	tests := []struct {
+		name             string
+		ground, elevated float64
+		threshold        float64
+		wantStrength     float64
+		wantInversion    bool
+		roundDecimals    string
+	}{
+		{"normal lapse rate", 10, 8.5, 0.5, -1.5, false, ""},
+		{"equal temperatures", 10, 10, 0.5, 0, false, ""},
+		{"just below the threshold", 10, 10.4, 0.5, 0.4, false, ""},
+		{"at the threshold", 10, 10.5, 0.5, 0.5, true, ""},
+		{"rounded up to the threshold", 10, 10.46, 0.5, 0.5, true, ""},
+		{"strong inversion", -4.2, 1.3, 0.5, 5.5, true, ""},
+		{"zero threshold", 5, 5, 0, 0, true, ""},
+		{"more decimals", 10, 10.46, 0.5, 0.46, false, "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{}
+			if tt.roundDecimals != "" {
+				env["ROUND_DECIMALS"] = tt.roundDecimals
+			}
+			setupTestConfig(t, env)
+			strength, inversion := inversionStrength(tt.ground, tt.elevated, tt.threshold)
+			if strength != tt.wantStrength || inversion != tt.wantInversion {
+				t.Errorf("inversionStrength(%v, %v, %v) = %v, %v, want %v, %v",
+					tt.ground, tt.elevated, tt.threshold, strength, inversion, tt.wantStrength, tt.wantInversion)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// jobStatus is the scheduler state of one cron job
+type jobStatus struct {
+	entry       cron.EntryID
+	lastSuccess time.Time
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// JobStatus is a cron job as returned by /api/jobs
+type JobStatus struct {
+	Name        string     `json:"name"`
+	LastSuccess *time.Time `json:"last_success"`
+	LastError   *string    `json:"last_error"`
+	LastErrorAt *time.Time `json:"last_error_at"`
+	NextRun     *time.Time `json:"next_run"`
+}
+
+// TrackJob registers a scheduled job so its runs and next run are reported
+func (p *Processor) TrackJob(name string, scheduler *cron.Cron, entry cron.EntryID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.scheduler = scheduler
+	p.jobs[name] = &jobStatus{entry: entry}
+}
+
+// RecordJob records the outcome of a run of a tracked job
+func (p *Processor) RecordJob(name string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job, ok := p.jobs[name]
+	if !ok {
+		return
+	}
+	if err != nil {
+		job.lastError = err.Error()
+		job.lastErrorAt = time.Now()
+	} else {
+		job.lastSuccess = time.Now()
+	}
+}
+
+// Jobs returns the state of the tracked jobs sorted by name, with the next
+// run taken from the scheduler
+func (p *Processor) Jobs() []JobStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	jobs := make([]JobStatus, 0, len(p.jobs))
+	for name, job := range p.jobs {
+		status := JobStatus{Name: name}
+		if !job.lastSuccess.IsZero() {
+			t := job.lastSuccess
+			status.LastSuccess = &t
+		}
+		if job.lastError != "" {
+			msg, t := job.lastError, job.lastErrorAt
+			status.LastError, status.LastErrorAt = &msg, &t
+		}
+		if p.scheduler != nil {
+			if next := p.scheduler.Entry(job.entry).Next; !next.IsZero() {
+				status.NextRun = &next
+			}
+		}
+		jobs = append(jobs, status)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// jobsHandler returns the last successful run, last error and next run of
+// every cron job
+func jobsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]JobStatus{"jobs": processor.Jobs()})
+}
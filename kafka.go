@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher publishes readings to a Kafka topic in the background.
+// Messages are queued in a bounded buffer; when the buffer is full new
+// messages are dropped so a slow broker never blocks processing.
+type KafkaPublisher struct {
+	writer *kafka.Writer
+	queue  chan kafka.Message
+	done   chan struct{}
+}
+
+var kafkaPublisher *KafkaPublisher
+
+// newKafkaPublisher creates a publisher for the configured brokers and topic
+func newKafkaPublisher(brokers []string, topic string, bufferSize int) *KafkaPublisher {
+	p := &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireOne,
+			WriteTimeout: 10 * time.Second,
+		},
+		queue: make(chan kafka.Message, bufferSize),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *KafkaPublisher) run() {
+	defer close(p.done)
+	for msg := range p.queue {
+		if err := p.writer.WriteMessages(context.Background(), msg); err != nil {
+			slog.Warn("Failed to publish reading to Kafka", "component", "kafka", "error", err)
+		}
+	}
+}
+
+// Publish queues a reading for publishing without blocking
+func (p *KafkaPublisher) Publish(reading StoredReading) {
+	value, err := json.Marshal(reading)
+	if err != nil {
+		slog.Warn("Failed to encode Kafka message", "component", "kafka", "error", err)
+		return
+	}
+
+	msg := kafka.Message{
+		Key:   []byte(strconv.FormatInt(reading.MeasuredAt.Unix(), 10)),
+		Value: value,
+	}
+	if dryRunSkip("kafka", "key", string(msg.Key), "value", string(value)) {
+		return
+	}
+
+	select {
+	case p.queue <- msg:
+	default:
+		slog.Warn("Kafka buffer full, dropping reading", "component", "kafka")
+	}
+}
+
+// Close flushes the queued messages and closes the writer
+func (p *KafkaPublisher) Close() error {
+	close(p.queue)
+	<-p.done
+	return p.writer.Close()
+}
+
+// parseBrokers splits a comma-separated broker list
+func parseBrokers(value string) []string {
+	var brokers []string
+	for _, b := range strings.Split(value, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			brokers = append(brokers, b)
+		}
+	}
+	return brokers
+}
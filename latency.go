@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// processingLatency returns the ingest lag between the measurement and the
+// insert in milliseconds. A negative lag means the sensor clock is ahead of
+// the server, it is clamped to zero.
+func processingLatency(measuredAt, insertedAt time.Time) int64 {
+	latency := insertedAt.Sub(measuredAt).Milliseconds()
+	if latency < 0 {
+		slog.Warn("Reading measured in the future, sensor clock is ahead; storing latency 0", "component", "processor", "ahead_ms", -latency)
+		return 0
+	}
+	return latency
+}
+
+// dailyLatencyP95 returns the 95th percentile of processing latency for the
+// given date using the nearest-rank method. ok is false when the day has no
+// latency samples.
+func dailyLatencyP95(db dbExecutor, date string) (p95 int64, ok bool, err error) {
+	var count int
+	err = db.QueryRow(`
+		SELECT COUNT(*)
+		FROM weather
+		WHERE `+observationDay()+` = ? AND processing_latency_ms IS NOT NULL`+stationFilter(db)+`
+	`, date).Scan(&count)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to count latency samples: %w", err)
+	}
+	if count == 0 {
+		return 0, false, nil
+	}
+
+	rank := int(math.Ceil(0.95*float64(count))) - 1
+	err = db.QueryRow(`
+		SELECT processing_latency_ms
+		FROM weather
+		WHERE `+observationDay()+` = ? AND processing_latency_ms IS NOT NULL`+stationFilter(db)+`
+		ORDER BY processing_latency_ms
+		LIMIT 1 OFFSET ?
+	`, date, rank).Scan(&p95)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to query latency p95: %w", err)
+	}
+	return p95, true, nil
+}
+
+// updateDailyLatencyP95 stores the daily processing latency p95
+func updateDailyLatencyP95(db dbExecutor, date string) error {
+	p95, ok, err := dailyLatencyP95(db, date)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	slog.Info("Processing latency p95", "component", "stats", "date", date, "p95_ms", p95)
+	_, err = db.Exec(`UPDATE weather_daily SET p95_processing_latency_ms = ? WHERE date = ?`+stationFilter(db), p95, date)
+	if err != nil {
+		return fmt.Errorf("failed to store latency p95: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Supported values of LOG_FORMAT
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// logOutput is where all log output is written; stdout unless LOG_FILE is set
+var logOutput io.Writer = os.Stdout
+
+// setupLogging configures the slog default logger from LOG_FORMAT and
+// LOG_LEVEL. The remaining log.Fatal calls for startup errors go through the
+// same handler. Logging goes to a size-rotated file when LOG_FILE
+// is set; rotated files are gzip-compressed unless LOG_COMPRESS=false.
+func setupLogging() {
+	level, err := parseLogLevel(config.LogLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if config.LogFile != "" {
+		logOutput = &lumberjack.Logger{
+			Filename:   config.LogFile,
+			MaxSize:    config.LogMaxSizeMB,
+			MaxBackups: config.LogMaxBackups,
+			Compress:   config.LogCompress,
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch config.LogFormat {
+	case LogFormatJSON:
+		handler = slog.NewJSONHandler(logOutput, opts)
+	case LogFormatText:
+		handler = slog.NewTextHandler(logOutput, opts)
+	default:
+		log.Fatalf("LOG_FORMAT must be %q or %q, got %q", LogFormatText, LogFormatJSON, config.LogFormat)
+	}
+	slog.SetDefault(slog.New(handler))
+
+	if config.LogFile != "" {
+		slog.Info("Logging to file", "component", "main", "file", config.LogFile,
+			"max_size_mb", config.LogMaxSizeMB, "max_backups", config.LogMaxBackups)
+	}
+}
+
+// parseLogLevel accepts debug, info, warn and error
+func parseLogLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", value)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// weather_long stores each reading in long/narrow form, one row per metric:
+// (station_id, measured_at, metric, value). BI tools can pivot it without
+// knowing the wide schema.
+
+// longRow is one metric value of a reading
+type longRow struct {
+	Metric string
+	Value  float64
+}
+
+// longFormatRows fans out the columns of a weather insert into one row per
+// numeric metric. measured_at, missing values and non-numeric columns are
+// skipped; flags are stored as 0/1.
+func longFormatRows(columns []string, values []any) []longRow {
+	var rows []longRow
+	for i, column := range columns {
+		if column == "measured_at" {
+			continue
+		}
+		var value float64
+		switch v := values[i].(type) {
+		case float64:
+			value = v
+		case *float64:
+			if v == nil {
+				continue
+			}
+			value = *v
+		case int:
+			value = float64(v)
+		case int64:
+			value = float64(v)
+		case *int64:
+			if v == nil {
+				continue
+			}
+			value = float64(*v)
+		case bool:
+			if v {
+				value = 1
+			}
+		default:
+			continue
+		}
+		rows = append(rows, longRow{Metric: column, Value: value})
+	}
+	return rows
+}
+
+// insertLongFormat writes the rows of one reading of the station of db into
+// weather_long
+func insertLongFormat(db dbExecutor, measuredAt time.Time, rows []longRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	station := stationOf(db)
+	tuples := make([]string, 0, len(rows))
+	args := make([]any, 0, len(rows)*4)
+	for _, r := range rows {
+		tuples = append(tuples, "(?, ?, ?, ?)")
+		args = append(args, station, measuredAt, r.Metric, r.Value)
+	}
+
+	query := `INSERT INTO weather_long (station_id, measured_at, metric, value) VALUES ` + strings.Join(tuples, ", ") +
+		` ON DUPLICATE KEY UPDATE value = VALUES(value)`
+	if _, err := db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to write long format rows: %w", err)
+	}
+	return nil
+}
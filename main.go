@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"math"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/joho/godotenv"
 	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WeatherData represents the structure of the weather.json file
@@ -20,17 +29,258 @@ type WeatherData struct {
 	Temperature float64 `json:"temperature"`
 	Pressure    float64 `json:"pressure"`
 	Humidity    float64 `json:"humidity"`
+
+	SolarRadiation *float64 `json:"solar_radiation,omitempty"`
+
+	// Cumulative rain gauge counter in mm, nil without a gauge
+	RainMM *float64 `json:"rain_mm,omitempty"`
+	// Instantaneous rain rate in mm/h, if the gauge reports it
+	RainRate *float64 `json:"rain_rate,omitempty"`
+
+	// Temperature of a second sensor mounted higher, for inversion detection
+	TemperatureElevated *float64 `json:"temperature_elevated,omitempty"`
+
+	// Wind speed in m/s and direction in degrees (0-360, from north)
+	WindSpeed     float64 `json:"wind_speed"`
+	WindDirection float64 `json:"wind_direction"`
+
+	// Air quality, nil for stations without the probes
+	CO2  *float64 `json:"co2,omitempty"`  // ppm
+	PM25 *float64 `json:"pm25,omitempty"` // µg/m³
+	PM10 *float64 `json:"pm10,omitempty"` // µg/m³
+
+	// Extra holds the EXTRA_METRICS values, nil when missing
+	Extra map[string]*float64 `json:"-"`
+
+	// Source identifies the sensor in mixed fleets, see SOURCE_UNITS
+	Source string `json:"source,omitempty"`
+
+	// StationID is the station of a reading fetched from JSON_SOURCE_URLS
+	StationID string `json:"-"`
+
+	// Raw is the payload the reading was parsed from, kept for the archive
+	Raw []byte `json:"-"`
+
+	// Missing marks the plain fields that held a SENSOR_SENTINELS value;
+	// they are stored as NULL
+	Missing map[string]bool `json:"-"`
+}
+
+// Supported values of HUMIDITY_SCALE
+const (
+	HumidityScalePercent  = "percent"
+	HumidityScaleFraction = "fraction"
+)
+
+// StoredReading is a reading as stored in the weather table, used for the
+// API and for outbound publishing
+type StoredReading struct {
+	ID          int64     `json:"id"`
+	MeasuredAt  time.Time `json:"measured_at"`
+	Temperature *float64  `json:"temperature"`
+	Pressure    *float64  `json:"pressure"`
+	Humidity    *float64  `json:"humidity"`
 }
 
 // Config holds application configuration from environment variables
 type Config struct {
-	JSONFilePath string
-	DBUser       string
-	DBPassword   string
-	DBHost       string
-	DBPort       string
-	DBName       string
-	CronSchedule string
+	JSONFilePath    string
+	DBUser          string
+	DBPassword      string
+	DBHost          string
+	DBPort          string
+	DBName          string
+	CronSchedule    string
+	RunOnStart      bool
+	HTTPPort        string
+	APIPageSize     int
+	APIToken        string
+	AdminToken      string
+	EnableDashboard bool
+	Location        *time.Location
+	ReadOnly        bool
+	DryRun          bool
+
+	DBMaxOpenConns           int
+	DBMaxIdleConns           int
+	DBConnMaxLifetimeMinutes int
+	DBReconnectAfterFailures int
+	MaxDBRetries             int
+	DBRetryBaseMS            int
+
+	DBDriver  string
+	DBSSLMode string
+
+	DayBoundaryHour int
+
+	S3Region         string
+	JSONSourceURL    string
+	JSONSourceURLs   string
+	HTTPFetchTimeout int
+	FetchConcurrency int
+
+	SQLiteMirrorPath string
+
+	SkipSchemaCheck bool
+	SchemaCheckMode string
+
+	LogFile       string
+	LogMaxSizeMB  int
+	LogMaxBackups int
+	LogCompress   bool
+	LogLevel      string
+	LogFormat     string
+
+	ShutdownSummary bool
+
+	HumidityScale    string
+	StrictValidation bool
+	SourceUnits      string
+	ExtraMetrics     string
+	RulesFile        string
+	SensorSentinels  string
+
+	StationID           string
+	StationName         string
+	StationLatitude     *float64
+	StationLongitude    *float64
+	StationAltitude     float64
+	StationSensorModel  string
+	StationInstalled    string
+	SaveStationMetadata bool
+
+	PersistProcessorState bool
+
+	InputPressureType  string
+	RefStationAltitude float64
+	EnableRefPressure  bool
+
+	EnablePressureChangePctile bool
+	PressureChangeMinSamples   int
+	PressureChangeAlertPctile  float64
+
+	EnablePressureTendency bool
+	PressureTendencySteady float64
+
+	ReadStrategy      string
+	ReadVerifyDelayMS int
+	InputEncoding     string
+	TimestampFields   string
+	TimestampLayout   string
+
+	CarryForwardOnMissing bool
+	CarryForwardMaxAgeMin int
+
+	StoreProcessingLatency bool
+	EnableArchive          bool
+	LongFormat             bool
+	TSDBEnabled            bool
+
+	MaintenanceWindow string
+	CatchupRatePerSec float64
+
+	RoundDecimals                int
+	StoreFullPrecisionAggregates bool
+	SplitDSTHours                bool
+	UseRunningTotals             bool
+	EnableMovingAverages         bool
+	EnableExclusions             bool
+	BatchAggregateWrites         bool
+	RecomputeLateData            bool
+	AlertOnEmptyPeriod           bool
+
+	EnablePeriodTotals bool
+	GDDBase            float64
+	HDDBase            float64
+	CDDBase            float64
+
+	EnableGrowingSeason bool
+	SeasonBaseTemp      float64
+	SeasonStreakDays    int
+
+	EnableFrostFreePeriod bool
+	FrostThreshold        float64
+
+	EnableDriftReport         bool
+	DriftTrendMonths          int
+	DriftThresholdTemperature float64
+	DriftThresholdPressure    float64
+	DriftThresholdHumidity    float64
+
+	EnableInversion          bool
+	InversionThreshold       float64
+	InversionStrongThreshold float64
+	InversionPersistMinutes  int
+
+	EnableWind       bool
+	EnableDewPoint   bool
+	EnableAirQuality bool
+
+	EnableMixingRatio bool
+	EnableFeelsLike   bool
+
+	EnableRainfall   bool
+	EnableRainDays   bool
+	EnableRainRate   bool
+	RainDayThreshold float64
+
+	EnableRainStreaks bool
+	WetDayThreshold   float64
+
+	EnableComfortBands    bool
+	ComfortColdMax        float64
+	ComfortCoolMax        float64
+	ComfortComfortableMax float64
+	ComfortWarmMax        float64
+
+	HourlyUpdateMinInterval int
+
+	EnableTempBands bool
+	TempBandEdges   string
+
+	EnableGriddedAverage bool
+	GridIntervalMinutes  int
+
+	EnableSunshineHours bool
+	SunshineThreshold   float64
+
+	KafkaBrokers    string
+	KafkaTopic      string
+	KafkaBufferSize int
+
+	TelegramBotToken string
+	TelegramChatID   string
+
+	AlertTempMin    *float64
+	AlertTempMax    *float64
+	AlertWebhookURL string
+
+	TextfilePath string
+
+	CurrentJSONPath string
+	DailyJSONPath   string
+	DailyJSONDays   int
+
+	StatsdAddr   string
+	StatsdPrefix string
+	StatsdTags   string
+
+	EnableWeatherType             bool
+	WeatherTypeRainyMM            float64
+	WeatherTypeStormyMM           float64
+	WeatherTypeStormyPressureDrop float64
+	WeatherTypeFallingPressure    float64
+	WeatherTypeRainyHumidity      float64
+	WeatherTypeFoggyHumidity      float64
+	WeatherTypeCloudyHumidity     float64
+	WeatherTypeSunnyAmplitude     float64
+
+	EnableChangeIndex            bool
+	ChangeIndexTemperatureWeight float64
+	ChangeIndexPressureWeight    float64
+	ChangeIndexHumidityWeight    float64
+
+	EnableMoonPhase bool
 }
 
 // getEnv retrieves an environment variable or returns a default value
@@ -38,181 +288,1515 @@ func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
-	return defaultValue
-}
+	return defaultValue
+}
+
+// getEnvBool retrieves a boolean environment variable or returns a default value
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("Invalid boolean value, using default", "component", "main", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvInt retrieves an integer environment variable or returns a default value
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Invalid integer value, using default", "component", "main", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvFloat retrieves a float environment variable or returns a default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("Invalid number, using default", "component", "main", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvFloatPtr retrieves an optional float environment variable, nil when
+// it is unset or invalid
+func getEnvFloatPtr(key string) *float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("Invalid number, ignoring", "component", "main", "key", key, "value", value)
+		return nil
+	}
+	return &parsed
+}
+
+// loadLocation resolves an IANA time zone name, falling back to UTC with a
+// warning if it is invalid. An empty name keeps the server's local zone.
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		slog.Warn("Invalid TIMEZONE, falling back to UTC", "component", "main", "timezone", name, "error", err)
+		return time.UTC
+	}
+	return loc
+}
+
+// loadConfig loads configuration from environment variables
+func loadConfig() Config {
+	return Config{
+		JSONFilePath:    getEnv("JSON_FILE_PATH", "/var/www/laravel-tene.life/public/files/weather.json"),
+		DBUser:          os.Getenv("DB_USER"),
+		DBPassword:      os.Getenv("DB_PASSWORD"),
+		DBHost:          getEnv("DB_HOST", "localhost"),
+		DBPort:          getEnv("DB_PORT", defaultDBPort(os.Getenv("DB_DRIVER"))),
+		DBName:          getEnv("DB_NAME", "tene_life"),
+		CronSchedule:    getEnv("CRON_SCHEDULE", "*/5 * * * *"),
+		RunOnStart:      getEnvBool("RUN_ON_START", true),
+		HTTPPort:        os.Getenv("HTTP_PORT"),
+		APIPageSize:     getEnvInt("API_PAGE_SIZE", 500),
+		APIToken:        os.Getenv("API_TOKEN"),
+		AdminToken:      os.Getenv("ADMIN_TOKEN"),
+		EnableDashboard: getEnvBool("ENABLE_DASHBOARD", false),
+		Location:        loadLocation(os.Getenv("TIMEZONE")),
+		ReadOnly:        getEnvBool("READONLY", false),
+		DryRun:          getEnvBool("DRY_RUN", false),
+
+		DBMaxOpenConns:           getEnvInt("DB_MAX_OPEN_CONNS", 10),
+		DBMaxIdleConns:           getEnvInt("DB_MAX_IDLE_CONNS", 5),
+		DBConnMaxLifetimeMinutes: getEnvInt("DB_CONN_MAX_LIFETIME_MINUTES", 5),
+		DBReconnectAfterFailures: getEnvInt("DB_RECONNECT_AFTER_FAILURES", 3),
+		MaxDBRetries:             getEnvInt("MAX_DB_RETRIES", 3),
+		DBRetryBaseMS:            getEnvInt("DB_RETRY_BASE_MS", 200),
+
+		DBDriver:  getEnv("DB_DRIVER", DBDriverMySQL),
+		DBSSLMode: getEnv("DB_SSLMODE", "disable"),
+
+		DayBoundaryHour: getEnvInt("DAY_BOUNDARY_HOUR", 0),
+
+		S3Region:         os.Getenv("S3_REGION"),
+		JSONSourceURL:    os.Getenv("JSON_SOURCE_URL"),
+		JSONSourceURLs:   os.Getenv("JSON_SOURCE_URLS"),
+		HTTPFetchTimeout: getEnvInt("HTTP_FETCH_TIMEOUT", 10),
+		FetchConcurrency: getEnvInt("FETCH_CONCURRENCY", 4),
+
+		SQLiteMirrorPath: os.Getenv("SQLITE_MIRROR_PATH"),
+
+		SkipSchemaCheck: getEnvBool("SKIP_SCHEMA_CHECK", false),
+		SchemaCheckMode: getEnv("SCHEMA_CHECK_MODE", SchemaCheckFail),
+
+		LogFile:       os.Getenv("LOG_FILE"),
+		LogMaxSizeMB:  getEnvInt("LOG_MAX_SIZE_MB", 100),
+		LogMaxBackups: getEnvInt("LOG_MAX_BACKUPS", 5),
+		LogCompress:   getEnvBool("LOG_COMPRESS", true),
+		LogLevel:      getEnv("LOG_LEVEL", "info"),
+		LogFormat:     getEnv("LOG_FORMAT", LogFormatText),
+
+		ShutdownSummary: getEnvBool("SHUTDOWN_SUMMARY", false),
+
+		HumidityScale:    getEnv("HUMIDITY_SCALE", HumidityScalePercent),
+		StrictValidation: getEnvBool("STRICT_VALIDATION", true),
+		SourceUnits:      os.Getenv("SOURCE_UNITS"),
+		ExtraMetrics:     os.Getenv("EXTRA_METRICS"),
+		RulesFile:        os.Getenv("RULES_FILE"),
+		SensorSentinels:  os.Getenv("SENSOR_SENTINELS"),
+
+		StationID:           getEnv("STATION_ID", defaultStationID),
+		StationName:         os.Getenv("STATION_NAME"),
+		StationLatitude:     getEnvFloatPtr("STATION_LATITUDE"),
+		StationLongitude:    getEnvFloatPtr("STATION_LONGITUDE"),
+		StationAltitude:     getEnvFloat("STATION_ALTITUDE", 0),
+		StationSensorModel:  os.Getenv("STATION_SENSOR_MODEL"),
+		StationInstalled:    os.Getenv("STATION_INSTALLED"),
+		SaveStationMetadata: getEnvBool("SAVE_STATION_METADATA", false),
+
+		PersistProcessorState: getEnvBool("PERSIST_PROCESSOR_STATE", false),
+
+		InputPressureType:  getEnv("INPUT_PRESSURE_TYPE", PressureTypeStation),
+		RefStationAltitude: getEnvFloat("REF_STATION_ALTITUDE", 0),
+		EnableRefPressure:  os.Getenv("REF_STATION_ALTITUDE") != "",
+
+		EnablePressureChangePctile: getEnvBool("ENABLE_PRESSURE_CHANGE_PCTILE", false),
+		PressureChangeMinSamples:   getEnvInt("PRESSURE_CHANGE_MIN_SAMPLES", 288),
+		PressureChangeAlertPctile:  getEnvFloat("PRESSURE_CHANGE_ALERT_PCTILE", 95),
+
+		EnablePressureTendency: getEnvBool("ENABLE_PRESSURE_TENDENCY", false),
+		PressureTendencySteady: getEnvFloat("PRESSURE_TENDENCY_STEADY", 0.2),
+
+		ReadStrategy:      getEnv("READ_STRATEGY", ReadStrategyReread),
+		ReadVerifyDelayMS: getEnvInt("READ_VERIFY_DELAY_MS", 50),
+		InputEncoding:     getEnv("INPUT_ENCODING", "utf-8"),
+		TimestampFields:   getEnv("TIMESTAMP_FIELDS", "timestamp"),
+		TimestampLayout:   getEnv("TIMESTAMP_LAYOUT", "2006-01-02 15:04:05"),
+
+		CarryForwardOnMissing: getEnvBool("CARRY_FORWARD_ON_MISSING", false),
+		CarryForwardMaxAgeMin: getEnvInt("CARRY_FORWARD_MAX_AGE_MINUTES", 15),
+
+		StoreProcessingLatency: getEnvBool("STORE_PROCESSING_LATENCY", false),
+		EnableArchive:          getEnvBool("ENABLE_ARCHIVE", false),
+		LongFormat:             getEnvBool("LONG_FORMAT", false),
+		TSDBEnabled:            getEnvBool("TSDB_ENABLED", false),
+
+		MaintenanceWindow: os.Getenv("MAINTENANCE_WINDOW"),
+		CatchupRatePerSec: getEnvFloat("CATCHUP_RATE_PER_SEC", 0),
+
+		RoundDecimals:                getEnvInt("ROUND_DECIMALS", defaultRoundDecimals),
+		StoreFullPrecisionAggregates: getEnvBool("STORE_FULL_PRECISION_AGGREGATES", false),
+		SplitDSTHours:                getEnvBool("DST_SPLIT_REPEATED_HOUR", false),
+		UseRunningTotals:             getEnvBool("USE_RUNNING_TOTALS", false),
+		EnableMovingAverages:         getEnvBool("ENABLE_MOVING_AVERAGES", false),
+		EnableExclusions:             getEnvBool("ENABLE_EXCLUSIONS", false),
+		BatchAggregateWrites:         getEnvBool("BATCH_AGGREGATE_WRITES", false),
+		RecomputeLateData:            getEnvBool("RECOMPUTE_LATE_DATA", false),
+		AlertOnEmptyPeriod:           getEnvBool("ALERT_ON_EMPTY_PERIOD", false),
+
+		EnablePeriodTotals: getEnvBool("ENABLE_PERIOD_TOTALS", false),
+		GDDBase:            getEnvFloat("GDD_BASE", 10.0),
+		HDDBase:            getEnvFloat("HDD_BASE", 18.0),
+		CDDBase:            getEnvFloat("CDD_BASE", 18.0),
+
+		EnableGrowingSeason: getEnvBool("ENABLE_GROWING_SEASON", false),
+		SeasonBaseTemp:      getEnvFloat("SEASON_BASE_TEMP", 5.0),
+		SeasonStreakDays:    getEnvInt("SEASON_STREAK_DAYS", 6),
+
+		EnableFrostFreePeriod: getEnvBool("ENABLE_FROST_FREE_PERIOD", false),
+		FrostThreshold:        getEnvFloat("FROST_THRESHOLD", 0.0),
+
+		EnableDriftReport:         getEnvBool("ENABLE_DRIFT_REPORT", false),
+		DriftTrendMonths:          getEnvInt("DRIFT_TREND_MONTHS", 12),
+		DriftThresholdTemperature: getEnvFloat("DRIFT_THRESHOLD_TEMPERATURE", 0.1),
+		DriftThresholdPressure:    getEnvFloat("DRIFT_THRESHOLD_PRESSURE", 0.2),
+		DriftThresholdHumidity:    getEnvFloat("DRIFT_THRESHOLD_HUMIDITY", 0.5),
+
+		EnableInversion:          getEnvBool("ENABLE_INVERSION", false),
+		InversionThreshold:       getEnvFloat("INVERSION_THRESHOLD", 0.5),
+		InversionStrongThreshold: getEnvFloat("INVERSION_STRONG_THRESHOLD", 2.0),
+		InversionPersistMinutes:  getEnvInt("INVERSION_PERSIST_MINUTES", 60),
+
+		EnableWind:       getEnvBool("ENABLE_WIND", false),
+		EnableDewPoint:   getEnvBool("ENABLE_DEW_POINT", false),
+		EnableAirQuality: getEnvBool("ENABLE_AIR_QUALITY", false),
+
+		EnableMixingRatio: getEnvBool("ENABLE_MIXING_RATIO", false),
+		EnableFeelsLike:   getEnvBool("ENABLE_FEELS_LIKE", false),
+
+		EnableRainfall:   getEnvBool("ENABLE_RAINFALL", false),
+		EnableRainDays:   getEnvBool("ENABLE_RAIN_DAYS", false),
+		EnableRainRate:   getEnvBool("ENABLE_RAIN_RATE", false),
+		RainDayThreshold: getEnvFloat("RAIN_DAY_THRESHOLD", 1.0),
+
+		EnableRainStreaks: getEnvBool("ENABLE_RAIN_STREAKS", false),
+		WetDayThreshold:   getEnvFloat("WET_DAY_THRESHOLD", 1.0),
+
+		EnableComfortBands:    getEnvBool("ENABLE_COMFORT_BANDS", false),
+		ComfortColdMax:        getEnvFloat("COMFORT_COLD_MAX", 10.0),
+		ComfortCoolMax:        getEnvFloat("COMFORT_COOL_MAX", 18.0),
+		ComfortComfortableMax: getEnvFloat("COMFORT_COMFORTABLE_MAX", 25.0),
+		ComfortWarmMax:        getEnvFloat("COMFORT_WARM_MAX", 30.0),
+
+		HourlyUpdateMinInterval: getEnvInt("HOURLY_UPDATE_MIN_INTERVAL", 0),
+
+		EnableTempBands: getEnvBool("ENABLE_TEMP_BANDS", false),
+		TempBandEdges:   getEnv("TEMP_BAND_EDGES", "0,10,20,30"),
+
+		EnableGriddedAverage: getEnvBool("ENABLE_GRIDDED_AVERAGE", false),
+		GridIntervalMinutes:  getEnvInt("GRID_INTERVAL_MINUTES", 10),
+
+		EnableSunshineHours: getEnvBool("ENABLE_SUNSHINE_HOURS", false),
+		SunshineThreshold:   getEnvFloat("SUNSHINE_THRESHOLD", 120.0),
+
+		KafkaBrokers:    os.Getenv("KAFKA_BROKERS"),
+		KafkaTopic:      os.Getenv("KAFKA_TOPIC"),
+		KafkaBufferSize: getEnvInt("KAFKA_BUFFER_SIZE", 100),
+
+		TelegramBotToken: os.Getenv("TELEGRAM_BOT_TOKEN"),
+		TelegramChatID:   os.Getenv("TELEGRAM_CHAT_ID"),
+
+		AlertTempMin:    getEnvFloatPtr("ALERT_TEMP_MIN"),
+		AlertTempMax:    getEnvFloatPtr("ALERT_TEMP_MAX"),
+		AlertWebhookURL: os.Getenv("ALERT_WEBHOOK_URL"),
+
+		TextfilePath: os.Getenv("TEXTFILE_PATH"),
+
+		CurrentJSONPath: os.Getenv("CURRENT_JSON_PATH"),
+		DailyJSONPath:   os.Getenv("DAILY_JSON_PATH"),
+		DailyJSONDays:   getEnvInt("DAILY_JSON_DAYS", 30),
+
+		StatsdAddr:   os.Getenv("STATSD_ADDR"),
+		StatsdPrefix: getEnv("STATSD_PREFIX", "weather."),
+		StatsdTags:   os.Getenv("STATSD_TAGS"),
+
+		EnableWeatherType:             getEnvBool("ENABLE_WEATHER_TYPE", false),
+		WeatherTypeRainyMM:            getEnvFloat("WEATHER_TYPE_RAINY_MM", 1.0),
+		WeatherTypeStormyMM:           getEnvFloat("WEATHER_TYPE_STORMY_MM", 20.0),
+		WeatherTypeStormyPressureDrop: getEnvFloat("WEATHER_TYPE_STORMY_PRESSURE_DROP", 6.0),
+		WeatherTypeFallingPressure:    getEnvFloat("WEATHER_TYPE_FALLING_PRESSURE", 2.0),
+		WeatherTypeRainyHumidity:      getEnvFloat("WEATHER_TYPE_RAINY_HUMIDITY", 90.0),
+		WeatherTypeFoggyHumidity:      getEnvFloat("WEATHER_TYPE_FOGGY_HUMIDITY", 95.0),
+		WeatherTypeCloudyHumidity:     getEnvFloat("WEATHER_TYPE_CLOUDY_HUMIDITY", 75.0),
+		WeatherTypeSunnyAmplitude:     getEnvFloat("WEATHER_TYPE_SUNNY_AMPLITUDE", 8.0),
+
+		EnableChangeIndex:            getEnvBool("ENABLE_CHANGE_INDEX", false),
+		ChangeIndexTemperatureWeight: getEnvFloat("CHANGE_INDEX_TEMPERATURE_WEIGHT", 1.0),
+		ChangeIndexPressureWeight:    getEnvFloat("CHANGE_INDEX_PRESSURE_WEIGHT", 1.0),
+		ChangeIndexHumidityWeight:    getEnvFloat("CHANGE_INDEX_HUMIDITY_WEIGHT", 1.0),
+
+		EnableMoonPhase: getEnvBool("ENABLE_MOON_PHASE", false),
+	}
+}
+
+var config Config
+
+// errReadOnly is returned by write paths when running in READONLY mode
+var errReadOnly = errors.New("write attempted in read-only mode")
+
+func main() {
+	os.Exit(run())
+}
+
+// run is the body of main. It returns the exit code, so the deferred
+// cleanups (closing the database pool and the SQLite mirror) run before the
+// process exits.
+func run() int {
+	rebuildRunning := flag.Bool("rebuild-running", false, "rebuild weather_running from the raw weather table and exit")
+	exclude := flag.String("exclude", "", "exclude START/END (RFC3339) from statistics, recompute affected aggregates and exit")
+	excludeReason := flag.String("exclude-reason", "", "reason stored with -exclude")
+	syncSQLite := flag.Bool("sync-sqlite", false, "push readings not yet synced from the SQLite mirror to MySQL and exit")
+	once := flag.Bool("once", false, "process the current reading once and exit with a non-zero status on failure, for external schedulers")
+	onceAggregate := flag.String("aggregate", "", "with -once, also compute daily, weekly, monthly or yearly statistics")
+	backfillDailyRange := flag.String("backfill-daily", "", "compute daily statistics for every day of FROM:TO (YYYY-MM-DD) and exit")
+	backfillWeeklyRange := flag.String("backfill-weekly", "", "compute weekly statistics for every week overlapping FROM:TO and exit")
+	backfillMonthlyRange := flag.String("backfill-monthly", "", "compute monthly statistics for every month overlapping FROM:TO and exit")
+	importDirPath := flag.String("import-dir", "", "import every .json reading below DIR in batched transactions, recompute the aggregates of the imported range and exit")
+	importBatchSize := flag.Int("import-batch-size", 500, "readings per transaction with -import-dir")
+	exportTable := flag.String("export", "", "write raw, hourly, daily, weekly or monthly rows between -from and -to as CSV to -out and exit")
+	exportFrom := flag.String("from", "", "first date (YYYY-MM-DD) of -export")
+	exportTo := flag.String("to", "", "last date (YYYY-MM-DD) of -export, inclusive")
+	exportOut := flag.String("out", "", "CSV file written by -export (empty = stdout)")
+	healthcheck := flag.Bool("healthcheck", false, "ping the database, check that the JSON source is readable and exit with a non-zero status on failure, for liveness probes")
+	flag.Parse()
+
+	slog.Info("Weather data processor started", "component", "main")
+
+	if err := godotenv.Load(); err != nil {
+		slog.Info("No .env file found, using environment variables from system", "component", "main")
+	} else {
+		slog.Info("Loaded configuration from .env file", "component", "main")
+	}
+
+	config = loadConfig()
+	setupLogging()
+
+	if config.DBUser == "" {
+		log.Fatal("DB_USER environment variable is required")
+	}
+	if config.DBPassword == "" {
+		log.Fatal("DB_PASSWORD environment variable is required")
+	}
+	if config.HumidityScale != HumidityScalePercent && config.HumidityScale != HumidityScaleFraction {
+		log.Fatalf("HUMIDITY_SCALE must be %q or %q, got %q", HumidityScalePercent, HumidityScaleFraction, config.HumidityScale)
+	}
+	switch config.ReadStrategy {
+	case ReadStrategySingle, ReadStrategyReread, ReadStrategyMarker:
+	default:
+		log.Fatalf("READ_STRATEGY must be %q, %q or %q, got %q",
+			ReadStrategySingle, ReadStrategyReread, ReadStrategyMarker, config.ReadStrategy)
+	}
+	if units, err := parseSourceUnits(config.SourceUnits); err != nil {
+		log.Fatalf("Invalid SOURCE_UNITS: %v", err)
+	} else {
+		sourceUnits = units
+	}
+	if enc, err := parseInputEncoding(config.InputEncoding); err != nil {
+		log.Fatalf("Invalid INPUT_ENCODING: %v", err)
+	} else {
+		inputEncoding = enc
+	}
+	timestampFields = parseTimestampFieldList(config.TimestampFields)
+	if len(timestampFields) == 0 {
+		log.Fatal("TIMESTAMP_FIELDS must list at least one field")
+	}
+	if metrics, err := parseExtraMetrics(config.ExtraMetrics); err != nil {
+		log.Fatalf("Invalid EXTRA_METRICS: %v", err)
+	} else {
+		extraMetrics = metrics
+	}
+	if sentinels, err := parseSensorSentinels(config.SensorSentinels); err != nil {
+		log.Fatalf("Invalid SENSOR_SENTINELS: %v", err)
+	} else {
+		sensorSentinels = sentinels
+	}
+	if config.RulesFile != "" {
+		rules, err := loadValidationRules(config.RulesFile)
+		if err != nil {
+			log.Fatalf("Invalid RULES_FILE: %v", err)
+		}
+		ruleEngine = newRuleEngine(rules)
+		slog.Info("Loaded validation rules", "component", "main", "rules", len(rules), "file", config.RulesFile)
+	}
+	if config.EnableTempBands {
+		if edges, err := parseTempBandEdges(config.TempBandEdges); err != nil {
+			log.Fatalf("Invalid TEMP_BAND_EDGES: %v", err)
+		} else {
+			tempBandEdges = edges
+		}
+	}
+	if config.EnableGriddedAverage && (config.GridIntervalMinutes < 1 || config.GridIntervalMinutes > 1440) {
+		log.Fatalf("GRID_INTERVAL_MINUTES must be between 1 and 1440, got %d", config.GridIntervalMinutes)
+	}
+	if config.RoundDecimals < 0 || config.RoundDecimals > maxRoundDecimals {
+		slog.Warn("ROUND_DECIMALS out of range, using the default", "component", "main",
+			"value", config.RoundDecimals, "max", maxRoundDecimals, "default", defaultRoundDecimals)
+		config.RoundDecimals = defaultRoundDecimals
+	}
+	if config.DailyJSONPath != "" && (config.DailyJSONDays < 1 || config.DailyJSONDays > maxDailyRangeDays) {
+		log.Fatalf("DAILY_JSON_DAYS must be between 1 and %d, got %d", maxDailyRangeDays, config.DailyJSONDays)
+	}
+	if columns, err := parseDerivedColumns(os.Environ()); err != nil {
+		log.Fatalf("Invalid derived column: %v", err)
+	} else {
+		derivedColumns = columns
+	}
+	for _, c := range derivedColumns {
+		if config.EnableFeelsLike && c.Name == "feels_like" {
+			log.Fatal("DERIVED_EXPR_feels_like cannot be used with ENABLE_FEELS_LIKE=true")
+		}
+	}
+	if config.InputPressureType != PressureTypeStation && config.InputPressureType != PressureTypeSeaLevel {
+		log.Fatalf("INPUT_PRESSURE_TYPE must be %q or %q, got %q", PressureTypeStation, PressureTypeSeaLevel, config.InputPressureType)
+	}
+	if config.StationInstalled != "" {
+		if _, err := time.Parse("2006-01-02", config.StationInstalled); err != nil {
+			log.Fatalf("STATION_INSTALLED must be a YYYY-MM-DD date, got %q", config.StationInstalled)
+		}
+	}
+	if *onceAggregate != "" && !*once {
+		log.Fatal("-aggregate requires -once")
+	}
+	if *once && config.ReadOnly {
+		log.Fatal("-once cannot be used with READONLY=true")
+	}
+	if err := validateOnceAggregate(*onceAggregate); err != nil {
+		log.Fatalf("Invalid -aggregate: %v", err)
+	}
+	if *exportTable == "" && (*exportFrom != "" || *exportTo != "" || *exportOut != "") {
+		log.Fatal("-from, -to and -out require -export")
+	}
+	if config.DBDriver != DBDriverMySQL && config.DBDriver != DBDriverPostgres {
+		log.Fatalf("DB_DRIVER must be %q or %q, got %q", DBDriverMySQL, DBDriverPostgres, config.DBDriver)
+	}
+	if config.DayBoundaryHour < 0 || config.DayBoundaryHour > 23 {
+		log.Fatalf("DAY_BOUNDARY_HOUR must be between 0 and 23, got %d", config.DayBoundaryHour)
+	}
+	if config.SchemaCheckMode != SchemaCheckFail && config.SchemaCheckMode != SchemaCheckWarn {
+		log.Fatalf("SCHEMA_CHECK_MODE must be %q or %q, got %q", SchemaCheckFail, SchemaCheckWarn, config.SchemaCheckMode)
+	}
+
+	slog.Info("Loaded configuration", "component", "main",
+		"db_user", config.DBUser, "db_host", config.DBHost, "db_port", config.DBPort, "db_name", config.DBName,
+		"schedule", config.CronSchedule)
+	if config.DryRun {
+		slog.Info("Dry-run mode: database writes are logged, not executed", "component", "main")
+	}
+
+	if err := validateStationID(config.StationID); err != nil {
+		log.Fatalf("Invalid STATION_ID: %v", err)
+	}
+	// Before the one-off modes, which aggregate every JSON_SOURCE_URLS station
+	setupSource()
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalf("DB connect error: %v", err)
+	}
+	if *healthcheck {
+		return runHealthcheck(db)
+	}
+	if err := db.Ping(); err != nil {
+		// With the mirror readings are kept locally until MySQL is back
+		if config.SQLiteMirrorPath == "" || *syncSQLite {
+			log.Fatalf("Failed to ping database: %v", err)
+		}
+		slog.Warn("Failed to ping database, readings are kept in the SQLite mirror", "component", "db", "error", err)
+	}
+	pool := newReconnectingDB(db, config.DBReconnectAfterFailures)
+	dbPool = pool
+	defer pool.Close()
+
+	if config.SQLiteMirrorPath != "" {
+		m, err := openSQLiteMirror(config.SQLiteMirrorPath)
+		if err != nil {
+			log.Fatalf("Invalid SQLITE_MIRROR_PATH: %v", err)
+		}
+		mirror = m
+		defer mirror.Close()
+		slog.Info("Mirroring readings to SQLite", "component", "mirror", "path", config.SQLiteMirrorPath)
+	}
+
+	if !config.SkipSchemaCheck {
+		verifySchema(db)
+	}
+
+	if config.SaveStationMetadata && !config.ReadOnly {
+		if err := saveStationMetadata(db, stationMetadata()); err != nil {
+			slog.Warn("Failed to save station metadata", "component", "main", "error", err)
+		}
+	}
+
+	if *syncSQLite {
+		if mirror == nil {
+			log.Fatal("-sync-sqlite requires SQLITE_MIRROR_PATH")
+		}
+		synced, err := mirror.Sync(db)
+		if err != nil {
+			log.Fatalf("Error syncing SQLite mirror (%d readings synced): %v", synced, err)
+		}
+		slog.Info("Synced readings from the SQLite mirror", "component", "mirror", "synced", synced)
+		return 0
+	}
+
+	if *rebuildRunning {
+		rows, err := rebuildRunningTotals(db)
+		if err != nil {
+			log.Fatalf("Error rebuilding running totals: %v", err)
+		}
+		slog.Info("Running totals rebuilt", "component", "stats", "rows_affected", rows)
+		return 0
+	}
+
+	if *exclude != "" {
+		if !config.EnableExclusions {
+			log.Fatal("-exclude requires ENABLE_EXCLUSIONS=true")
+		}
+		start, end, err := parseExclusionRange(*exclude)
+		if err != nil {
+			log.Fatalf("Invalid -exclude: %v", err)
+		}
+
+		if err := addExclusion(db, start, end, *excludeReason); err != nil {
+			log.Fatalf("Error adding exclusion: %v", err)
+		}
+		if err := recomputeRange(db, start, end); err != nil {
+			log.Fatalf("Error recomputing aggregates: %v", err)
+		}
+		slog.Info("Excluded range and recomputed affected aggregates", "component", "stats", "range", *exclude)
+		return 0
+	}
+
+	if *exportTable != "" {
+		if *exportFrom == "" || *exportTo == "" {
+			log.Fatal("-export requires -from and -to")
+		}
+		from, to, err := parseDates(*exportFrom, *exportTo)
+		if err != nil {
+			log.Fatalf("Invalid -from/-to: %v", err)
+		}
+		rows, err := exportCSV(db, *exportTable, from, to, *exportOut)
+		if err != nil {
+			log.Fatalf("Error exporting %s (%d rows written): %v", *exportTable, rows, err)
+		}
+		slog.Info("Export finished", "component", "export", "table", *exportTable, "rows", rows)
+		return 0
+	}
+
+	if *importDirPath != "" {
+		if config.ReadOnly {
+			log.Fatal("-import-dir cannot be used with READONLY=true")
+		}
+		if *importBatchSize < 1 {
+			log.Fatal("-import-batch-size must be at least 1")
+		}
+		result, err := importDir(db, *importDirPath, *importBatchSize)
+		if err != nil {
+			log.Fatalf("Error importing %s (%d inserted so far): %v", *importDirPath, result.Inserted, err)
+		}
+		slog.Info("Import finished", "component", "import", "files", result.Files,
+			"inserted", result.Inserted, "duplicates", result.Duplicates, "skipped", result.Skipped)
+		return 0
+	}
+
+	// Daily statistics go first, weekly and monthly ones are computed from
+	// the raw readings but the optional totals are summed from daily rows
+	backfills := []struct {
+		flag  string
+		value string
+		run   func(*sql.DB, time.Time, time.Time) error
+	}{
+		{"backfill-daily", *backfillDailyRange, backfillDaily},
+		{"backfill-weekly", *backfillWeeklyRange, backfillWeekly},
+		{"backfill-monthly", *backfillMonthlyRange, backfillMonthly},
+	}
+	backfilled := false
+	for _, b := range backfills {
+		if b.value == "" {
+			continue
+		}
+		from, to, err := parseDateRange(b.value)
+		if err != nil {
+			log.Fatalf("Invalid -%s: %v", b.flag, err)
+		}
+		if err := b.run(db, from, to); err != nil {
+			log.Fatalf("Error in -%s: %v", b.flag, err)
+		}
+		backfilled = true
+	}
+	if backfilled {
+		return 0
+	}
+
+	if config.ReadOnly {
+		if config.HTTPPort == "" {
+			log.Fatal("HTTP_PORT is required in READONLY mode")
+		}
+		slog.Info("Read-only mode: serving the HTTP API only, no jobs are scheduled", "component", "main")
+		signals := notifyShutdown()
+		startHTTPServer(pool)
+
+		slog.Info("Shutting down", "component", "main", "signal", (<-signals).String())
+		shutdown(nil, pool)
+		return 0
+	}
+
+	if config.MaintenanceWindow != "" {
+		window, err := parseMaintenanceWindow(config.MaintenanceWindow)
+		if err != nil {
+			log.Fatalf("Invalid MAINTENANCE_WINDOW: %v", err)
+		}
+		maintenanceWindow = window
+		slog.Info("Maintenance window, readings will be buffered", "component", "main", "window", window.String(), "timezone", config.Location.String())
+	}
+
+	catchupLimiter = newCatchupLimiter(config.CatchupRatePerSec)
+	if catchupLimiter != nil {
+		slog.Info("Catch-up inserts limited", "component", "main", "rate_per_sec", config.CatchupRatePerSec)
+	}
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" {
+		if stop, err := initTracing(context.Background()); err != nil {
+			slog.Warn("Tracing disabled", "component", "main", "error", err)
+		} else {
+			shutdownTracing = stop
+			slog.Info("OpenTelemetry tracing enabled", "component", "main")
+		}
+	}
+
+	if config.HTTPPort != "" && !*once {
+		startHTTPServer(pool)
+	}
+
+	if config.KafkaBrokers != "" && config.KafkaTopic != "" {
+		kafkaPublisher = newKafkaPublisher(parseBrokers(config.KafkaBrokers), config.KafkaTopic, config.KafkaBufferSize)
+		slog.Info("Publishing readings to Kafka", "component", "kafka", "topic", config.KafkaTopic)
+	}
+
+	if config.HourlyUpdateMinInterval > 0 && !config.ReadOnly {
+		hourlyDebouncer = newHourlyDebouncer(time.Duration(config.HourlyUpdateMinInterval)*time.Second,
+			func(station string, t time.Time) error {
+				return updateHourlyAverages(forStation(pool.DB(), station), t)
+			})
+		slog.Info("Hourly averages debounced", "component", "main", "min_interval_s", config.HourlyUpdateMinInterval)
+	}
+
+	if config.TelegramBotToken != "" && config.TelegramChatID != "" {
+		telegramNotifier = newTelegramNotifier(config.TelegramBotToken, config.TelegramChatID)
+		notifiers = append(notifiers, telegramNotifier)
+		slog.Info("Sending alerts to Telegram", "component", "alert", "chat_id", config.TelegramChatID)
+	}
+
+	if config.AlertWebhookURL != "" && (config.AlertTempMin != nil || config.AlertTempMax != nil) {
+		if config.AlertTempMin != nil && config.AlertTempMax != nil && *config.AlertTempMin > *config.AlertTempMax {
+			log.Fatalf("ALERT_TEMP_MIN (%g) must not be above ALERT_TEMP_MAX (%g)", *config.AlertTempMin, *config.AlertTempMax)
+		}
+		tempAlerter = newThresholdAlerter(config.AlertWebhookURL)
+		slog.Info("Posting temperature threshold alerts to ALERT_WEBHOOK_URL", "component", "main")
+	}
+
+	if config.StatsdAddr != "" {
+		client, err := newStatsdClient(config.StatsdAddr, config.StatsdPrefix, parseTags(config.StatsdTags))
+		if err != nil {
+			slog.Warn("StatsD disabled", "component", "statsd", "error", err)
+		} else {
+			statsdClient = client
+			slog.Info("Sending statistics to StatsD", "component", "statsd", "addr", config.StatsdAddr)
+		}
+	}
+
+	if config.PersistProcessorState {
+		timestamps, err := loadProcessorState(db)
+		if err != nil {
+			slog.Warn("Failed to load processor state", "component", "main", "error", err)
+		} else {
+			resume.Set(timestamps)
+			slog.Info("Loaded last processed timestamps", "component", "main", "stations", len(timestamps))
+		}
+	}
+
+	if *once {
+		return runOnce(pool, *onceAggregate)
+	}
+
+	// Job times such as 00:05 for the daily statistics are station time
+	c := cron.New(cron.WithLocation(config.Location))
+
+	// Main 5-minute processing
+	entry, err := c.AddFunc(config.CronSchedule, func() {
+		slog.Info("Starting scheduled weather data processing", "component", "scheduler", "job", "process")
+		err := processWeatherData(pool.DB())
+		// Without RUN_ON_START the first tick is the first run after restart
+		resume.Clear()
+		processor.RecordJob("process", err)
+		if err != nil {
+			slog.Error("Failed to process weather data", "component", "scheduler", "job", "process", "error", err)
+			processor.RecordFailure()
+		} else {
+			slog.Info("Weather data processed successfully", "component", "scheduler", "job", "process")
+			writeCurrentJSON(pool.DB())
+		}
+		writeTextfile()
+	})
+	if err != nil {
+		log.Fatalf("Failed to schedule main processing job: %v", err)
+	}
+	processor.TrackJob("process", c, entry)
+
+	// Daily stats
+	entry, err = c.AddFunc(statisticsSchedule(5, "*", "*", "*"), func() {
+		slog.Info("Starting daily statistics calculation", "component", "scheduler", "job", "daily")
+		db := pool.DB()
+
+		err := traceJob("updateDailyStatistics", func() error {
+			return observeStats("daily", func() error {
+				return forEachStation(db, func(db Store) error { return withAggregateTx(db, updateDailyStatistics) })
+			})
+		})
+		processor.RecordJob("daily", err)
+		if err != nil {
+			slog.Error("Failed to calculate daily statistics", "component", "scheduler", "job", "daily", "error", err)
+			processor.RecordFailure()
+		} else {
+			processor.RecordStatsRun("daily")
+			slog.Info("Daily statistics calculated successfully", "component", "scheduler", "job", "daily")
+			writeDailyJSON(db)
+		}
+	})
+	if err != nil {
+		log.Fatalf("Failed to schedule daily statistics job: %v", err)
+	}
+	processor.TrackJob("daily", c, entry)
+
+	// Weekly stats
+	entry, err = c.AddFunc(statisticsSchedule(10, "*", "*", "1"), func() {
+		slog.Info("Starting weekly statistics calculation", "component", "scheduler", "job", "weekly")
+		db := pool.DB()
+
+		err := traceJob("updateWeeklyStatistics", func() error {
+			return observeStats("weekly", func() error {
+				return forEachStation(db, func(db Store) error { return withAggregateTx(db, updateWeeklyStatistics) })
+			})
+		})
+		processor.RecordJob("weekly", err)
+		if err != nil {
+			slog.Error("Failed to calculate weekly statistics", "component", "scheduler", "job", "weekly", "error", err)
+			processor.RecordFailure()
+		} else {
+			processor.RecordStatsRun("weekly")
+			slog.Info("Weekly statistics calculated successfully", "component", "scheduler", "job", "weekly")
+		}
+	})
+	if err != nil {
+		log.Fatalf("Failed to schedule weekly statistics job: %v", err)
+	}
+	processor.TrackJob("weekly", c, entry)
+
+	// Monthly stats
+	entry, err = c.AddFunc(statisticsSchedule(15, "1", "*", "*"), func() {
+		slog.Info("Starting monthly statistics calculation", "component", "scheduler", "job", "monthly")
+		db := pool.DB()
+
+		err := traceJob("updateMonthlyStatistics", func() error {
+			return observeStats("monthly", func() error {
+				return forEachStation(db, func(db Store) error { return withAggregateTx(db, updateMonthlyStatistics) })
+			})
+		})
+		processor.RecordJob("monthly", err)
+		if err != nil {
+			slog.Error("Failed to calculate monthly statistics", "component", "scheduler", "job", "monthly", "error", err)
+			processor.RecordFailure()
+		} else {
+			processor.RecordStatsRun("monthly")
+			slog.Info("Monthly statistics calculated successfully", "component", "scheduler", "job", "monthly")
+		}
+	})
+	if err != nil {
+		log.Fatalf("Failed to schedule monthly statistics job: %v", err)
+	}
+	processor.TrackJob("monthly", c, entry)
+
+	// Yearly stats
+	if config.EnableFrostFreePeriod {
+		entry, err = c.AddFunc(statisticsSchedule(20, "1", "1", "*"), func() {
+			slog.Info("Starting yearly statistics calculation", "component", "scheduler", "job", "yearly")
+			db := pool.DB()
+
+			err := traceJob("updateYearlyStatistics", func() error {
+				return observeStats("yearly", func() error {
+					return forEachStation(db, func(db Store) error { return withAggregateTx(db, updateYearlyStatistics) })
+				})
+			})
+			processor.RecordJob("yearly", err)
+			if err != nil {
+				slog.Error("Failed to calculate yearly statistics", "component", "scheduler", "job", "yearly", "error", err)
+				processor.RecordFailure()
+			} else {
+				processor.RecordStatsRun("yearly")
+				slog.Info("Yearly statistics calculated successfully", "component", "scheduler", "job", "yearly")
+			}
+		})
+		if err != nil {
+			log.Fatalf("Failed to schedule yearly statistics job: %v", err)
+		}
+		processor.TrackJob("yearly", c, entry)
+	}
+
+	signals := notifyShutdown()
+	c.Start()
+
+	slog.Info("Cron scheduler started", "component", "scheduler")
+
+	// Run once immediately, unless disabled so that several instances
+	// starting together do not all process the same file
+	if config.RunOnStart {
+		err = processWeatherData(pool.DB())
+		resume.Clear()
+		processor.RecordJob("process", err)
+		if err != nil {
+			slog.Error("Failed initial processing", "component", "scheduler", "job", "process", "error", err)
+			processor.RecordFailure()
+		}
+	} else {
+		slog.Info("Initial processing disabled, waiting for the first scheduled run", "component", "scheduler", "job", "process")
+	}
+	writeTextfile()
+	// Publish both files right away instead of waiting for the daily job
+	writeCurrentJSON(pool.DB())
+	writeDailyJSON(pool.DB())
+
+	slog.Info("Shutting down", "component", "main", "signal", (<-signals).String())
+	shutdown(c, pool)
+	return 0
+}
+
+// openDB creates the connection pool shared by the processing run, the
+// statistics jobs and the HTTP API for the lifetime of the process
+func openDB() (*sql.DB, error) {
+	driverName := "mysql"
+	// loc makes the driver write and read DATETIME values in TIMEZONE, so
+	// DATE()/HOUR() in SQL agree with the dates and hours derived in Go
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&loc=%s",
+		config.DBUser, config.DBPassword, config.DBHost, config.DBPort, config.DBName,
+		url.QueryEscape(config.Location.String()))
+	if config.DBDriver == DBDriverPostgres {
+		driverName, dsn = postgresCompatDriver, postgresDSN()
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if config.DryRun {
+		// sql.Open does not connect yet, only its driver is reused
+		base := db.Driver()
+		db.Close()
+		db = sql.OpenDB(dryRunConnector{base: base, dsn: dsn})
+	}
+	db.SetMaxOpenConns(config.DBMaxOpenConns)
+	db.SetMaxIdleConns(config.DBMaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(config.DBConnMaxLifetimeMinutes) * time.Minute)
+	return db, nil
+}
+
+// setupSource builds the reader of JSON_SOURCE_URLS, JSON_SOURCE_URL or
+// JSON_FILE_PATH, in that order of precedence
+func setupSource() {
+	if config.JSONSourceURLs != "" {
+		sources, err := parseStationSources(config.JSONSourceURLs, time.Duration(config.HTTPFetchTimeout)*time.Second)
+		if err != nil {
+			log.Fatalf("Invalid JSON_SOURCE_URLS: %v", err)
+		}
+		stationSources = sources
+		slog.Info("Reading weather data", "component", "main", "stations", len(sources), "concurrency", config.FetchConcurrency)
+	} else if config.JSONSourceURL != "" {
+		source = newHTTPReader(config.JSONSourceURL, time.Duration(config.HTTPFetchTimeout)*time.Second)
+		slog.Info("Reading weather data", "component", "main", "source", config.JSONSourceURL)
+	} else if isS3Path(config.JSONFilePath) {
+		reader, err := newS3Reader(config.JSONFilePath, config.S3Region)
+		if err != nil {
+			log.Fatalf("Invalid JSON_FILE_PATH: %v", err)
+		}
+		source = reader
+		slog.Info("Reading weather data", "component", "main", "source", config.JSONFilePath)
+	} else {
+		source = FileReader{
+			Path:        config.JSONFilePath,
+			Strategy:    config.ReadStrategy,
+			VerifyDelay: time.Duration(config.ReadVerifyDelayMS) * time.Millisecond,
+		}
+	}
+}
+
+func processWeatherData(db Store) (err error) {
+	ctx, span := tracer.Start(context.Background(), "processWeatherData")
+	defer func() { endSpan(span, err) }()
+
+	if config.ReadOnly {
+		return errReadOnly
+	}
+
+	if len(stationSources) > 0 {
+		return processStations(ctx, db, stationSources)
+	}
+
+	readStart := time.Now()
+	data, err := source.Read()
+	jsonReadDuration.Observe(time.Since(readStart).Seconds())
+	if errors.Is(err, errIncompleteRead) {
+		slog.Info("Skipping incomplete reading, retrying next tick", "component", "processor", "error", err)
+		return nil
+	}
+	if err != nil {
+		noteReadError(err)
+		return fmt.Errorf("failed to read JSON file: %w", err)
+	}
+
+	return processPayload(ctx, db, data, "")
+}
+
+// errReadingRejected marks a reading skipped by validation. It is logged
+// rather than reported as a processing failure.
+var errReadingRejected = errors.New("reading rejected")
+
+// processPayload parses, validates and stores one raw reading. stationID
+// tags readings fetched from JSON_SOURCE_URLS and is empty otherwise.
+func processPayload(ctx context.Context, db Store, data []byte, stationID string) error {
+	span := trace.SpanFromContext(ctx)
+
+	weatherData, err := parseReading(data, stationID)
+	if errors.Is(err, errReadingRejected) {
+		slog.Warn("Skipping invalid reading", "component", "processor",
+			"measured_at", measurementTime(weatherData.Timestamp), "error", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	current.Set(weatherData)
+
+	span.SetAttributes(attribute.Int64("weather.timestamp", weatherData.Timestamp))
+
+	if resume.AlreadyProcessed(weatherData.StationID, weatherData.Timestamp) {
+		slog.Info("Reading already processed before restart, skipped", "component", "processor",
+			"measured_at", measurementTime(weatherData.Timestamp), "station_id", weatherData.StationID, "rows_inserted", 0)
+		return nil
+	}
+
+	if maintenanceWindow != nil && maintenanceWindow.Contains(now().In(config.Location)) {
+		pending := bufferReading(weatherData)
+		slog.Info("Inside maintenance window, reading buffered", "component", "processor",
+			"window", maintenanceWindow.String(), "pending", pending)
+		return nil
+	}
+
+	// The mirror keeps the reading if MySQL cannot be reached
+	var mirrorID int64
+	if mirror != nil {
+		if id, err := mirror.Save(weatherData); err != nil {
+			slog.Warn("Failed to mirror reading", "component", "mirror", "error", err)
+		} else {
+			mirrorID = id
+		}
+	}
+
+	if err := withDBRetry(db.Ping); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	if err := flushBufferedReadings(db); err != nil {
+		if mirrorID == 0 {
+			bufferReading(weatherData)
+		}
+		return err
+	}
+
+	lastID, err := storeReading(db, weatherData)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.Int64("weather.row_id", lastID))
+
+	if tempAlerter != nil && lastID != 0 {
+		tempAlerter.Check(weatherData)
+	}
+
+	if mirrorID != 0 {
+		if err := mirror.MarkSynced(mirrorID); err != nil {
+			slog.Warn("Failed to mark mirrored reading as synced", "component", "mirror", "error", err)
+		}
+	}
+
+	return nil
+}
+
+// parseReading parses a raw payload, normalizes its units and runs the
+// plausibility checks and validation rules. Rejected readings are returned
+// with an error wrapping errReadingRejected.
+func parseReading(data []byte, stationID string) (WeatherData, error) {
+	data, err := decodeInput(data)
+	if err != nil {
+		return WeatherData{}, err
+	}
+
+	var weatherData WeatherData
+	if err := json.Unmarshal(data, &weatherData); err != nil {
+		return WeatherData{}, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	weatherData.Raw = data
+	weatherData.StationID = stationID
+	if stationID == "" {
+		weatherData.StationID = config.StationID
+	}
+
+	ts, field, err := readingTimestamp(data, timestampFields)
+	if err != nil {
+		return WeatherData{}, err
+	}
+	weatherData.Timestamp = ts
+	slog.Debug("Timestamp taken from field", "component", "processor", "field", field)
+	weatherData.Extra = extractExtraMetrics(data)
+	applySensorSentinels(&weatherData)
+
+	if !weatherData.Missing["humidity"] {
+		weatherData.Humidity = normalizeHumidity(weatherData.Humidity)
+	}
+	normalizeUnits(&weatherData)
+
+	if err := validateReading(weatherData); err != nil {
+		if config.StrictValidation {
+			return weatherData, fmt.Errorf("%w: %w", errReadingRejected, err)
+		}
+		slog.Warn("Clamped invalid reading", "component", "processor",
+			"measured_at", measurementTime(weatherData.Timestamp), "clamped", strings.Join(clampReading(&weatherData), ", "))
+	}
+
+	if ruleEngine != nil {
+		if err := applyValidationRules(&weatherData); err != nil {
+			return weatherData, fmt.Errorf("%w: %w", errReadingRejected, err)
+		}
+	}
+
+	return weatherData, nil
+}
+
+// normalizeHumidity converts humidity to a percentage according to
+// HUMIDITY_SCALE and warns when the value does not look like that scale
+func normalizeHumidity(humidity float64) float64 {
+	if config.HumidityScale == HumidityScaleFraction {
+		if humidity > 1.0 {
+			slog.Warn("Humidity above 1.0 but HUMIDITY_SCALE=fraction, check the sensor configuration", "component", "processor", "humidity", humidity)
+		}
+		return humidity * 100
+	}
+
+	if humidity <= 1.0 {
+		slog.Warn("Humidity looks like a fraction but HUMIDITY_SCALE=percent, check the sensor configuration", "component", "processor", "humidity", humidity)
+	}
+	return humidity
+}
+
+// storeReading inserts a single reading, refreshes its hourly average and
+// returns the ID of the inserted row. A reading whose measured_at is already
+// stored is skipped and returns ID 0.
+func storeReading(db Store, weatherData WeatherData) (int64, error) {
+	if config.ReadOnly {
+		return 0, errReadOnly
+	}
+
+	// Readings replayed from the SQLite mirror do not keep their station
+	if weatherData.StationID == "" {
+		weatherData.StationID = config.StationID
+	}
+	// The aggregates refreshed below are those of the reading's station
+	db = forStationStore(db, weatherData.StationID)
+
+	// The sensor updates the file less often than the job runs, so the same
+	// reading is seen repeatedly and would inflate samples_count
+	duplicate, err := readingExists(db, weatherData.Timestamp, weatherData.StationID)
+	if err != nil {
+		return 0, err
+	}
+	if duplicate {
+		slog.Info("Duplicate reading skipped", "component", "processor",
+			"measured_at", measurementTime(weatherData.Timestamp), "rows_inserted", 0)
+		return 0, nil
+	}
+
+	stored := roundedValues(weatherData)
+	measuredAt := measurementTime(weatherData.Timestamp)
+
+	columns, values := readingRow(db, weatherData, stored)
+
+	query := fmt.Sprintf("INSERT INTO weather (%s) VALUES (%s)",
+		strings.Join(columns, ", "), placeholders(len(columns)))
+
+	// The row, its weather_metrics rows and its hourly average are committed
+	// together, so a crash in between cannot leave weather_hourly behind the
+	// raw table. With HOURLY_UPDATE_MIN_INTERVAL the debouncer updates the
+	// hour later.
+	var lastID int64
+	attempt := 0
+	err = withDBRetry(func() error {
+		// A commit lost on the network may still have been applied, so a
+		// retry first looks for the row instead of inserting it again
+		if attempt++; attempt > 1 {
+			id, err := storedReadingID(db, weatherData.Timestamp, weatherData.StationID)
+			if err != nil {
+				return err
+			}
+			if id != 0 {
+				slog.Info("Reading was committed before the retry", "component", "processor", "id", id, "measured_at", measuredAt)
+				lastID = id
+				return nil
+			}
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback() // no-op after Commit
+
+		insertStart := time.Now()
+		lastID, err = insertReadingRow(tx, query, values)
+		insertDuration.Observe(time.Since(insertStart).Seconds())
+		if err != nil {
+			return fmt.Errorf("failed to insert data: %w", err)
+		}
+
+		if config.TSDBEnabled {
+			if err := insertMetricRows(tx, weatherData, stored); err != nil {
+				return err
+			}
+		}
+
+		if hourlyDebouncer == nil {
+			slog.Debug("Calculating hourly averages", "component", "stats", "measured_at", measuredAt)
+			if err := updateHourlyAverages(forStation(tx, weatherData.StationID), measuredAt); err != nil {
+				return fmt.Errorf("failed to update hourly averages: %w", err)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit reading: %w", err)
+		}
+		return nil
+	})
+	if isDuplicateEntry(err) {
+		// Stored concurrently, caught by a UNIQUE index on measured_at
+		slog.Info("Duplicate reading skipped", "component", "processor", "measured_at", measuredAt, "rows_inserted", 0)
+		return 0, nil
+	}
+	if err != nil {
+		insertErrorsTotal.Inc()
+		return 0, err
+	}
+	insertsTotal.Inc()
+	lastProcessedTimestamp.Set(float64(now().Unix()))
+	processor.RecordReading(weatherData.Timestamp)
 
-// loadConfig loads configuration from environment variables
-func loadConfig() Config {
-	return Config{
-		JSONFilePath: getEnv("JSON_FILE_PATH", "/var/www/laravel-tene.life/public/files/weather.json"),
-		DBUser:       os.Getenv("DB_USER"),
-		DBPassword:   os.Getenv("DB_PASSWORD"),
-		DBHost:       getEnv("DB_HOST", "localhost"),
-		DBPort:       getEnv("DB_PORT", "3306"),
-		DBName:       getEnv("DB_NAME", "tene_life"),
-		CronSchedule: getEnv("CRON_SCHEDULE", "*/5 * * * *"),
+	if config.PersistProcessorState {
+		if err := saveProcessorState(db, weatherData.StationID, weatherData.Timestamp); err != nil {
+			slog.Warn("Failed to save processor state", "component", "processor", "measured_at", measuredAt, "error", err)
+		}
 	}
-}
 
-var config Config
+	slog.Info("Data inserted successfully", "component", "processor",
+		"id", lastID, "measured_at", measuredAt, "station_id", weatherData.StationID, "rows_inserted", 1)
 
-func main() {
-	log.Println("Weather data processor started")
+	if config.LongFormat {
+		if err := insertLongFormat(db, measuredAt, longFormatRows(columns, values)); err != nil {
+			slog.Warn("Failed to store long format rows", "component", "processor", "measured_at", measuredAt, "error", err)
+		}
+	}
 
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables from system")
-	} else {
-		log.Println("Loaded configuration from .env file")
+	if config.EnableArchive {
+		if err := archiveReading(db, lastID, measuredAt, weatherData); err != nil {
+			slog.Warn("Failed to archive reading", "component", "processor", "measured_at", measuredAt, "error", err)
+		}
 	}
 
-	config = loadConfig()
+	if kafkaPublisher != nil {
+		kafkaPublisher.Publish(StoredReading{
+			ID:          lastID,
+			MeasuredAt:  measuredAt,
+			Temperature: stored.Temperature,
+			Pressure:    stored.Pressure,
+			Humidity:    stored.Humidity,
+		})
+	}
 
-	if config.DBUser == "" {
-		log.Fatal("DB_USER environment variable is required")
+	if config.EnablePressureChangePctile && stored.Pressure != nil {
+		if err := updatePressureChangePercentile(db, lastID, measuredAt, *stored.Pressure); err != nil {
+			slog.Warn("Failed to update pressure change percentile", "component", "processor", "measured_at", measuredAt, "error", err)
+		}
 	}
-	if config.DBPassword == "" {
-		log.Fatal("DB_PASSWORD environment variable is required")
+
+	if config.EnablePressureTendency && stored.Pressure != nil {
+		if err := updatePressureTendency(db, lastID, measuredAt, *stored.Pressure); err != nil {
+			slog.Warn("Failed to update pressure tendency", "component", "processor", "measured_at", measuredAt, "error", err)
+		}
 	}
 
-	log.Printf("Loaded configuration - DB: %s@%s:%s/%s, Schedule: %s",
-		config.DBUser, config.DBHost, config.DBPort, config.DBName, config.CronSchedule)
+	if config.EnableInversion && weatherData.TemperatureElevated != nil {
+		if err := checkPersistentInversion(db, measuredAt); err != nil {
+			slog.Warn("Failed to check temperature inversion", "component", "processor", "measured_at", measuredAt, "error", err)
+		}
+	}
 
-	c := cron.New()
+	if config.UseRunningTotals {
+		if err := updateRunningTotals(db, measuredAt, stored.Temperature, stored.Pressure, stored.Humidity); err != nil {
+			slog.Warn("Failed to update running totals", "component", "processor", "measured_at", measuredAt, "error", err)
+		}
+	}
 
-	// Main 5-minute processing
-	_, err := c.AddFunc(config.CronSchedule, func() {
-		log.Println("Starting scheduled weather data processing...")
-		if err := processWeatherData(); err != nil {
-			log.Printf("Error processing weather data: %v", err)
-		} else {
-			log.Println("Weather data processed successfully")
+	if config.EnableMovingAverages {
+		if err := updateMovingAverages(db, now()); err != nil {
+			slog.Warn("Failed to update moving averages", "component", "stats", "measured_at", measuredAt, "error", err)
 		}
-	})
-	if err != nil {
-		log.Fatalf("Failed to schedule main processing job: %v", err)
 	}
 
-	// Daily stats
-	_, err = c.AddFunc("5 0 * * *", func() {
-		log.Println("Starting daily statistics calculation...")
-		db := openDB()
-		defer db.Close()
+	if hourlyDebouncer != nil {
+		hourlyDebouncer.Trigger(weatherData.StationID, measuredAt)
+	}
 
-		if err := updateDailyStatistics(db); err != nil {
-			log.Printf("Error calculating daily statistics: %v", err)
-		} else {
-			log.Println("Daily statistics calculated successfully")
+	// The scheduled jobs never revisit past periods, so a late reading
+	// refreshes the ones it belongs to right away
+	if config.RecomputeLateData && measuredAt.Before(observationDayStart(now().In(config.Location))) {
+		slog.Info("Late reading, recomputing its periods", "component", "stats", "measured_at", measuredAt)
+		if err := recomputePeriods(db, measuredAt, measuredAt); err != nil {
+			slog.Warn("Failed to recompute periods of late reading", "component", "stats", "measured_at", measuredAt, "error", err)
 		}
-	})
-	if err != nil {
-		log.Fatalf("Failed to schedule daily statistics job: %v", err)
 	}
 
-	// Weekly stats
-	_, err = c.AddFunc("10 0 * * 1", func() {
-		log.Println("Starting weekly statistics calculation...")
-		db := openDB()
-		defer db.Close()
+	return lastID, nil
+}
 
-		if err := updateWeeklyStatistics(db); err != nil {
-			log.Printf("Error calculating weekly statistics: %v", err)
-		} else {
-			log.Println("Weekly statistics calculated successfully")
-		}
-	})
+// insertReadingRow runs the weather INSERT and returns the new row's id
+func insertReadingRow(db dbExecutor, query string, values []any) (int64, error) {
+	// lib/pq does not support LastInsertId; a dry run only logs the Exec
+	if config.DBDriver == DBDriverPostgres && !config.DryRun {
+		var id int64
+		err := db.QueryRow(query+" RETURNING id", values...).Scan(&id)
+		return id, err
+	}
+	result, err := db.Exec(query, values...)
 	if err != nil {
-		log.Fatalf("Failed to schedule weekly statistics job: %v", err)
+		return 0, err
 	}
+	id, _ := result.LastInsertId()
+	return id, nil
+}
 
-	// Monthly stats
-	_, err = c.AddFunc("15 0 1 * *", func() {
-		log.Println("Starting monthly statistics calculation...")
-		db := openDB()
-		defer db.Close()
+// storedValues are the temperature, pressure and humidity stored for a
+// reading: rounded to ROUND_DECIMALS, nil when the sensor value is missing
+type storedValues struct {
+	Temperature *float64
+	Pressure    *float64
+	Humidity    *float64
+}
 
-		if err := updateMonthlyStatistics(db); err != nil {
-			log.Printf("Error calculating monthly statistics: %v", err)
-		} else {
-			log.Println("Monthly statistics calculated successfully")
-		}
-	})
-	if err != nil {
-		log.Fatalf("Failed to schedule monthly statistics job: %v", err)
+// roundedValues computes the storedValues of a reading
+func roundedValues(weatherData WeatherData) storedValues {
+	return storedValues{
+		Temperature: presentValue(weatherData, "temperature", roundTo(weatherData.Temperature, config.RoundDecimals)),
+		Pressure:    presentValue(weatherData, "pressure", roundTo(weatherData.Pressure, config.RoundDecimals)),
+		Humidity:    presentValue(weatherData, "humidity", roundTo(weatherData.Humidity, config.RoundDecimals)),
 	}
+}
 
-	c.Start()
+// readingRow returns the weather columns and values stored for a reading
+// with the given rounded values
+func readingRow(db dbExecutor, weatherData WeatherData, stored storedValues) ([]string, []any) {
+	missing := weatherData.Missing
+
+	measuredAt := measurementTime(weatherData.Timestamp)
 
-	log.Println("Cron scheduler started.")
+	columns := []string{"measured_at", "temperature", "pressure", "humidity"}
+	values := []any{measuredAt, stored.Temperature, stored.Pressure, stored.Humidity}
 
-	// Run once immediately
-	if err := processWeatherData(); err != nil {
-		log.Printf("Error in initial processing: %v", err)
+	columns = append(columns, "station_id")
+	values = append(values, weatherData.StationID)
+
+	// measured_at holds the local wall time, which repeats on a DST "fall
+	// back" day; the Unix time tells the two hours apart
+	if config.SplitDSTHours {
+		columns = append(columns, "measured_epoch")
+		values = append(values, weatherData.Timestamp)
 	}
 
-	select {}
-}
+	if config.EnableDewPoint {
+		var dew *float64
+		if !missing["temperature"] && !missing["humidity"] {
+			d := roundTo(dewPoint(weatherData.Temperature, weatherData.Humidity), config.RoundDecimals)
+			dew = &d
+		}
+		columns = append(columns, "dew_point")
+		values = append(values, dew)
+	}
+	// The station pressure of a sea-level reading depends on the temperature
+	stationPressureMissing := missing["pressure"] ||
+		(config.InputPressureType == PressureTypeSeaLevel && missing["temperature"])
+	if config.EnableMixingRatio {
+		var mixingRatio *float64
+		if !stationPressureMissing && !missing["temperature"] && !missing["humidity"] {
+			r := roundTo(computeMixingRatio(weatherData.Temperature, readingStationPressure(weatherData), weatherData.Humidity), config.RoundDecimals)
+			mixingRatio = &r
+		}
+		columns = append(columns, "mixing_ratio")
+		values = append(values, mixingRatio)
+	}
+	if config.EnableFeelsLike {
+		var feels *float64
+		if !missing["temperature"] && !missing["humidity"] {
+			f := roundTo(feelsLike(weatherData.Temperature, weatherData.Humidity), config.RoundDecimals)
+			feels = &f
+		}
+		columns = append(columns, "feels_like")
+		values = append(values, feels)
+	}
+	if config.InputPressureType == PressureTypeSeaLevel {
+		var station *float64
+		if !stationPressureMissing {
+			p := roundTo(readingStationPressure(weatherData), config.RoundDecimals)
+			station = &p
+		}
+		columns = append(columns, "pressure_station")
+		values = append(values, station)
+	}
+	if config.EnableRefPressure {
+		var pressureRef *float64
+		if !stationPressureMissing && !missing["temperature"] {
+			p := roundTo(reducePressure(readingStationPressure(weatherData), weatherData.Temperature,
+				config.StationAltitude, config.RefStationAltitude), config.RoundDecimals)
+			pressureRef = &p
+		}
+		columns = append(columns, "pressure_ref")
+		values = append(values, pressureRef)
+	}
+	if config.EnableSunshineHours {
+		columns = append(columns, "solar_radiation")
+		values = append(values, weatherData.SolarRadiation)
+	}
+	if config.EnableRainfall {
+		columns = append(columns, "rain_mm")
+		values = append(values, weatherData.RainMM)
+	}
+	if config.EnableRainRate {
+		rate := weatherData.RainRate
+		if rate == nil && weatherData.RainMM != nil {
+			derived, err := derivedRainRate(db, measuredAt, *weatherData.RainMM)
+			if err != nil {
+				slog.Warn("Failed to derive rain rate", "component", "processor", "measured_at", measuredAt, "error", err)
+			}
+			rate = derived
+		}
+		columns = append(columns, "rain_rate")
+		values = append(values, rate)
+	}
+	var inversion bool
+	if config.EnableInversion {
+		var strength *float64
+		if weatherData.TemperatureElevated != nil && !missing["temperature"] {
+			s, inv := inversionStrength(weatherData.Temperature, *weatherData.TemperatureElevated, config.InversionThreshold)
+			strength, inversion = &s, inv
+		}
+		columns = append(columns, "temperature_elevated", "inversion", "inversion_strength")
+		values = append(values, weatherData.TemperatureElevated, inversion, strength)
+	}
+	if config.EnableWind {
+		columns = append(columns, "wind_speed", "wind_direction")
+		values = append(values,
+			presentValue(weatherData, "wind_speed", roundTo(weatherData.WindSpeed, config.RoundDecimals)),
+			presentValue(weatherData, "wind_direction", math.Mod(roundTo(weatherData.WindDirection, config.RoundDecimals), 360)))
+	}
+	if config.EnableAirQuality {
+		columns = append(columns, "co2", "pm25", "pm10")
+		values = append(values, weatherData.CO2, weatherData.PM25, weatherData.PM10)
+	}
+	if config.StoreProcessingLatency {
+		columns = append(columns, "processing_latency_ms")
+		values = append(values, processingLatency(measuredAt, now()))
+	}
 
-func openDB() *sql.DB {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
-		config.DBUser, config.DBPassword, config.DBHost, config.DBPort, config.DBName)
+	for _, m := range extraMetrics {
+		columns = append(columns, m)
+		values = append(values, weatherData.Extra[m])
+	}
 
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		log.Fatalf("DB connect error: %v", err)
+	for _, c := range derivedColumns {
+		columns = append(columns, c.Name)
+		values = append(values, c.Value(weatherData))
 	}
-	return db
+
+	return columns, values
 }
 
-func processWeatherData() error {
+// placeholders returns a comma-separated list of n query placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
 
-	data, err := os.ReadFile(config.JSONFilePath)
-	if err != nil {
-		return fmt.Errorf("failed to read JSON file: %w", err)
+// Default and upper limit of ROUND_DECIMALS
+const (
+	defaultRoundDecimals = 1
+	maxRoundDecimals     = 4
+)
+
+// roundTo rounds value half away from zero to the given number of decimal
+// places
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(value*scale) / scale
+}
+
+// roundAggregate rounds an aggregate to ROUND_DECIMALS unless full precision
+// storage is enabled, in which case rounding is left to the display layer
+func roundAggregate(value float64) float64 {
+	if config.StoreFullPrecisionAggregates {
+		return value
 	}
+	return roundTo(value, config.RoundDecimals)
+}
 
-	var weatherData WeatherData
-	if err := json.Unmarshal(data, &weatherData); err != nil {
-		return fmt.Errorf("failed to parse JSON: %w", err)
+// roundNullAggregates rounds the valid aggregates in place. A metric without
+// samples in the period stays NULL, so a failed sensor does not discard the
+// aggregates of the others.
+func roundNullAggregates(values ...*sql.NullFloat64) {
+	for _, v := range values {
+		if v.Valid {
+			v.Float64 = roundAggregate(v.Float64)
+		}
+	}
+}
+
+// ------------------------- HOURLY ------------------------------
+
+func updateHourlyAverages(db dbExecutor, currentTime time.Time) error {
+	if config.SplitDSTHours {
+		return updateHourlyAveragesByOffset(db, currentTime)
 	}
 
-	temperature := math.Round(weatherData.Temperature*10) / 10
-	pressure := math.Round(weatherData.Pressure*10) / 10
-	humidity := math.Round(weatherData.Humidity*10) / 10
+	date := currentTime.Format("2006-01-02")
+	hour := currentTime.Hour()
 
-	measuredAt := time.Unix(weatherData.Timestamp, 0)
+	var avgTemp, avgPressure, avgHumidity sql.NullFloat64
+	var samplesCount int
 
-	db := openDB()
-	defer db.Close()
+	query := `
+		SELECT
+			AVG(temperature) AS avg_temp,
+			AVG(pressure) AS avg_pressure,
+			AVG(humidity) AS avg_humidity,
+			COUNT(*) AS samples
+		FROM weather
+		WHERE DATE(measured_at) = ? AND HOUR(measured_at) = ?` + exclusionFilter() + stationFilter(db) + `
+	`
 
-	if err := db.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
+	err := db.QueryRow(query, date, hour).Scan(&avgTemp, &avgPressure, &avgHumidity, &samplesCount)
+	if err == nil && samplesCount == 0 {
+		err = sql.ErrNoRows
+	}
+	if err == sql.ErrNoRows {
+		slog.Info("No samples found, skipping", "component", "stats", "period", "hourly", "date", date, "hour", hour)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to calculate averages: %w", err)
 	}
 
-	query := `INSERT INTO weather (measured_at, temperature, pressure, humidity)
-              VALUES (?, ?, ?, ?)`
+	roundNullAggregates(&avgTemp, &avgPressure, &avgHumidity)
+
+	upsert := `
+		INSERT INTO weather_hourly (station_id, date, hour, avg_temperature, avg_pressure, avg_humidity, samples_count)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			avg_temperature = VALUES(avg_temperature),
+			avg_pressure = VALUES(avg_pressure),
+			avg_humidity = VALUES(avg_humidity),
+			samples_count = VALUES(samples_count),
+			updated_at = CURRENT_TIMESTAMP
+	`
 
-	result, err := db.Exec(query, measuredAt, temperature, pressure, humidity)
+	_, err = db.Exec(upsert, stationOf(db), date, hour, avgTemp, avgPressure, avgHumidity, samplesCount)
 	if err != nil {
-		return fmt.Errorf("failed to insert data: %w", err)
+		return fmt.Errorf("failed to upsert hourly averages: %w", err)
 	}
 
-	lastID, _ := result.LastInsertId()
-	log.Printf("Data inserted successfully with ID: %d", lastID)
+	err = updateExtraAggregates(db, "weather_hourly", false,
+		"DATE(measured_at) = ? AND HOUR(measured_at) = ?", []any{date, hour},
+		"date = ? AND hour = ?", []any{date, hour})
+	if err != nil {
+		slog.Warn("Failed to update extra metric averages", "component", "stats", "period", "hourly", "date", date, "hour", hour, "error", err)
+	}
 
-	log.Println("Calculating hourly averages...")
-	if err := updateHourlyAverages(db, measuredAt); err != nil {
-		log.Printf("Warning: Failed to update hourly averages: %v", err)
+	if config.EnableWind {
+		err = updateHourlyWind(db,
+			"DATE(measured_at) = ? AND HOUR(measured_at) = ?", []any{date, hour},
+			"date = ? AND hour = ?", []any{date, hour})
+		if err != nil {
+			slog.Warn("Failed to update hourly wind", "component", "stats", "date", date, "hour", hour, "error", err)
+		}
 	}
 
 	return nil
 }
 
-// ------------------------- HOURLY ------------------------------
-func updateHourlyAverages(db *sql.DB, currentTime time.Time) error {
+// updateHourlyAveragesByOffset buckets readings by the Unix time range of the
+// hour containing currentTime instead of by local DATE()/HOUR(). measured_at
+// is stored as local wall time, so both copies of the repeated hour of a DST
+// "fall back" day look the same there; measured_epoch tells them apart and the
+// hour yields two rows that share date and hour but differ in utc_offset.
+func updateHourlyAveragesByOffset(db dbExecutor, currentTime time.Time) error {
 	date := currentTime.Format("2006-01-02")
 	hour := currentTime.Hour()
+	_, offset := currentTime.Zone()
+	utcOffset := offset / 60
 
-	var avgTemp, avgPressure, avgHumidity float64
+	// Strip the local minutes/seconds rather than using time.Date, which is
+	// ambiguous for the repeated hour
+	hourStart := hourStart(currentTime).Unix()
+	hourEnd := hourStart + int64(time.Hour/time.Second)
+
+	var avgTemp, avgPressure, avgHumidity sql.NullFloat64
 	var samplesCount int
 
 	query := `
@@ -222,26 +1806,27 @@ func updateHourlyAverages(db *sql.DB, currentTime time.Time) error {
 			AVG(humidity) AS avg_humidity,
 			COUNT(*) AS samples
 		FROM weather
-		WHERE DATE(measured_at) = ? AND HOUR(measured_at) = ?
-		HAVING samples > 0
+		WHERE measured_epoch >= ? AND measured_epoch < ?` + exclusionFilter() + stationFilter(db) + `
 	`
 
-	err := db.QueryRow(query, date, hour).Scan(&avgTemp, &avgPressure, &avgHumidity, &samplesCount)
+	err := db.QueryRow(query, hourStart, hourEnd).Scan(&avgTemp, &avgPressure, &avgHumidity, &samplesCount)
+	if err == nil && samplesCount == 0 {
+		err = sql.ErrNoRows
+	}
 	if err == sql.ErrNoRows {
-		log.Printf("No samples found for %s hour %d, skipping", date, hour)
+		slog.Info("No samples found, skipping", "component", "stats", "period", "hourly",
+			"date", date, "hour", hour, "utc_offset_minutes", utcOffset)
 		return nil
 	}
 	if err != nil {
 		return fmt.Errorf("failed to calculate averages: %w", err)
 	}
 
-	avgTemp = math.Round(avgTemp*10) / 10
-	avgPressure = math.Round(avgPressure*10) / 10
-	avgHumidity = math.Round(avgHumidity*10) / 10
+	roundNullAggregates(&avgTemp, &avgPressure, &avgHumidity)
 
 	upsert := `
-		INSERT INTO weather_hourly (date, hour, avg_temperature, avg_pressure, avg_humidity, samples_count)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO weather_hourly (station_id, date, hour, utc_offset, avg_temperature, avg_pressure, avg_humidity, samples_count)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		ON DUPLICATE KEY UPDATE
 			avg_temperature = VALUES(avg_temperature),
 			avg_pressure = VALUES(avg_pressure),
@@ -250,23 +1835,46 @@ func updateHourlyAverages(db *sql.DB, currentTime time.Time) error {
 			updated_at = CURRENT_TIMESTAMP
 	`
 
-	_, err = db.Exec(upsert, date, hour, avgTemp, avgPressure, avgHumidity, samplesCount)
+	_, err = db.Exec(upsert, stationOf(db), date, hour, utcOffset, avgTemp, avgPressure, avgHumidity, samplesCount)
 	if err != nil {
 		return fmt.Errorf("failed to upsert hourly averages: %w", err)
 	}
 
+	err = updateExtraAggregates(db, "weather_hourly", false,
+		"measured_epoch >= ? AND measured_epoch < ?", []any{hourStart, hourEnd},
+		"date = ? AND hour = ? AND utc_offset = ?", []any{date, hour, utcOffset})
+	if err != nil {
+		slog.Warn("Failed to update extra metric averages", "component", "stats", "period", "hourly", "date", date, "hour", hour, "error", err)
+	}
+
+	if config.EnableWind {
+		err = updateHourlyWind(db,
+			"measured_epoch >= ? AND measured_epoch < ?", []any{hourStart, hourEnd},
+			"date = ? AND hour = ? AND utc_offset = ?", []any{date, hour, utcOffset})
+		if err != nil {
+			slog.Warn("Failed to update hourly wind", "component", "stats", "date", date, "hour", hour, "error", err)
+		}
+	}
+
 	return nil
 }
 
 // ------------------------- DAILY ------------------------------
-func updateDailyStatistics(db *sql.DB) error {
+func updateDailyStatistics(db dbExecutor) error {
+	return updateDailyStatisticsFor(db, now().In(config.Location).AddDate(0, 0, -1))
+}
+
+// updateDailyStatisticsFor computes and stores the statistics of one day
+func updateDailyStatisticsFor(db dbExecutor, day time.Time) error {
+	if config.ReadOnly {
+		return errReadOnly
+	}
 
-	yesterday := time.Now().AddDate(0, 0, -1)
-	date := yesterday.Format("2006-01-02")
+	date := day.Format("2006-01-02")
 
-	var avgTemp, minTemp, maxTemp float64
-	var avgPressure, minPressure, maxPressure float64
-	var avgHumidity, minHumidity, maxHumidity float64
+	var avgTemp, minTemp, maxTemp sql.NullFloat64
+	var avgPressure, minPressure, maxPressure sql.NullFloat64
+	var avgHumidity, minHumidity, maxHumidity sql.NullFloat64
 	var samplesCount int
 
 	query := `
@@ -276,42 +1884,55 @@ func updateDailyStatistics(db *sql.DB) error {
 			AVG(humidity), MIN(humidity), MAX(humidity),
 			COUNT(*) AS samples
 		FROM weather
-		WHERE DATE(measured_at) = ?
-		HAVING samples > 0
+		WHERE ` + observationDay() + ` = ?` + exclusionFilter() + stationFilter(db) + `
 	`
+	if config.UseRunningTotals {
+		query = `
+			SELECT
+				temperature_sum / NULLIF(temperature_count, 0), temperature_min, temperature_max,
+				pressure_sum / NULLIF(pressure_count, 0), pressure_min, pressure_max,
+				humidity_sum / NULLIF(humidity_count, 0), humidity_min, humidity_max,
+				samples_count
+			FROM weather_running
+			WHERE date = ? AND samples_count > 0` + stationFilter(db) + `
+		`
+	}
 
 	err := db.QueryRow(query, date).Scan(
 		&avgTemp, &minTemp, &maxTemp,
 		&avgPressure, &minPressure, &maxPressure,
 		&avgHumidity, &minHumidity, &maxHumidity,
 		&samplesCount)
+	if err == nil && samplesCount == 0 {
+		err = sql.ErrNoRows
+	}
 	if err == sql.ErrNoRows {
-		log.Printf("No samples found for %s, skipping", date)
+		slog.Info("No samples found, skipping", "component", "stats", "period", "daily", "date", date)
+		if config.AlertOnEmptyPeriod {
+			if err := alertEmptyDay(db, day); err != nil {
+				slog.Warn("Failed to check for empty periods", "component", "stats", "date", date, "error", err)
+			}
+		}
 		return nil
 	}
 	if err != nil {
 		return fmt.Errorf("failed to calculate daily statistics: %w", err)
 	}
 
-	avgTemp = math.Round(avgTemp*10) / 10
-	minTemp = math.Round(minTemp*10) / 10
-	maxTemp = math.Round(maxTemp*10) / 10
-	avgPressure = math.Round(avgPressure*10) / 10
-	minPressure = math.Round(minPressure*10) / 10
-	maxPressure = math.Round(maxPressure*10) / 10
-	avgHumidity = math.Round(avgHumidity*10) / 10
-	minHumidity = math.Round(minHumidity*10) / 10
-	maxHumidity = math.Round(maxHumidity*10) / 10
+	roundNullAggregates(
+		&avgTemp, &minTemp, &maxTemp,
+		&avgPressure, &minPressure, &maxPressure,
+		&avgHumidity, &minHumidity, &maxHumidity)
 
 	upsert := `
 		INSERT INTO weather_daily (
-			date,
+			station_id, date,
 			avg_temperature, min_temperature, max_temperature,
 			avg_pressure, min_pressure, max_pressure,
 			avg_humidity, min_humidity, max_humidity,
 			samples_count
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON DUPLICATE KEY UPDATE
 			avg_temperature = VALUES(avg_temperature),
 			min_temperature = VALUES(min_temperature),
@@ -327,34 +1948,182 @@ func updateDailyStatistics(db *sql.DB) error {
 		-- sea_temperature is NOT updated here, only manually via API
 	`
 
-	_, err = db.Exec(upsert, date,
+	_, err = db.Exec(upsert, stationOf(db), date,
 		avgTemp, minTemp, maxTemp,
 		avgPressure, minPressure, maxPressure,
 		avgHumidity, minHumidity, maxHumidity,
 		samplesCount)
+	if err != nil {
+		return err
+	}
+
+	err = updateExtraAggregates(db, "weather_daily", true,
+		observationDay()+" = ?", []any{date},
+		"date = ?", []any{date})
+	if err != nil {
+		slog.Warn("Failed to update extra metric aggregates", "component", "stats", "period", "daily", "date", date, "error", err)
+	}
+
+	if config.AlertOnEmptyPeriod {
+		if err := alertEmptyHours(db, date); err != nil {
+			slog.Warn("Failed to check for empty periods", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.UseRunningTotals {
+		if err := updateDailyStddev(db, date); err != nil {
+			slog.Warn("Failed to update daily standard deviation", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	emitStatsdGauges("daily", "temperature", avgTemp, minTemp, maxTemp)
+	emitStatsdGauges("daily", "pressure", avgPressure, minPressure, maxPressure)
+	emitStatsdGauges("daily", "humidity", avgHumidity, minHumidity, maxHumidity)
+
+	if config.StoreProcessingLatency {
+		if err := updateDailyLatencyP95(db, date); err != nil {
+			slog.Warn("Failed to update processing latency p95", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableSunshineHours {
+		if err := updateDailySunshineHours(db, date); err != nil {
+			slog.Warn("Failed to update sunshine hours", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableRainfall {
+		if err := updateDailyRainfall(db, date); err != nil {
+			slog.Warn("Failed to update daily rainfall", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableRainRate {
+		if err := updateDailyMaxRainRate(db, date); err != nil {
+			slog.Warn("Failed to update max rain rate", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableRainfall && config.EnableRainStreaks {
+		if err := updateRainStreaks(db, day); err != nil {
+			slog.Warn("Failed to update dry/wet streaks", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableAirQuality {
+		if err := updateDailyAirQuality(db, date); err != nil {
+			slog.Warn("Failed to update air quality", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableMixingRatio {
+		if err := updateDailyMixingRatio(db, date); err != nil {
+			slog.Warn("Failed to update mixing ratio", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableFeelsLike {
+		if err := updateDailyFeelsLike(db, date); err != nil {
+			slog.Warn("Failed to update feels-like temperature", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableComfortBands {
+		if err := updateDailyComfortBands(db, date); err != nil {
+			slog.Warn("Failed to update comfort bands", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableTempBands {
+		if err := updateDailyTempBandHours(db, date); err != nil {
+			slog.Warn("Failed to update temperature band hours", "component", "stats", "date", date, "error", err)
+		}
+	}
 
-	return err
+	if config.EnableGriddedAverage {
+		if err := updateDailyGriddedAverage(db, date); err != nil {
+			slog.Warn("Failed to update gridded average", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableGrowingSeason && avgTemp.Valid {
+		if err := updateGrowingSeason(db, day, avgTemp.Float64); err != nil {
+			slog.Warn("Failed to update growing season", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableWeatherType && avgTemp.Valid && avgHumidity.Valid {
+		conditions := DayConditions{
+			MinTemperature: minTemp.Float64,
+			MaxTemperature: maxTemp.Float64,
+			AvgHumidity:    avgHumidity.Float64,
+		}
+		if err := updateDailyWeatherType(db, date, conditions); err != nil {
+			slog.Warn("Failed to update weather type", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableChangeIndex {
+		weights := ChangeIndexWeights{
+			Temperature: config.ChangeIndexTemperatureWeight,
+			Pressure:    config.ChangeIndexPressureWeight,
+			Humidity:    config.ChangeIndexHumidityWeight,
+		}
+		// A metric without samples drops out of the weighted average
+		if !avgTemp.Valid {
+			weights.Temperature = 0
+		}
+		if !avgPressure.Valid {
+			weights.Pressure = 0
+		}
+		if !avgHumidity.Valid {
+			weights.Humidity = 0
+		}
+		index := changeIndex(maxTemp.Float64-minTemp.Float64, maxPressure.Float64-minPressure.Float64,
+			maxHumidity.Float64-minHumidity.Float64, weights)
+		if err := updateDailyChangeIndex(db, date, index); err != nil {
+			slog.Warn("Failed to update change index", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	if config.EnableMoonPhase {
+		if err := updateDailyMoonPhase(db, day); err != nil {
+			slog.Warn("Failed to update moon phase", "component", "stats", "date", date, "error", err)
+		}
+	}
+
+	return nil
 }
 
 // ------------------------- WEEKLY ------------------------------
-func updateWeeklyStatistics(db *sql.DB) error {
+func updateWeeklyStatistics(db dbExecutor) error {
+	current := now().In(config.Location)
+	lastMonday := current.AddDate(0, 0, -int(current.Weekday())-6)
+	if current.Weekday() == time.Sunday {
+		lastMonday = current.AddDate(0, 0, -13)
+	}
+	return updateWeeklyStatisticsFor(db, lastMonday)
+}
 
-	now := time.Now()
-	lastMonday := now.AddDate(0, 0, -int(now.Weekday())-6)
-	if now.Weekday() == time.Sunday {
-		lastMonday = now.AddDate(0, 0, -13)
+// updateWeeklyStatisticsFor computes and stores the statistics of the ISO
+// week containing day
+func updateWeeklyStatisticsFor(db dbExecutor, day time.Time) error {
+	if config.ReadOnly {
+		return errReadOnly
 	}
-	lastMonday = time.Date(lastMonday.Year(), lastMonday.Month(), lastMonday.Day(), 0, 0, 0, 0, lastMonday.Location())
 
-	lastSunday := lastMonday.AddDate(0, 0, 6)
+	monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	monday = time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, monday.Location())
+
+	sunday := monday.AddDate(0, 0, 6)
 
-	year, week := lastMonday.ISOWeek()
-	weekStart := lastMonday.Format("2006-01-02")
-	weekEnd := lastSunday.Format("2006-01-02")
+	year, week := monday.ISOWeek()
+	weekStart := monday.Format("2006-01-02")
+	weekEnd := sunday.Format("2006-01-02")
 
-	var avgTemp, minTemp, maxTemp float64
-	var avgPressure, minPressure, maxPressure float64
-	var avgHumidity, minHumidity, maxHumidity float64
+	var avgTemp, minTemp, maxTemp sql.NullFloat64
+	var avgPressure, minPressure, maxPressure sql.NullFloat64
+	var avgHumidity, minHumidity, maxHumidity sql.NullFloat64
 	var samplesCount int
 
 	query := `
@@ -364,8 +2133,7 @@ func updateWeeklyStatistics(db *sql.DB) error {
 			AVG(humidity), MIN(humidity), MAX(humidity),
 			COUNT(*) AS samples
 		FROM weather
-		WHERE DATE(measured_at) >= ? AND DATE(measured_at) <= ?
-		HAVING samples > 0
+		WHERE ` + observationDay() + ` >= ? AND ` + observationDay() + ` <= ?` + exclusionFilter() + stationFilter(db) + `
 	`
 
 	err := db.QueryRow(query, weekStart, weekEnd).Scan(
@@ -373,33 +2141,31 @@ func updateWeeklyStatistics(db *sql.DB) error {
 		&avgPressure, &minPressure, &maxPressure,
 		&avgHumidity, &minHumidity, &maxHumidity,
 		&samplesCount)
+	if err == nil && samplesCount == 0 {
+		err = sql.ErrNoRows
+	}
 	if err == sql.ErrNoRows {
-		log.Printf("No samples found for week %d/%d", week, year)
+		slog.Info("No samples found, skipping", "component", "stats", "period", "weekly", "year", year, "week", week)
 		return nil
 	}
 	if err != nil {
 		return fmt.Errorf("failed to calculate weekly statistics: %w", err)
 	}
 
-	avgTemp = math.Round(avgTemp*10) / 10
-	minTemp = math.Round(minTemp*10) / 10
-	maxTemp = math.Round(maxTemp*10) / 10
-	avgPressure = math.Round(avgPressure*10) / 10
-	minPressure = math.Round(minPressure*10) / 10
-	maxPressure = math.Round(maxPressure*10) / 10
-	avgHumidity = math.Round(avgHumidity*10) / 10
-	minHumidity = math.Round(minHumidity*10) / 10
-	maxHumidity = math.Round(maxHumidity*10) / 10
+	roundNullAggregates(
+		&avgTemp, &minTemp, &maxTemp,
+		&avgPressure, &minPressure, &maxPressure,
+		&avgHumidity, &minHumidity, &maxHumidity)
 
 	upsert := `
 		INSERT INTO weather_weekly (
-			year, week, week_start, week_end,
+			station_id, year, week, week_start, week_end,
 			avg_temperature, min_temperature, max_temperature,
 			avg_pressure, min_pressure, max_pressure,
 			avg_humidity, min_humidity, max_humidity,
 			samples_count
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON DUPLICATE KEY UPDATE
 			week_start = VALUES(week_start),
 			week_end = VALUES(week_end),
@@ -416,30 +2182,55 @@ func updateWeeklyStatistics(db *sql.DB) error {
 			updated_at = CURRENT_TIMESTAMP
 	`
 
-	_, err = db.Exec(upsert, year, week, weekStart, weekEnd,
+	_, err = db.Exec(upsert, stationOf(db), year, week, weekStart, weekEnd,
 		avgTemp, minTemp, maxTemp,
 		avgPressure, minPressure, maxPressure,
 		avgHumidity, minHumidity, maxHumidity,
 		samplesCount)
+	if err != nil {
+		return err
+	}
+
+	err = updateExtraAggregates(db, "weather_weekly", true,
+		observationDay()+" >= ? AND "+observationDay()+" <= ?", []any{weekStart, weekEnd},
+		"year = ? AND week = ?", []any{year, week})
+	if err != nil {
+		slog.Warn("Failed to update extra metric aggregates", "component", "stats", "period", "weekly", "year", year, "week", week, "error", err)
+	}
 
-	return err
+	emitStatsdGauges("weekly", "temperature", avgTemp, minTemp, maxTemp)
+	emitStatsdGauges("weekly", "pressure", avgPressure, minPressure, maxPressure)
+	emitStatsdGauges("weekly", "humidity", avgHumidity, minHumidity, maxHumidity)
+
+	if config.EnablePeriodTotals {
+		if err := updateWeeklyTotals(db, year, week, weekStart, weekEnd); err != nil {
+			slog.Warn("Failed to update weekly totals", "component", "stats", "year", year, "week", week, "error", err)
+		}
+	}
+
+	return nil
 }
 
 // ------------------------- MONTHLY ------------------------------
-func updateMonthlyStatistics(db *sql.DB) error {
+func updateMonthlyStatistics(db dbExecutor) error {
+	lastMonth := now().In(config.Location).AddDate(0, -1, 0)
+	return updateMonthlyStatisticsFor(db, lastMonth.Year(), lastMonth.Month())
+}
 
-	now := time.Now()
-	lastMonth := now.AddDate(0, -1, 0)
+// updateMonthlyStatisticsFor computes and stores the statistics of one month
+func updateMonthlyStatisticsFor(db dbExecutor, year int, m time.Month) error {
+	if config.ReadOnly {
+		return errReadOnly
+	}
 
-	year := lastMonth.Year()
-	month := int(lastMonth.Month())
+	month := int(m)
 
-	firstDay := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, now.Location())
+	firstDay := time.Date(year, m, 1, 0, 0, 0, 0, config.Location)
 	lastDay := firstDay.AddDate(0, 1, -1)
 
-	var avgTemp, minTemp, maxTemp float64
-	var avgPressure, minPressure, maxPressure float64
-	var avgHumidity, minHumidity, maxHumidity float64
+	var avgTemp, minTemp, maxTemp sql.NullFloat64
+	var avgPressure, minPressure, maxPressure sql.NullFloat64
+	var avgHumidity, minHumidity, maxHumidity sql.NullFloat64
 	var samplesCount int
 
 	query := `
@@ -449,8 +2240,7 @@ func updateMonthlyStatistics(db *sql.DB) error {
 			AVG(humidity), MIN(humidity), MAX(humidity),
 			COUNT(*) AS samples
 		FROM weather
-		WHERE DATE(measured_at) >= ? AND DATE(measured_at) <= ?
-		HAVING samples > 0
+		WHERE ` + observationDay() + ` >= ? AND ` + observationDay() + ` <= ?` + exclusionFilter() + stationFilter(db) + `
 	`
 
 	err := db.QueryRow(query,
@@ -460,34 +2250,31 @@ func updateMonthlyStatistics(db *sql.DB) error {
 		&avgPressure, &minPressure, &maxPressure,
 		&avgHumidity, &minHumidity, &maxHumidity,
 		&samplesCount)
-
+	if err == nil && samplesCount == 0 {
+		err = sql.ErrNoRows
+	}
 	if err == sql.ErrNoRows {
-		log.Printf("No samples found for %d-%02d", year, month)
+		slog.Info("No samples found, skipping", "component", "stats", "period", "monthly", "year", year, "month", month)
 		return nil
 	}
 	if err != nil {
 		return fmt.Errorf("failed to calculate monthly statistics: %w", err)
 	}
 
-	avgTemp = math.Round(avgTemp*10) / 10
-	minTemp = math.Round(minTemp*10) / 10
-	maxTemp = math.Round(maxTemp*10) / 10
-	avgPressure = math.Round(avgPressure*10) / 10
-	minPressure = math.Round(minPressure*10) / 10
-	maxPressure = math.Round(maxPressure*10) / 10
-	avgHumidity = math.Round(avgHumidity*10) / 10
-	minHumidity = math.Round(minHumidity*10) / 10
-	maxHumidity = math.Round(maxHumidity*10) / 10
+	roundNullAggregates(
+		&avgTemp, &minTemp, &maxTemp,
+		&avgPressure, &minPressure, &maxPressure,
+		&avgHumidity, &minHumidity, &maxHumidity)
 
 	upsert := `
 		INSERT INTO weather_monthly (
-			year, month,
+			station_id, year, month,
 			avg_temperature, min_temperature, max_temperature,
 			avg_pressure, min_pressure, max_pressure,
 			avg_humidity, min_humidity, max_humidity,
 			samples_count
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON DUPLICATE KEY UPDATE
 			avg_temperature = VALUES(avg_temperature),
 			min_temperature = VALUES(min_temperature),
@@ -502,11 +2289,58 @@ func updateMonthlyStatistics(db *sql.DB) error {
 			updated_at = CURRENT_TIMESTAMP
 	`
 
-	_, err = db.Exec(upsert, year, month,
+	_, err = db.Exec(upsert, stationOf(db), year, month,
 		avgTemp, minTemp, maxTemp,
 		avgPressure, minPressure, maxPressure,
 		avgHumidity, minHumidity, maxHumidity,
 		samplesCount)
+	if err != nil {
+		return err
+	}
+
+	err = updateExtraAggregates(db, "weather_monthly", true,
+		observationDay()+" >= ? AND "+observationDay()+" <= ?",
+		[]any{firstDay.Format("2006-01-02"), lastDay.Format("2006-01-02")},
+		"year = ? AND month = ?", []any{year, month})
+	if err != nil {
+		slog.Warn("Failed to update extra metric aggregates", "component", "stats", "period", "monthly", "year", year, "month", month, "error", err)
+	}
+
+	emitStatsdGauges("monthly", "temperature", avgTemp, minTemp, maxTemp)
+	emitStatsdGauges("monthly", "pressure", avgPressure, minPressure, maxPressure)
+	emitStatsdGauges("monthly", "humidity", avgHumidity, minHumidity, maxHumidity)
+
+	if config.EnablePeriodTotals {
+		err := updateMonthlyTotals(db, year, month,
+			firstDay.Format("2006-01-02"), lastDay.Format("2006-01-02"))
+		if err != nil {
+			slog.Warn("Failed to update monthly totals", "component", "stats", "year", year, "month", month, "error", err)
+		}
+	}
+
+	if config.EnableRainfall && config.EnableRainDays {
+		err := updateMonthlyRainDays(db, year, month,
+			firstDay.Format("2006-01-02"), lastDay.Format("2006-01-02"))
+		if err != nil {
+			slog.Warn("Failed to update rain days", "component", "stats", "year", year, "month", month, "error", err)
+		}
+	}
+
+	if config.EnableDriftReport {
+		if err := updateMonthlyDrift(db, year, m); err != nil {
+			slog.Warn("Failed to update sensor drift", "component", "stats", "year", year, "month", month, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// ------------------------- YEARLY ------------------------------
+func updateYearlyStatistics(db dbExecutor) error {
+	if config.ReadOnly {
+		return errReadOnly
+	}
 
-	return err
+	year := now().In(config.Location).Year() - 1
+	return updateFrostFreePeriod(db, year)
 }
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readFixtureLines returns the non-empty lines of a testdata file, each a
+// weather.json payload
+func readFixtureLines(t *testing.T, name string) [][]byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			lines = append(lines, append([]byte(nil), line...))
+		}
+	}
+	return lines
+}
+
+func TestProcessWeatherDataStoresFixture(t *testing.T) {
+	setupTestConfig(t, nil)
+	setTestNow(t, time.Date(2024, 6, 1, 8, 0, 30, 0, time.UTC))
+	db := newTestStore(t)
+
+	payload, err := os.ReadFile(filepath.Join("testdata", "weather.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second run sees the unchanged file and must not store it again
+	processFixture(t, db, payload)
+	processFixture(t, db, payload)
+
+	assertGolden(t, "weather", queryRows(t, db,
+		`SELECT measured_at, temperature, pressure, humidity, station_id FROM weather ORDER BY measured_at`))
+	assertGolden(t, "hourly", queryRows(t, db,
+		`SELECT station_id, date, hour, avg_temperature, avg_pressure, avg_humidity, samples_count
+		FROM weather_hourly ORDER BY date, hour`))
+}
+
+func TestDailyAggregateGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"daily", nil},
+		{"daily_rounded", map[string]string{"ROUND_DECIMALS": "2"}},
+		{"daily_full_precision", map[string]string{"STORE_FULL_PRECISION_AGGREGATES": "true"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestConfig(t, tt.env)
+			db := newTestStore(t)
+
+			for _, payload := range readFixtureLines(t, "day.jsonl") {
+				processFixture(t, db, payload)
+			}
+
+			day := time.Date(2024, 6, 1, 0, 0, 0, 0, config.Location)
+			setTestNow(t, day.AddDate(0, 0, 1).Add(5*time.Minute))
+			if err := updateDailyStatistics(db); err != nil {
+				t.Fatalf("updateDailyStatistics: %v", err)
+			}
+
+			assertGolden(t, tt.name, queryRows(t, db,
+				`SELECT station_id, date,
+					avg_temperature, min_temperature, max_temperature,
+					avg_pressure, min_pressure, max_pressure,
+					avg_humidity, min_humidity, max_humidity,
+					samples_count
+				FROM weather_daily ORDER BY date`))
+		})
+	}
+}
+
+func TestSentinelTemperatureAggregatesAsNull(t *testing.T) {
+	setupTestConfig(t, map[string]string{"SENSOR_SENTINELS": "temperature=-127"})
+	db := newTestStore(t)
+
+	// Monday 2024-06-03 10:00 and 10:30 UTC, the temperature probe is
+	// disconnected for both
+	processFixture(t, db, []byte(`{"timestamp": 1717408800, "temperature": -127, "pressure": 1010.0, "humidity": 60.0}`))
+	processFixture(t, db, []byte(`{"timestamp": 1717410600, "temperature": -127, "pressure": 1012.0, "humidity": 64.0}`))
+
+	day := time.Date(2024, 6, 3, 0, 0, 0, 0, config.Location)
+	if err := updateDailyStatisticsFor(db, day); err != nil {
+		t.Fatalf("updateDailyStatisticsFor: %v", err)
+	}
+	if err := updateWeeklyStatisticsFor(db, day); err != nil {
+		t.Fatalf("updateWeeklyStatisticsFor: %v", err)
+	}
+	if err := updateMonthlyStatisticsFor(db, 2024, time.June); err != nil {
+		t.Fatalf("updateMonthlyStatisticsFor: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]string
+	}{
+		{
+			name:  "weather",
+			query: `SELECT temperature, pressure, humidity FROM weather`,
+			want:  map[string]string{"temperature": "", "pressure": "1010", "humidity": "60"},
+		},
+		{
+			name:  "hourly",
+			query: `SELECT avg_temperature, avg_pressure, avg_humidity, samples_count FROM weather_hourly`,
+			want:  map[string]string{"avg_temperature": "", "avg_pressure": "1011", "avg_humidity": "62", "samples_count": "2"},
+		},
+		{
+			name: "daily",
+			query: `SELECT avg_temperature, min_temperature, max_temperature,
+				avg_pressure, min_pressure, max_pressure, avg_humidity, samples_count FROM weather_daily`,
+			want: map[string]string{
+				"avg_temperature": "", "min_temperature": "", "max_temperature": "",
+				"avg_pressure": "1011", "min_pressure": "1010", "max_pressure": "1012",
+				"avg_humidity": "62", "samples_count": "2",
+			},
+		},
+		{
+			name:  "weekly",
+			query: `SELECT avg_temperature, max_temperature, avg_pressure, avg_humidity, samples_count FROM weather_weekly`,
+			want: map[string]string{
+				"avg_temperature": "", "max_temperature": "",
+				"avg_pressure": "1011", "avg_humidity": "62", "samples_count": "2",
+			},
+		},
+		{
+			name:  "monthly",
+			query: `SELECT avg_temperature, min_temperature, avg_pressure, max_humidity, samples_count FROM weather_monthly`,
+			want: map[string]string{
+				"avg_temperature": "", "min_temperature": "",
+				"avg_pressure": "1011", "max_humidity": "64", "samples_count": "2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := queryRows(t, db, tt.query)
+			if len(rows) == 0 {
+				t.Fatal("no row stored")
+			}
+			for column, want := range tt.want {
+				if got := rows[0][column]; got != want {
+					t.Errorf("%s = %q, want %q", column, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoundAggregate(t *testing.T) {
+	tests := []struct {
+		name          string
+		fullPrecision string
+		value         float64
+		want          float64
+	}{
+		{"rounded by default", "", 20.083333333333332, 20.1},
+		{"rounded half away from zero", "false", -3.25, -3.3},
+		{"full precision", "true", 20.083333333333332, 20.083333333333332},
+		{"full precision negative", "true", -3.25, -3.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STORE_FULL_PRECISION_AGGREGATES", tt.fullPrecision)
+			saved := config
+			t.Cleanup(func() { config = saved })
+			config = loadConfig()
+
+			if got := roundAggregate(tt.value); got != tt.want {
+				t.Errorf("roundAggregate(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitDSTHoursFallBackDay(t *testing.T) {
+	setupTestConfig(t, map[string]string{
+		"TIMEZONE":                "Europe/Prague",
+		"DST_SPLIT_REPEATED_HOUR": "true",
+	})
+	db := newTestStore(t)
+	for _, stmt := range []string{
+		`ALTER TABLE weather ADD COLUMN measured_epoch INTEGER NULL`,
+		`DROP TABLE weather_hourly`,
+		`CREATE TABLE weather_hourly (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			station_id VARCHAR(64) NOT NULL DEFAULT 'default',
+			date DATE NOT NULL,
+			hour INTEGER NOT NULL,
+			utc_offset INTEGER NOT NULL DEFAULT 0,
+			avg_temperature DOUBLE NULL,
+			avg_pressure DOUBLE NULL,
+			avg_humidity DOUBLE NULL,
+			samples_count INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (station_id, date, hour, utc_offset)
+		)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 2024-10-27 in Prague runs from 00:00 CEST to 24:00 CET, 25 hours with
+	// 02:00 twice. Every hour gets readings at :00 and :30.
+	start := time.Date(2024, 10, 26, 22, 0, 0, 0, time.UTC)
+	for i := 0; i < 50; i++ {
+		at := start.Add(time.Duration(i) * 30 * time.Minute)
+		processFixture(t, db, []byte(fmt.Sprintf(
+			`{"timestamp": %d, "temperature": 10.0, "pressure": 1010.0, "humidity": 80.0}`, at.Unix())))
+	}
+
+	rows := queryRows(t, db,
+		`SELECT date, hour, utc_offset, samples_count FROM weather_hourly ORDER BY date, hour, utc_offset DESC`)
+	if len(rows) != 25 {
+		t.Fatalf("got %d hourly rows, want 25: %v", len(rows), rows)
+	}
+	for i, row := range rows {
+		// Rows 0-2 are 00:00-02:00 CEST, row 3 the repeated 02:00 in CET
+		wantHour, wantOffset := i, "120"
+		if i > 2 {
+			wantHour, wantOffset = i-1, "60"
+		}
+		want := map[string]string{
+			"date":          "2024-10-27",
+			"hour":          fmt.Sprint(wantHour),
+			"utc_offset":    wantOffset,
+			"samples_count": "2",
+		}
+		for column, value := range want {
+			if row[column] != value {
+				t.Errorf("row %d: %s = %q, want %q", i, column, row[column], value)
+			}
+		}
+	}
+}
+
+func TestNormalizeHumidity(t *testing.T) {
+	tests := []struct {
+		name     string
+		scale    string
+		humidity float64
+		want     float64
+	}{
+		{"percent", HumidityScalePercent, 55, 55},
+		{"percent zero", HumidityScalePercent, 0, 0},
+		{"percent at one", HumidityScalePercent, 1, 1},
+		{"percent full", HumidityScalePercent, 100, 100},
+		{"fraction", HumidityScaleFraction, 0.55, 55},
+		{"fraction zero", HumidityScaleFraction, 0, 0},
+		{"fraction full", HumidityScaleFraction, 1, 100},
+		{"fraction given a percentage", HumidityScaleFraction, 55, 5500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestConfig(t, map[string]string{"HUMIDITY_SCALE": tt.scale})
+
+			if got := normalizeHumidity(tt.humidity); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("normalizeHumidity(%v) = %v, want %v", tt.humidity, got, tt.want)
+			}
+		})
+	}
+}
+
+// lostCommitStore reports a connection error on the first Begin after
+// storing the reading, like a commit applied by the server whose
+// acknowledgement never arrived
+type lostCommitStore struct {
+	*sql.DB
+	timestamp int64
+	begins    int
+}
+
+func (s *lostCommitStore) Begin() (*sql.Tx, error) {
+	s.begins++
+	if s.begins == 1 {
+		_, err := s.DB.Exec(`INSERT INTO weather (measured_at, temperature, pressure, humidity, station_id) VALUES (?, 21.5, 1012, 55, ?)`,
+			measurementTime(s.timestamp), config.StationID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, driver.ErrBadConn
+	}
+	return s.DB.Begin()
+}
+
+func TestRetryAfterLostCommitDoesNotDuplicateReading(t *testing.T) {
+	setupTestConfig(t, map[string]string{"MAX_DB_RETRIES": "3", "DB_RETRY_BASE_MS": "0"})
+	const timestamp = 1717236000
+	db := &lostCommitStore{DB: newTestStore(t), timestamp: timestamp}
+
+	id, err := storeReading(db, WeatherData{Timestamp: timestamp, Temperature: 21.5, Pressure: 1012, Humidity: 55})
+	if err != nil {
+		t.Fatalf("storeReading: %v", err)
+	}
+	if id != 1 {
+		t.Errorf("storeReading returned ID %d, want the committed row 1", id)
+	}
+	if rows := queryRows(t, db, `SELECT id FROM weather`); len(rows) != 1 {
+		t.Errorf("got %d weather rows, want 1", len(rows))
+	}
+	if db.begins != 1 {
+		t.Errorf("%d transactions begun, want only the lost one", db.begins)
+	}
+}
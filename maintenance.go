@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// MaintenanceWindow is a daily time range (in config.Location) during which
+// readings are buffered in memory instead of written to the database.
+// End may be earlier than Start for windows spanning midnight.
+type MaintenanceWindow struct {
+	Start int // minutes after midnight, inclusive
+	End   int // minutes after midnight, exclusive
+}
+
+var maintenanceWindow *MaintenanceWindow
+
+var (
+	bufferMu         sync.Mutex
+	bufferedReadings []WeatherData
+)
+
+// parseMaintenanceWindow parses a window in the "HH:MM-HH:MM" format
+func parseMaintenanceWindow(value string) (*MaintenanceWindow, error) {
+	var startH, startM, endH, endM int
+	if _, err := fmt.Sscanf(value, "%d:%d-%d:%d", &startH, &startM, &endH, &endM); err != nil {
+		return nil, fmt.Errorf("expected HH:MM-HH:MM, got %q", value)
+	}
+	for _, v := range []int{startH, endH} {
+		if v < 0 || v > 23 {
+			return nil, fmt.Errorf("hour out of range in %q", value)
+		}
+	}
+	for _, v := range []int{startM, endM} {
+		if v < 0 || v > 59 {
+			return nil, fmt.Errorf("minute out of range in %q", value)
+		}
+	}
+
+	w := &MaintenanceWindow{Start: startH*60 + startM, End: endH*60 + endM}
+	if w.Start == w.End {
+		return nil, fmt.Errorf("window %q is empty", value)
+	}
+	return w, nil
+}
+
+// Contains reports whether t falls inside the window, using t's location
+func (w *MaintenanceWindow) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start < w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+func (w *MaintenanceWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
+
+// bufferReading queues a reading for a later write and returns the number
+// of readings now pending
+func bufferReading(weatherData WeatherData) int {
+	bufferMu.Lock()
+	defer bufferMu.Unlock()
+	bufferedReadings = append(bufferedReadings, weatherData)
+	return len(bufferedReadings)
+}
+
+// flushBufferedReadings writes readings buffered during the maintenance
+// window. On failure the unwritten readings stay buffered for the next run.
+func flushBufferedReadings(db Store) error {
+	bufferMu.Lock()
+	pending := bufferedReadings
+	bufferedReadings = nil
+	bufferMu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	slog.Info("Flushing readings buffered during maintenance window", "component", "processor", "readings", len(pending))
+	for i, weatherData := range pending {
+		waitCatchup()
+		if _, err := storeReading(db, weatherData); err != nil {
+			bufferMu.Lock()
+			bufferedReadings = append(pending[i:], bufferedReadings...)
+			bufferMu.Unlock()
+			return fmt.Errorf("failed to flush buffered readings: %w", err)
+		}
+	}
+	return nil
+}
+
+// flushBufferedOnShutdown writes the readings still buffered when the
+// service stops, e.g. inside the maintenance window. Readings the database
+// does not take are kept in the SQLite mirror for -sync-sqlite when it is
+// enabled; otherwise they are lost and counted in the log.
+func flushBufferedOnShutdown(db Store) {
+	err := flushBufferedReadings(db)
+	if err == nil {
+		return
+	}
+
+	bufferMu.Lock()
+	pending := bufferedReadings
+	bufferedReadings = nil
+	bufferMu.Unlock()
+
+	saved := 0
+	if mirror != nil {
+		for _, weatherData := range pending {
+			if _, err := mirror.Save(weatherData); err != nil {
+				slog.Warn("Failed to mirror buffered reading", "component", "mirror",
+					"measured_at", measurementTime(weatherData.Timestamp), "error", err)
+				continue
+			}
+			saved++
+		}
+	}
+	slog.Warn("Failed to flush buffered readings on shutdown", "component", "processor",
+		"mirrored", saved, "lost", len(pending)-saved, "error", err)
+}
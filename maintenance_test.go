@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setMaintenanceWindow installs window and clears the buffer for the test
+func setMaintenanceWindow(t *testing.T, window string) {
+	t.Helper()
+	w, err := parseMaintenanceWindow(window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := maintenanceWindow
+	maintenanceWindow = w
+	bufferedReadings = nil
+	t.Cleanup(func() {
+		maintenanceWindow = saved
+		bufferedReadings = nil
+	})
+}
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	tests := []struct {
+		value   string
+		at      string
+		want    bool
+		wantErr bool
+	}{
+		{value: "02:00-03:00", at: "02:00", want: true},
+		{value: "02:00-03:00", at: "02:59", want: true},
+		{value: "02:00-03:00", at: "03:00", want: false},
+		{value: "23:30-00:30", at: "23:45", want: true},
+		{value: "23:30-00:30", at: "00:15", want: true},
+		{value: "23:30-00:30", at: "12:00", want: false},
+		{value: "02:00-02:00", wantErr: true},
+		{value: "24:00-01:00", wantErr: true},
+		{value: "02:60-03:00", wantErr: true},
+		{value: "2am-3am", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value+" "+tt.at, func(t *testing.T) {
+			w, err := parseMaintenanceWindow(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseMaintenanceWindow(%q) = %v, want an error", tt.value, w)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			at, _ := time.Parse("15:04", tt.at)
+			if got := w.Contains(at); got != tt.want {
+				t.Errorf("Contains(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowTransition(t *testing.T) {
+	setupTestConfig(t, nil)
+	setMaintenanceWindow(t, "02:00-03:00")
+	db := newTestStore(t)
+
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	process := func(at time.Time) {
+		t.Helper()
+		setTestNow(t, at.Add(30*time.Second))
+		processFixture(t, db, []byte(fmt.Sprintf(
+			`{"timestamp": %d, "temperature": 15, "pressure": 1013, "humidity": 70}`, at.Unix())))
+	}
+	stored := func() string {
+		t.Helper()
+		return queryRows(t, db, `SELECT COUNT(*) AS n FROM weather`)[0]["n"]
+	}
+
+	steps := []struct {
+		name    string
+		at      time.Time
+		stored  string
+		pending int
+	}{
+		{"first reading in the window is buffered", day.Add(2*time.Hour + 10*time.Minute), "0", 1},
+		{"second reading in the window is buffered", day.Add(2*time.Hour + 40*time.Minute), "0", 2},
+		{"first reading after the window flushes the buffer", day.Add(3*time.Hour + 5*time.Minute), "3", 0},
+	}
+	for _, s := range steps {
+		process(s.at)
+		if got := stored(); got != s.stored {
+			t.Errorf("%s: %s readings stored, want %s", s.name, got, s.stored)
+		}
+		if got := len(bufferedReadings); got != s.pending {
+			t.Errorf("%s: %d readings pending, want %d", s.name, got, s.pending)
+		}
+	}
+
+	got := queryRows(t, db, `SELECT hour, samples_count FROM weather_hourly ORDER BY hour`)
+	want := []map[string]string{{"hour": "2", "samples_count": "2"}, {"hour": "3", "samples_count": "1"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("weather_hourly = %v, want %v", got, want)
+	}
+}
+
+func TestFlushBufferedOnShutdown(t *testing.T) {
+	tests := []struct {
+		name       string
+		closeDB    bool
+		withMirror bool
+		stored     string
+		mirrored   string
+	}{
+		{"written to the database", false, false, "2", ""},
+		{"kept in the mirror when the database is gone", true, true, "", "2"},
+		{"lost without a mirror", true, false, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestConfig(t, nil)
+			setMaintenanceWindow(t, "02:00-03:00")
+			db := newTestStore(t)
+
+			if tt.withMirror {
+				m, err := openSQLiteMirror(filepath.Join(t.TempDir(), "mirror.db"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				saved := mirror
+				mirror = m
+				t.Cleanup(func() {
+					mirror = saved
+					m.Close()
+				})
+			}
+
+			at := time.Date(2024, 6, 1, 2, 10, 0, 0, time.UTC)
+			setTestNow(t, at)
+			for i := 0; i < 2; i++ {
+				payload := fmt.Sprintf(`{"timestamp": %d, "temperature": 15, "pressure": 1013, "humidity": 70}`,
+					at.Add(time.Duration(i)*time.Minute).Unix())
+				if err := processPayload(context.Background(), db, []byte(payload), ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tt.closeDB {
+				db.Close()
+			}
+			flushBufferedOnShutdown(db)
+
+			if len(bufferedReadings) != 0 {
+				t.Errorf("%d readings still buffered", len(bufferedReadings))
+			}
+			if tt.stored != "" {
+				if got := queryRows(t, db, `SELECT COUNT(*) AS n FROM weather`)[0]["n"]; got != tt.stored {
+					t.Errorf("%s readings stored, want %s", got, tt.stored)
+				}
+			}
+			if tt.mirrored != "" {
+				if got := queryRows(t, mirror.db, `SELECT COUNT(*) AS n FROM readings WHERE synced = 0`)[0]["n"]; got != tt.mirrored {
+					t.Errorf("%s readings mirrored, want %s", got, tt.mirrored)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsRegistry holds the processing health metrics. It is served on
+// /metrics and written to the node_exporter textfile collector when
+// TEXTFILE_PATH is set.
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	insertsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "weather_inserts_total",
+		Help: "Readings inserted into the weather table.",
+	})
+	insertErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "weather_insert_errors_total",
+		Help: "Failed inserts into the weather table.",
+	})
+	lastProcessedTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "weather_last_processed_timestamp_seconds",
+		Help: "Unix time of the last successfully inserted reading.",
+	})
+	insertDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "weather_insert_duration_seconds",
+		Help:    "Latency of inserting a reading into the weather table, including retries.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+	jsonReadDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "weather_json_read_duration_seconds",
+		Help:    "Latency of reading the JSON source (file, S3 or HTTP).",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+	})
+	statsRunsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "weather_stats_runs_total",
+		Help: "Statistics calculations by job and result (success or error).",
+	}, []string{"job", "result"})
+	statsDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "weather_stats_duration_seconds",
+		Help:    "Duration of the statistics calculations by job.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"job"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(insertsTotal, insertErrorsTotal, lastProcessedTimestamp,
+		insertDuration, jsonReadDuration, statsRunsTotal, statsDuration)
+}
+
+// observeStats runs a statistics calculation and records its duration and
+// result under the given job name
+func observeStats(job string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	statsDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	statsRunsTotal.WithLabelValues(job, result).Inc()
+	return err
+}
+
+// writeTextfile atomically replaces the textfile collector file with the
+// current metrics (WriteToTextfile writes a temp file and renames it)
+func writeTextfile() {
+	if config.TextfilePath == "" || dryRunSkip("textfile", "path", config.TextfilePath) {
+		return
+	}
+	if err := prometheus.WriteToTextfile(config.TextfilePath, metricsRegistry); err != nil {
+		slog.Warn("Failed to write metrics textfile", "component", "metrics", "error", err)
+	}
+}
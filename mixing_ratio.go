@@ -0,0 +1,46 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+)
+
+// epsilonGramsPerKg is the ratio of the molar masses of water vapour and dry
+// air (0.62197) in g/kg
+const epsilonGramsPerKg = 621.97
+
+// computeMixingRatio returns the water vapour mixing ratio in g/kg for a
+// temperature in °C, station pressure in hPa and relative humidity in
+// percent. The vapour pressure uses the same Magnus coefficients as the dew
+// point; humidity is clamped to 0..100 %.
+func computeMixingRatio(tempC, pressureHPa, humidity float64) float64 {
+	rh := math.Max(0, math.Min(humidity, 100))
+	saturation := 6.112 * math.Exp(magnusA*tempC/(magnusB+tempC))
+	vapour := saturation * rh / 100
+	if pressureHPa <= vapour {
+		return 0
+	}
+	return epsilonGramsPerKg * vapour / (pressureHPa - vapour)
+}
+
+// updateDailyMixingRatio stores the daily average mixing ratio of a date
+func updateDailyMixingRatio(db dbExecutor, date string) error {
+	var avg sql.NullFloat64
+	query := `
+		SELECT AVG(mixing_ratio)
+		FROM weather
+		WHERE ` + observationDay() + ` = ?` + exclusionFilter() + stationFilter(db)
+	if err := db.QueryRow(query, date).Scan(&avg); err != nil {
+		return fmt.Errorf("failed to calculate daily mixing ratio: %w", err)
+	}
+	if avg.Valid {
+		avg.Float64 = roundAggregate(avg.Float64)
+	}
+
+	_, err := db.Exec(`UPDATE weather_daily SET avg_mixing_ratio = ? WHERE date = ?`+stationFilter(db), avg, date)
+	if err != nil {
+		return fmt.Errorf("failed to store daily mixing ratio: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeMixingRatio(t *testing.T) {
+	// Reference values from psychrometric tables at sea level pressure
+	tests := []struct {
+		name     string
+		tempC    float64
+		pressure float64
+		humidity float64
+		want     float64 // g/kg
+	}{
+		{"20 °C, 50 %", 20, 1013.25, 50, 7.26},
+		{"30 °C, 80 %", 30, 1013.25, 80, 21.5},
+		{"0 °C, saturated", 0, 1013.25, 100, 3.77},
+		{"20 °C, 50 % at 850 hPa", 20, 850, 50, 8.67},
+		{"dry air", 20, 1013.25, 0, 0},
+		{"humidity clamped to 100 %", 0, 1013.25, 120, 3.77},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeMixingRatio(tt.tempC, tt.pressure, tt.humidity)
+			if math.Abs(got-tt.want) > 0.1 {
+				t.Errorf("computeMixingRatio(%v, %v, %v) = %.3f g/kg, want %.2f ± 0.1", tt.tempC, tt.pressure, tt.humidity, got, tt.want)
+			}
+		})
+	}
+}
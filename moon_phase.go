@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Moon phase names stored in weather_daily.moon_phase_name, starting at the
+// new moon
+var moonPhaseNames = []string{
+	"new_moon",
+	"waxing_crescent",
+	"first_quarter",
+	"waxing_gibbous",
+	"full_moon",
+	"waning_gibbous",
+	"last_quarter",
+	"waning_crescent",
+}
+
+// moonPhase returns the phase of the moon at noon of the given date as a
+// fraction of the lunation (0 = new moon, 0.25 = first quarter, 0.5 = full
+// moon, 0.75 = last quarter) and its name. The phase is the elongation of the
+// moon from the sun, from the low-precision ecliptic longitudes with the
+// largest periodic terms (accurate to a few tenths of a degree, i.e. well
+// under an hour of the lunation).
+func moonPhase(date time.Time) (float64, string) {
+	noon := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, date.Location())
+	d := float64(noon.Unix())/86400 + 2440587.5 - 2451545.0 // days since J2000.0
+
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	// Sun
+	sunAnomaly := rad(357.5291 + 0.98560028*d)
+	sunLongitude := 280.4665 + 0.98564736*d +
+		1.9146*math.Sin(sunAnomaly) + 0.0200*math.Sin(2*sunAnomaly)
+
+	// Moon
+	moonAnomaly := rad(134.9634 + 13.0649929*d)
+	elongation := rad(297.8502 + 12.1907491*d)
+	latitudeArg := rad(93.2721 + 13.2293502*d)
+	moonLongitude := 218.3165 + 13.17639648*d +
+		6.2888*math.Sin(moonAnomaly) +
+		1.2740*math.Sin(2*elongation-moonAnomaly) +
+		0.6583*math.Sin(2*elongation) +
+		0.2136*math.Sin(2*moonAnomaly) -
+		0.1856*math.Sin(sunAnomaly) -
+		0.1143*math.Sin(2*latitudeArg) +
+		0.0588*math.Sin(2*elongation-2*moonAnomaly) +
+		0.0572*math.Sin(2*elongation-sunAnomaly-moonAnomaly) +
+		0.0533*math.Sin(2*elongation+moonAnomaly) +
+		0.0459*math.Sin(2*elongation-sunAnomaly) +
+		0.0410*math.Sin(moonAnomaly-sunAnomaly) -
+		0.0348*math.Sin(elongation) -
+		0.0305*math.Sin(sunAnomaly+moonAnomaly)
+
+	phase := math.Mod(moonLongitude-sunLongitude, 360) / 360
+	if phase < 0 {
+		phase++
+	}
+
+	// Each name covers an eighth of the lunation centred on its phase
+	index := int(math.Floor(phase*8+0.5)) % len(moonPhaseNames)
+	return phase, moonPhaseNames[index]
+}
+
+// updateDailyMoonPhase stores the moon phase of a date
+func updateDailyMoonPhase(db dbExecutor, day time.Time) error {
+	phase, name := moonPhase(day)
+	_, err := db.Exec(`UPDATE weather_daily SET moon_phase = ?, moon_phase_name = ? WHERE date = ?`+stationFilter(db),
+		math.Round(phase*1000)/1000, name, day.Format("2006-01-02"))
+	if err != nil {
+		return fmt.Errorf("failed to store moon phase: %w", err)
+	}
+	return nil
+}
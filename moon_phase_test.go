@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMoonPhase(t *testing.T) {
+	const synodicDays = 29.530589
+	tests := []struct {
+		name  string
+		event time.Time // time of the phase in UTC
+		phase float64
+		day   time.Time
+		want  string
+	}{
+		{"new moon", time.Date(2024, 1, 11, 11, 57, 0, 0, time.UTC), 0, time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC), "new_moon"},
+		{"new moon in the evening", time.Date(2024, 4, 8, 18, 21, 0, 0, time.UTC), 0, time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC), "new_moon"},
+		{"new moon in 2000", time.Date(2000, 1, 6, 18, 14, 0, 0, time.UTC), 0, time.Date(2000, 1, 6, 0, 0, 0, 0, time.UTC), "new_moon"},
+		{"waxing crescent", time.Date(2024, 1, 11, 11, 57, 0, 0, time.UTC), 0, time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC), "waxing_crescent"},
+		{"first quarter", time.Date(2024, 1, 18, 3, 53, 0, 0, time.UTC), 0.25, time.Date(2024, 1, 18, 0, 0, 0, 0, time.UTC), "first_quarter"},
+		{"full moon", time.Date(2024, 1, 25, 17, 54, 0, 0, time.UTC), 0.5, time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC), "full_moon"},
+		{"full moon after midnight", time.Date(2024, 6, 22, 1, 8, 0, 0, time.UTC), 0.5, time.Date(2024, 6, 22, 0, 0, 0, 0, time.UTC), "full_moon"},
+		{"blue moon", time.Date(2023, 8, 31, 1, 36, 0, 0, time.UTC), 0.5, time.Date(2023, 8, 31, 0, 0, 0, 0, time.UTC), "full_moon"},
+		{"last quarter", time.Date(2024, 2, 2, 23, 18, 0, 0, time.UTC), 0.75, time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC), "last_quarter"},
+		{"waning crescent", time.Date(2024, 2, 9, 22, 59, 0, 0, time.UTC), 1, time.Date(2024, 2, 6, 0, 0, 0, 0, time.UTC), "waning_crescent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phase, name := moonPhase(tt.day)
+			if name != tt.want {
+				t.Errorf("moonPhase(%s) name = %s, want %s", tt.day.Format(time.DateOnly), name, tt.want)
+			}
+
+			// Expected phase at noon from the known event, the lunation
+			// treated as uniform
+			noon := tt.day.Add(12 * time.Hour)
+			want := math.Mod(tt.phase+noon.Sub(tt.event).Hours()/24/synodicDays+1, 1)
+			diff := math.Abs(phase - want)
+			if diff > 0.5 {
+				diff = 1 - diff
+			}
+			if diff > 0.02 {
+				t.Errorf("moonPhase(%s) = %.3f, want %.3f", tt.day.Format(time.DateOnly), phase, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// weather_moving keeps one row per trailing window with the averages of the
+// readings within it, refreshed after every insert. Unlike the calendar
+// buckets the windows slide with the current time.
+
+// movingWindows are the trailing windows stored in weather_moving, keyed by
+// window_name
+var movingWindows = []struct {
+	name   string
+	length time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// updateMovingAverages stores the average temperature, pressure and humidity
+// of every trailing window ending at at. A window starts at the same minute
+// its length before at and includes at itself. A window without readings is
+// stored with NULL averages and a zero samples_count, so stale values do not
+// linger.
+func updateMovingAverages(db dbExecutor, at time.Time) error {
+	end := at.In(config.Location)
+	for _, w := range movingWindows {
+		start := end.Truncate(time.Minute).Add(-w.length)
+
+		var avgTemp, avgPressure, avgHumidity sql.NullFloat64
+		var samplesCount int
+		query := `
+			SELECT AVG(temperature), AVG(pressure), AVG(humidity), COUNT(*)
+			FROM weather
+			WHERE measured_at >= ? AND measured_at <= ?` + exclusionFilter() + stationFilter(db)
+		if err := db.QueryRow(query, start, end).Scan(&avgTemp, &avgPressure, &avgHumidity, &samplesCount); err != nil {
+			return fmt.Errorf("failed to calculate %s moving averages: %w", w.name, err)
+		}
+		roundNullAggregates(&avgTemp, &avgPressure, &avgHumidity)
+
+		upsert := `
+			INSERT INTO weather_moving (
+				station_id, window_name, window_start, window_end,
+				avg_temperature, avg_pressure, avg_humidity, samples_count
+			)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				window_start = VALUES(window_start),
+				window_end = VALUES(window_end),
+				avg_temperature = VALUES(avg_temperature),
+				avg_pressure = VALUES(avg_pressure),
+				avg_humidity = VALUES(avg_humidity),
+				samples_count = VALUES(samples_count),
+				updated_at = CURRENT_TIMESTAMP
+		`
+		_, err := db.Exec(upsert, stationOf(db), w.name, start, end,
+			avgTemp, avgPressure, avgHumidity, samplesCount)
+		if err != nil {
+			return fmt.Errorf("failed to store %s moving averages: %w", w.name, err)
+		}
+	}
+	return nil
+}
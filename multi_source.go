@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StationSource is one entry of JSON_SOURCE_URLS
+type StationSource struct {
+	StationID string
+	Reader    HTTPReader
+}
+
+// stationSources are the parsed JSON_SOURCE_URLS, empty for a single source
+var stationSources []StationSource
+
+// stationFetch is the outcome of fetching one station
+type stationFetch struct {
+	StationID string
+	Data      []byte
+	Err       error
+}
+
+// parseStationSources parses JSON_SOURCE_URLS, a comma-separated list of
+// station=url pairs, e.g. "garden=http://10.0.0.5/weather.json"
+func parseStationSources(value string, timeout time.Duration) ([]StationSource, error) {
+	var sources []StationSource
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		station, url, ok := strings.Cut(entry, "=")
+		station, url = strings.TrimSpace(station), strings.TrimSpace(url)
+		if !ok || station == "" || url == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected station=url", entry)
+		}
+		if err := validateStationID(station); err != nil {
+			return nil, fmt.Errorf("invalid station in %q: %w", entry, err)
+		}
+		if seen[station] {
+			return nil, fmt.Errorf("station %q is listed twice", station)
+		}
+		seen[station] = true
+		sources = append(sources, StationSource{StationID: station, Reader: newHTTPReader(url, timeout)})
+	}
+	return sources, nil
+}
+
+// fetchStations fetches all stations concurrently, at most concurrency at a
+// time. Results keep the order of sources; a failed fetch does not affect
+// the others.
+func fetchStations(sources []StationSource, concurrency int) []stationFetch {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]stationFetch, len(sources))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, s := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			data, err := s.Reader.Read()
+			jsonReadDuration.Observe(time.Since(start).Seconds())
+			results[i] = stationFetch{StationID: s.StationID, Data: data, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// processStations fetches every station and stores the readings in one
+// pass. Failing stations are logged and reported together in the returned
+// error; the remaining stations are still processed.
+func processStations(ctx context.Context, db Store, sources []StationSource) error {
+	results := fetchStations(sources, config.FetchConcurrency)
+
+	var errs []error
+	for _, r := range results {
+		err := r.Err
+		if err == nil {
+			err = processPayload(ctx, db, r.Data, r.StationID)
+		}
+		if err != nil {
+			slog.Error("Station failed", "component", "processor", "station_id", r.StationID, "error", err)
+			errs = append(errs, fmt.Errorf("station %s: %w", r.StationID, err))
+		}
+	}
+
+	slog.Info("Processed stations", "component", "processor", "stations", len(results),
+		"succeeded", len(results)-len(errs), "failed", len(errs))
+	return errors.Join(errs...)
+}
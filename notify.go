@@ -0,0 +1,22 @@
+package main
+
+import "log/slog"
+
+// Notifier delivers alerts about processing problems
+type Notifier interface {
+	Notify(message string) error
+}
+
+// notifiers receive every alert; alerts are always logged as well
+var notifiers []Notifier
+
+// notifyError logs an alert and sends it to every configured notifier.
+// Delivery failures are only logged.
+func notifyError(message string) {
+	slog.Error("Alert", "component", "alert", "message", message)
+	for _, n := range notifiers {
+		if err := n.Notify(message); err != nil {
+			slog.Warn("Failed to send alert", "component", "alert", "error", err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// onceAggregates are the statistics jobs -aggregate can run with -once
+var onceAggregates = map[string]func(db dbExecutor) error{
+	"daily":   updateDailyStatistics,
+	"weekly":  updateWeeklyStatistics,
+	"monthly": updateMonthlyStatistics,
+	"yearly":  updateYearlyStatistics,
+}
+
+// validateOnceAggregate checks the -aggregate value
+func validateOnceAggregate(name string) error {
+	if _, ok := onceAggregates[name]; ok || name == "" {
+		return nil
+	}
+	names := make([]string, 0, len(onceAggregates))
+	for n := range onceAggregates {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown aggregate %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// runOnce processes the current reading and, if aggregate is set, runs that
+// statistics job, for external schedulers (cron, systemd timers) driving the
+// cadence. It returns the exit code: 0 when everything succeeded, 1 otherwise.
+func runOnce(pool *ReconnectingDB, aggregate string) int {
+	failed := false
+
+	err := processWeatherData(pool.DB())
+	processor.RecordJob("process", err)
+	if err != nil {
+		slog.Error("Failed to process weather data", "component", "scheduler", "job", "process", "error", err)
+		processor.RecordFailure()
+		failed = true
+	}
+
+	if aggregate != "" {
+		err := observeStats(aggregate, func() error {
+			return forEachStation(pool.DB(), func(db Store) error {
+				return withAggregateTx(db, onceAggregates[aggregate])
+			})
+		})
+		processor.RecordJob(aggregate, err)
+		if err != nil {
+			slog.Error("Failed to calculate statistics", "component", "scheduler", "job", aggregate, "error", err)
+			processor.RecordFailure()
+			failed = true
+		} else {
+			processor.RecordStatsRun(aggregate)
+			slog.Info("Statistics calculated successfully", "component", "scheduler", "job", aggregate)
+		}
+	}
+
+	writeTextfile()
+	if !failed {
+		writeCurrentJSON(pool.DB())
+		writeDailyJSON(pool.DB())
+	}
+	shutdown(nil, pool)
+
+	if failed {
+		return 1
+	}
+	return 0
+}
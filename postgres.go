@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Supported values of DB_DRIVER
+const (
+	DBDriverMySQL    = "mysql"
+	DBDriverPostgres = "postgres"
+)
+
+// postgresCompatDriver is the database/sql name of the PostgreSQL driver
+// that translates the processor's MySQL-flavoured queries, so the rest of
+// the code does not depend on the backend
+const postgresCompatDriver = "postgres-compat"
+
+func init() {
+	sql.Register(postgresCompatDriver, compatDriver{})
+}
+
+// defaultDBPort is the DB_PORT default of the driver
+func defaultDBPort(driver string) string {
+	if driver == DBDriverPostgres {
+		return "5432"
+	}
+	return "3306"
+}
+
+// conflictKeys returns the unique key an upsert into table conflicts on,
+// the target of ON CONFLICT in PostgreSQL
+func conflictKeys(table string) []string {
+	switch table {
+	case "weather_hourly":
+		if config.SplitDSTHours {
+			return []string{"station_id", "date", "hour", "utc_offset"}
+		}
+		return []string{"station_id", "date", "hour"}
+	case "weather_daily", "weather_running":
+		return []string{"station_id", "date"}
+	case "weather_weekly":
+		return []string{"station_id", "year", "week"}
+	case "weather_monthly":
+		return []string{"station_id", "year", "month"}
+	case "weather_yearly", "weather_season":
+		return []string{"station_id", "year"}
+	case "weather_drift":
+		return []string{"station_id", "year", "month", "metric"}
+	case "weather_long":
+		return []string{"station_id", "measured_at", "metric"}
+	case "weather_pressure_change_hist":
+		return []string{"station_id", "bucket"}
+	case "weather_station":
+		return []string{"id"}
+	case "weather_streaks":
+		return []string{"station_id", "kind"}
+	case "weather_moving":
+		return []string{"station_id", "window_name"}
+	case "processor_state":
+		return []string{"station_id"}
+	}
+	return nil
+}
+
+var (
+	insertTablePattern = regexp.MustCompile(`(?i)INSERT\s+INTO\s+(\w+)`)
+	upsertPattern      = regexp.MustCompile(`(?i)ON\s+DUPLICATE\s+KEY\s+UPDATE`)
+	upsertValuePattern = regexp.MustCompile(`(?i)VALUES\((\w+)\)`)
+	identifierPattern  = regexp.MustCompile(`[A-Za-z_]\w*`)
+	hourPattern        = regexp.MustCompile(`(?i)HOUR\(([\w.]+)\)`)
+	intervalPattern    = regexp.MustCompile(`(?i)INTERVAL (\d+) HOUR`)
+)
+
+// sqlKeywords are the words in upsert assignments that are not columns
+var sqlKeywords = map[string]bool{
+	"EXCLUDED": true, "CURRENT_TIMESTAMP": true, "NULL": true,
+	"CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true,
+	"AND": true, "OR": true, "NOT": true, "IS": true,
+}
+
+// translatePostgres rewrites a MySQL query for PostgreSQL: ON DUPLICATE KEY
+// UPDATE becomes ON CONFLICT ... DO UPDATE SET, VALUES(col) EXCLUDED.col,
+// HOUR() and INTERVAL n HOUR their PostgreSQL forms, DATABASE() the current
+// schema and ? placeholders $1, $2, ...
+func translatePostgres(query string) (string, error) {
+	if loc := upsertPattern.FindStringIndex(query); loc != nil {
+		m := insertTablePattern.FindStringSubmatch(query)
+		if m == nil {
+			return "", fmt.Errorf("upsert without INSERT INTO: %s", query)
+		}
+		keys := conflictKeys(m[1])
+		if keys == nil {
+			return "", fmt.Errorf("no conflict key known for table %s", m[1])
+		}
+		update := upsertValuePattern.ReplaceAllString(query[loc[1]:], "EXCLUDED.$1")
+		update = qualifyColumns(update, m[1])
+		query = query[:loc[0]] + "ON CONFLICT (" + strings.Join(keys, ", ") + ") DO UPDATE SET" + update
+	}
+
+	query = hourPattern.ReplaceAllString(query, "CAST(EXTRACT(HOUR FROM $1) AS INTEGER)")
+	query = intervalPattern.ReplaceAllString(query, "INTERVAL '$1 hours'")
+	query = strings.ReplaceAll(query, "DATABASE()", "current_schema()")
+
+	return rebindPlaceholders(query), nil
+}
+
+// qualifyColumns prefixes the columns read in upsert assignments with the
+// table name. PostgreSQL rejects them unqualified as ambiguous with EXCLUDED.
+func qualifyColumns(update, table string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range identifierPattern.FindAllStringIndex(update, -1) {
+		word := update[loc[0]:loc[1]]
+		rest := strings.TrimLeft(update[loc[1]:], " \t\n")
+		qualified := loc[0] > 0 && update[loc[0]-1] == '.'
+		function := strings.HasPrefix(rest, "(")
+		target := strings.HasPrefix(rest, "=")
+		if sqlKeywords[strings.ToUpper(word)] || qualified || function || target {
+			continue
+		}
+		b.WriteString(update[last:loc[0]])
+		b.WriteString(table + "." + word)
+		last = loc[1]
+	}
+	b.WriteString(update[last:])
+	return b.String()
+}
+
+// rebindPlaceholders numbers the ? placeholders outside string literals
+func rebindPlaceholders(query string) string {
+	var b strings.Builder
+	n := 0
+	inString := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inString = !inString
+		case r == '?' && !inString:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// pqConn is the set of interfaces implemented by a lib/pq connection
+type pqConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// compatDriver opens lib/pq connections that translate every query
+type compatDriver struct{}
+
+func (compatDriver) Open(dsn string) (driver.Conn, error) {
+	c, err := pq.Driver{}.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return compatConn{c.(pqConn)}, nil
+}
+
+// compatConn passes everything through to lib/pq after translating queries
+type compatConn struct {
+	pqConn
+}
+
+func (c compatConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c compatConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	q, err := translatePostgres(query)
+	if err != nil {
+		return nil, err
+	}
+	return c.pqConn.PrepareContext(ctx, q)
+}
+
+func (c compatConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	q, err := translatePostgres(query)
+	if err != nil {
+		return nil, err
+	}
+	return c.pqConn.ExecContext(ctx, q, args)
+}
+
+func (c compatConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, err := translatePostgres(query)
+	if err != nil {
+		return nil, err
+	}
+	return c.pqConn.QueryContext(ctx, q, args)
+}
+
+// postgresDSN builds the lib/pq connection string. Times are bound in
+// TIMEZONE, so TIMESTAMP columns hold the station's wall clock as in MySQL.
+func postgresDSN() string {
+	quote := func(v string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+	}
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s timezone=UTC",
+		quote(config.DBHost), quote(config.DBPort), quote(config.DBUser), quote(config.DBPassword),
+		quote(config.DBName), quote(config.DBSSLMode))
+}
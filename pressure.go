@@ -0,0 +1,49 @@
+package main
+
+import "math"
+
+// Supported values of INPUT_PRESSURE_TYPE
+const (
+	PressureTypeStation  = "station"
+	PressureTypeSeaLevel = "sealevel"
+)
+
+// Constants for the hypsometric equation
+const (
+	gravity           = 9.80665 // m/s²
+	dryAirGasConst    = 287.05  // J/(kg·K)
+	standardLapseRate = 0.0065  // K/m
+)
+
+// reducePressure converts station pressure measured at fromAltitude to the
+// pressure at toAltitude using the hypsometric equation. The temperature of
+// the air column is taken as the measured temperature corrected by the
+// standard lapse rate to the middle of the column. Altitudes are in metres,
+// temperature in °C; the result is in the unit of pressure.
+func reducePressure(pressure, temperature, fromAltitude, toAltitude float64) float64 {
+	dh := fromAltitude - toAltitude
+	meanTemp := temperature + 273.15 + standardLapseRate*dh/2
+	return pressure * math.Exp(gravity*dh/(dryAirGasConst*meanTemp))
+}
+
+// seaLevelPressure reduces station pressure to mean sea level
+func seaLevelPressure(pressure, temperature, altitude float64) float64 {
+	return reducePressure(pressure, temperature, altitude, 0)
+}
+
+// stationPressure is the inverse of seaLevelPressure: it derives the pressure
+// at altitude from sea-level pressure and the temperature measured there
+func stationPressure(seaLevel, temperature, altitude float64) float64 {
+	meanTemp := temperature + 273.15 + standardLapseRate*altitude/2
+	return seaLevel / math.Exp(gravity*altitude/(dryAirGasConst*meanTemp))
+}
+
+// readingStationPressure returns the station pressure of a reading,
+// converting it from sea level when INPUT_PRESSURE_TYPE=sealevel. Anything
+// that depends on the local air column must use this instead of the raw value.
+func readingStationPressure(data WeatherData) float64 {
+	if config.InputPressureType == PressureTypeSeaLevel {
+		return stationPressure(data.Pressure, data.Temperature, config.StationAltitude)
+	}
+	return data.Pressure
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// The 3-hour pressure change of every reading is ranked against the
+// historical distribution of such changes. The distribution is kept as a
+// histogram of absolute changes in 0.1 hPa buckets in
+// weather_pressure_change_hist, so ranking never scans the raw table.
+
+const (
+	pressureChangeWindow    = 3 * time.Hour
+	pressureChangeTolerance = 15 * time.Minute
+)
+
+// percentileRank returns the percentile rank of a value given how many
+// historical values fall below it and into its own bucket
+func percentileRank(below, equal, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return (float64(below) + float64(equal)/2) / float64(total) * 100
+}
+
+// pressureBefore returns the pressure of the latest reading with a pressure
+// at or before at, if it is at most pressureChangeTolerance older. It returns
+// sql.ErrNoRows when there is no such reading.
+func pressureBefore(db dbExecutor, at time.Time) (float64, error) {
+	var pressure float64
+	err := db.QueryRow(`
+		SELECT pressure FROM weather
+		WHERE measured_at <= ? AND measured_at >= ? AND pressure IS NOT NULL`+stationFilter(db)+`
+		ORDER BY measured_at DESC
+		LIMIT 1
+	`, at, at.Add(-pressureChangeTolerance)).Scan(&pressure)
+	return pressure, err
+}
+
+// updatePressureChangePercentile stores the 3-hour pressure change of a
+// reading with its percentile rank and adds the change to the histogram
+func updatePressureChangePercentile(db dbExecutor, weatherID int64, measuredAt time.Time, pressure float64) error {
+	// The latest reading at least 3 hours old, if it is not much older
+	past, err := pressureBefore(db, measuredAt.Add(-pressureChangeWindow))
+	if err == sql.ErrNoRows {
+		slog.Info("No reading 3 hours before, skipping pressure change", "component", "processor", "measured_at", measuredAt)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query pressure 3 hours ago: %w", err)
+	}
+
+	change := roundTo(pressure-past, config.RoundDecimals)
+	bucket := int(math.Round(math.Abs(change) * 10))
+
+	var below, equal, total int64
+	histQuery := `
+		SELECT
+			COALESCE(SUM(CASE WHEN bucket < ? THEN samples END), 0),
+			COALESCE(SUM(CASE WHEN bucket = ? THEN samples END), 0),
+			COALESCE(SUM(samples), 0)
+		FROM weather_pressure_change_hist
+		WHERE 1 = 1` + stationFilter(db) + `
+	`
+	if err := db.QueryRow(histQuery, bucket, bucket).Scan(&below, &equal, &total); err != nil {
+		return fmt.Errorf("failed to query pressure change histogram: %w", err)
+	}
+
+	// Too little history for a meaningful rank
+	var pctile sql.NullFloat64
+	if total >= int64(config.PressureChangeMinSamples) {
+		pctile = sql.NullFloat64{Float64: roundTo(percentileRank(below, equal, total), config.RoundDecimals), Valid: true}
+	}
+
+	_, err = db.Exec(`UPDATE weather SET pressure_change_3h = ?, pressure_change_pctile = ? WHERE id = ?`,
+		change, pctile, weatherID)
+	if err != nil {
+		return fmt.Errorf("failed to store pressure change: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO weather_pressure_change_hist (station_id, bucket, samples) VALUES (?, ?, 1)
+		ON DUPLICATE KEY UPDATE samples = samples + 1
+	`, stationOf(db), bucket)
+	if err != nil {
+		return fmt.Errorf("failed to update pressure change histogram: %w", err)
+	}
+
+	if pctile.Valid && pctile.Float64 > config.PressureChangeAlertPctile {
+		slog.Warn("Significant pressure change in 3 hours", "component", "processor", "change_hpa", change, "percentile", pctile.Float64)
+	}
+	return nil
+}
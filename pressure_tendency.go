@@ -0,0 +1,92 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// pressureTendency returns the WMO code table 0200 characteristic (0-8) of
+// the pressure curve over the last 3 hours and the amount of the change
+// (hPa, unsigned as in SYNOP 5appp; the sign follows from the code). The
+// curve is described by the pressure 3 hours ago, 1.5 hours ago and now;
+// a half-period change within steady hPa counts as steady.
+//
+//	0 rising then falling, same or higher    5 falling then rising, same or lower
+//	1 rising then steady or rising slower    6 falling then steady or falling slower
+//	2 rising steadily                        7 falling steadily
+//	3 steady/falling then rising, or rising  8 steady/rising then falling, or
+//	  faster                                   falling faster
+//	4 steady
+func pressureTendency(start, mid, end, steady float64) (int, float64) {
+	first, second := mid-start, end-mid
+	net := end - start
+	amount := roundTo(math.Abs(net), config.RoundDecimals)
+
+	rising := func(d float64) bool { return d > steady }
+	falling := func(d float64) bool { return d < -steady }
+
+	switch {
+	case math.Abs(net) <= steady:
+		switch {
+		case rising(first) && falling(second):
+			return 0, amount
+		case falling(first) && rising(second):
+			return 5, amount
+		}
+		return 4, amount
+
+	case net > 0:
+		switch {
+		case rising(first) && falling(second):
+			return 0, amount
+		case !rising(first):
+			return 3, amount
+		case second < first/2:
+			return 1, amount
+		case second > first*2:
+			return 3, amount
+		}
+		return 2, amount
+
+	default:
+		switch {
+		case falling(first) && rising(second):
+			return 5, amount
+		case !falling(first):
+			return 8, amount
+		case second > first/2:
+			return 6, amount
+		case second < first*2:
+			return 8, amount
+		}
+		return 7, amount
+	}
+}
+
+// updatePressureTendency stores the 3-hour pressure tendency of a reading.
+// Readings without history 3 and 1.5 hours back are skipped.
+func updatePressureTendency(db dbExecutor, weatherID int64, measuredAt time.Time, pressure float64) error {
+	var mid float64
+	start, err := pressureBefore(db, measuredAt.Add(-pressureChangeWindow))
+	if err == nil {
+		mid, err = pressureBefore(db, measuredAt.Add(-pressureChangeWindow/2))
+	}
+	if err == sql.ErrNoRows {
+		slog.Info("Not enough pressure history, skipping pressure tendency", "component", "processor", "measured_at", measuredAt)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query pressure history: %w", err)
+	}
+
+	code, amount := pressureTendency(start, mid, pressure, config.PressureTendencySteady)
+	_, err = db.Exec(`UPDATE weather SET pressure_tendency_code = ?, pressure_tendency_amount = ? WHERE id = ?`,
+		code, amount, weatherID)
+	if err != nil {
+		return fmt.Errorf("failed to store pressure tendency: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPressureTendency(t *testing.T) {
+	setupTestConfig(t, nil)
+
+	tests := []struct {
+		name            string
+		start, mid, end float64
+		wantCode        int
+		wantAmount      float64
+	}{
+		{"0 rising then falling", 1000, 1002, 1001, 0, 1},
+		{"1 rising then rising slower", 1000, 1002, 1002.5, 1, 2.5},
+		{"2 rising steadily", 1000, 1001, 1002, 2, 2},
+		{"3 steady then rising", 1000, 1000, 1002, 3, 2},
+		{"3 rising faster", 1000, 1000.5, 1002.5, 3, 2.5},
+		{"4 steady", 1000, 1000.05, 1000, 4, 0},
+		{"5 falling then rising", 1002, 1000, 1001, 5, 1},
+		{"6 falling then falling slower", 1002.5, 1000.5, 1000, 6, 2.5},
+		{"7 falling steadily", 1002, 1001, 1000, 7, 2},
+		{"8 steady then falling", 1002, 1002, 1000, 8, 2},
+		{"8 falling faster", 1002.5, 1002, 1000, 8, 2.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, amount := pressureTendency(tt.start, tt.mid, tt.end, 0.1)
+			if code != tt.wantCode || math.Abs(amount-tt.wantAmount) > 1e-9 {
+				t.Errorf("pressureTendency(%v, %v, %v) = %d, %v, want %d, %v",
+					tt.start, tt.mid, tt.end, code, amount, tt.wantCode, tt.wantAmount)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReducePressure(t *testing.T) {
+	tests := []struct {
+		name         string
+		pressure     float64
+		temperature  float64
+		fromAltitude float64
+		toAltitude   float64
+		want         float64
+		tol          float64
+	}{
+		{"same altitude", 987.6, 12, 350, 350, 987.6, 1e-9},
+		{"sea level station", 1013.25, 15, 0, 0, 1013.25, 1e-9},
+		// ICAO standard atmosphere: 1013.25 hPa and 15 °C at sea level,
+		// 954.61 hPa at 500 m, 898.75 hPa at 1000 m, 845.56 hPa at 1500 m
+		{"standard atmosphere from 500 m", 954.61, 11.75, 500, 0, 1013.25, 0.05},
+		{"standard atmosphere from 1500 m", 845.56, 5.25, 1500, 0, 1013.25, 0.05},
+		{"standard atmosphere to a higher station", 1013.25, 15, 0, 1000, 898.75, 0.05},
+		{"standard atmosphere to a lower station", 898.75, 8.5, 1000, 200, 989.45, 0.05},
+		// Colder air is denser, so the reduction adds more
+		{"cold air from 500 m", 955, -10, 500, 0, 1018.64, 0.01},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := reducePressure(tt.pressure, tt.temperature, tt.fromAltitude, tt.toAltitude)
+			if math.Abs(got-tt.want) > tt.tol {
+				t.Errorf("reducePressure(%v, %v, %v, %v) = %.3f, want %v ± %v",
+					tt.pressure, tt.temperature, tt.fromAltitude, tt.toAltitude, got, tt.want, tt.tol)
+			}
+			if tt.toAltitude == 0 {
+				if sl := seaLevelPressure(tt.pressure, tt.temperature, tt.fromAltitude); sl != got {
+					t.Errorf("seaLevelPressure() = %v, reducePressure to 0 m = %v", sl, got)
+				}
+				if back := stationPressure(got, tt.temperature, tt.fromAltitude); math.Abs(back-tt.pressure) > 1e-9 {
+					t.Errorf("stationPressure(seaLevelPressure()) = %v, want %v", back, tt.pressure)
+				}
+			}
+		})
+	}
+}
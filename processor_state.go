@@ -0,0 +1,80 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// processor_state keeps the timestamp of the last stored reading of each
+// station, so a restart does not process the unchanged file again. A single
+// station has a single row.
+
+// resumeState holds the timestamps loaded at startup. They only guard the
+// first run after the restart; later runs rely on the duplicate check alone.
+type resumeState struct {
+	mu         sync.Mutex
+	timestamps map[string]int64
+}
+
+var resume resumeState
+
+// Set replaces the loaded timestamps
+func (r *resumeState) Set(timestamps map[string]int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timestamps = timestamps
+}
+
+// Clear stops skipping readings once the first run is done
+func (r *resumeState) Clear() {
+	r.Set(nil)
+}
+
+// AlreadyProcessed reports whether a reading of stationID at timestamp is
+// not newer than the last one stored before the restart
+func (r *resumeState) AlreadyProcessed(stationID string, timestamp int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last, ok := r.timestamps[stationID]
+	return ok && timestamp <= last
+}
+
+// loadProcessorState reads the last processed timestamp of every station
+func loadProcessorState(db *sql.DB) (map[string]int64, error) {
+	rows, err := db.Query(`SELECT station_id, last_timestamp FROM processor_state`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query processor state: %w", err)
+	}
+	defer rows.Close()
+
+	timestamps := make(map[string]int64)
+	for rows.Next() {
+		var stationID string
+		var ts int64
+		if err := rows.Scan(&stationID, &ts); err != nil {
+			return nil, fmt.Errorf("failed to scan processor state: %w", err)
+		}
+		timestamps[stationID] = ts
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate processor state: %w", err)
+	}
+	return timestamps, nil
+}
+
+// saveProcessorState records timestamp as processed for stationID. The
+// stored value never moves back, so imports and late readings keep it.
+func saveProcessorState(db dbExecutor, stationID string, timestamp int64) error {
+	upsert := `
+		INSERT INTO processor_state (station_id, last_timestamp)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE
+			last_timestamp = GREATEST(last_timestamp, VALUES(last_timestamp)),
+			updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := db.Exec(upsert, stationID, timestamp); err != nil {
+		return fmt.Errorf("failed to save processor state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// The rain gauge reports a cumulative counter in mm (rain_mm). Rainfall over
+// a period is the sum of the increases of the counter; when the counter drops
+// it was reset and its new value is the rain since the reset.
+
+// rainfallTotal sums the rainfall from consecutive counter values. baseline
+// is the last value before the period, or nil when unknown.
+func rainfallTotal(baseline *float64, values []float64) float64 {
+	var total float64
+	prev := baseline
+	for i := range values {
+		v := values[i]
+		switch {
+		case prev == nil:
+		case v >= *prev:
+			total += v - *prev
+		default:
+			total += v
+		}
+		prev = &values[i]
+	}
+	return total
+}
+
+// updateDailyRainfall stores the total rainfall of a date. The baseline is
+// the last counter value before the observation day starts.
+func updateDailyRainfall(db dbExecutor, date string) error {
+	start, _, err := observationDayBounds(date)
+	if err != nil {
+		return err
+	}
+
+	var baseline *float64
+	var last float64
+	err = db.QueryRow(`
+		SELECT rain_mm FROM weather
+		WHERE measured_at < ? AND rain_mm IS NOT NULL`+stationFilter(db)+`
+		ORDER BY measured_at DESC
+		LIMIT 1
+	`, start).Scan(&last)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to query previous rain counter: %w", err)
+	}
+	if err == nil {
+		baseline = &last
+	}
+
+	rows, err := db.Query(`
+		SELECT rain_mm FROM weather
+		WHERE `+observationDay()+` = ? AND rain_mm IS NOT NULL`+exclusionFilter()+stationFilter(db)+`
+		ORDER BY measured_at
+	`, date)
+	if err != nil {
+		return fmt.Errorf("failed to query rain counter: %w", err)
+	}
+	defer rows.Close()
+
+	var values []float64
+	for rows.Next() {
+		var v float64
+		if err := rows.Scan(&v); err != nil {
+			return fmt.Errorf("failed to scan rain counter: %w", err)
+		}
+		values = append(values, v)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate rain counter: %w", err)
+	}
+
+	var total sql.NullFloat64
+	if len(values) > 0 {
+		total = sql.NullFloat64{Float64: roundAggregate(rainfallTotal(baseline, values)), Valid: true}
+		slog.Info("Daily rainfall", "component", "stats", "date", date, "rain_mm", total.Float64)
+	}
+
+	_, err = db.Exec(`UPDATE weather_daily SET total_rain = ? WHERE date = ?`+stationFilter(db), total, date)
+	if err != nil {
+		return fmt.Errorf("failed to store daily rainfall: %w", err)
+	}
+	return nil
+}
+
+// updateMonthlyRainDays counts the days of a month with rainfall at or above
+// RAIN_DAY_THRESHOLD and finds the wettest day
+func updateMonthlyRainDays(db dbExecutor, year, month int, firstDay, lastDay string) error {
+	var rainDays int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM weather_daily
+		WHERE date >= ? AND date <= ? AND total_rain >= ?`+stationFilter(db)+`
+	`, firstDay, lastDay, config.RainDayThreshold).Scan(&rainDays)
+	if err != nil {
+		return fmt.Errorf("failed to count rain days: %w", err)
+	}
+
+	var wettestDay sql.NullTime
+	var wettestRain sql.NullFloat64
+	err = db.QueryRow(`
+		SELECT date, total_rain FROM weather_daily
+		WHERE date >= ? AND date <= ? AND total_rain > 0`+stationFilter(db)+`
+		ORDER BY total_rain DESC, date
+		LIMIT 1
+	`, firstDay, lastDay).Scan(&wettestDay, &wettestRain)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to find wettest day: %w", err)
+	}
+
+	if wettestDay.Valid {
+		slog.Info("Monthly rain days", "component", "stats", "year", year, "month", month,
+			"rain_days", rainDays, "wettest_day", wettestDay.Time.Format(time.DateOnly), "wettest_day_rain_mm", wettestRain.Float64)
+	}
+
+	update := `
+		UPDATE weather_monthly
+		SET rain_days = ?, wettest_day = ?, wettest_day_rain = ?
+		WHERE year = ? AND month = ?` + stationFilter(db) + `
+	`
+	if _, err := db.Exec(update, rainDays, wettestDay, wettestRain, year, month); err != nil {
+		return fmt.Errorf("failed to store rain days: %w", err)
+	}
+	return nil
+}
+
+// rainRateFromDelta derives the rain rate in mm/h from two consecutive
+// counter values taken interval apart. A counter drop is treated as a reset.
+func rainRateFromDelta(prev, cur float64, interval time.Duration) float64 {
+	if interval <= 0 {
+		return 0
+	}
+	delta := cur - prev
+	if delta < 0 {
+		delta = cur
+	}
+	return delta / interval.Hours()
+}
+
+// derivedRainRate computes the rain rate of a reading from the rain counter
+// of the previous reading; nil when there is no usable previous reading
+func derivedRainRate(db dbExecutor, measuredAt time.Time, rainMM float64) (*float64, error) {
+	var prevAt time.Time
+	var prevMM float64
+	err := db.QueryRow(`
+		SELECT measured_at, rain_mm FROM weather
+		WHERE measured_at < ? AND rain_mm IS NOT NULL`+stationFilter(db)+`
+		ORDER BY measured_at DESC
+		LIMIT 1
+	`, measuredAt).Scan(&prevAt, &prevMM)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query previous rain counter: %w", err)
+	}
+
+	// A long gap would spread a burst of rain over hours
+	interval := measuredAt.Sub(prevAt)
+	if interval > time.Hour {
+		return nil, nil
+	}
+
+	rate := roundTo(rainRateFromDelta(prevMM, rainMM, interval), config.RoundDecimals)
+	return &rate, nil
+}
+
+// updateDailyMaxRainRate stores the peak rain rate of a date
+func updateDailyMaxRainRate(db dbExecutor, date string) error {
+	var maxRate sql.NullFloat64
+	query := `SELECT MAX(rain_rate) FROM weather WHERE ` + observationDay() + ` = ?` + exclusionFilter() + stationFilter(db)
+	if err := db.QueryRow(query, date).Scan(&maxRate); err != nil {
+		return fmt.Errorf("failed to query max rain rate: %w", err)
+	}
+
+	_, err := db.Exec(`UPDATE weather_daily SET max_rain_rate = ? WHERE date = ?`+stationFilter(db), roundNullAggregate(maxRate), date)
+	if err != nil {
+		return fmt.Errorf("failed to store max rain rate: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRainfallTotal(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name     string
+		baseline *float64
+		values   []float64
+		want     float64
+	}{
+		{"no values", f(3), nil, 0},
+		{"unknown baseline skips the first value", nil, []float64{5, 6, 8}, 3},
+		{"increases from the baseline", f(2), []float64{2.5, 4, 4}, 2},
+		{"unchanged counter", f(7.2), []float64{7.2, 7.2}, 0},
+		{"reset counts the new value", f(10), []float64{12, 0.5, 1.5}, 3.5},
+		{"reset to zero", f(10), []float64{0, 0.2}, 0.2},
+		{"reset before the first value", f(50), []float64{1, 2}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rainfallTotal(tt.baseline, tt.values); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("rainfallTotal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonthlyRainDaysThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold string
+		rainDays  string
+	}{
+		{"threshold of 1 mm counts the day at 1 mm", "1", "3"},
+		{"threshold just above", "1.01", "2"},
+		{"threshold of 0.2 mm", "0.2", "4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestConfig(t, map[string]string{"RAIN_DAY_THRESHOLD": tt.threshold})
+			db := newTestStore(t)
+
+			days := []struct {
+				date string
+				rain any
+			}{
+				{"2024-06-01", 0.0},
+				{"2024-06-02", 0.9},
+				{"2024-06-03", 1.0},
+				{"2024-06-04", 12.4},
+				{"2024-06-05", 3.1},
+				{"2024-06-06", nil},
+				{"2024-07-01", 30.0},
+			}
+			for _, d := range days {
+				_, err := db.Exec(`INSERT INTO weather_daily (date, samples_count, total_rain) VALUES (?, 1, ?)`, d.date, d.rain)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := db.Exec(`INSERT INTO weather_monthly (year, month, samples_count) VALUES (2024, 6, 1)`); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := updateMonthlyRainDays(db, 2024, 6, "2024-06-01", "2024-06-30"); err != nil {
+				t.Fatalf("updateMonthlyRainDays: %v", err)
+			}
+			got := queryRows(t, db, `SELECT rain_days, wettest_day, wettest_day_rain FROM weather_monthly`)[0]
+			want := map[string]string{"rain_days": tt.rainDays, "wettest_day": "2024-06-04", "wettest_day_rain": "12.4"}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("weather_monthly = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRainRateFromDelta(t *testing.T) {
+	tests := []struct {
+		name     string
+		prev     float64
+		cur      float64
+		interval time.Duration
+		want     float64
+	}{
+		{"dry", 4.2, 4.2, 10 * time.Minute, 0},
+		{"rising counter", 4.2, 5.2, 10 * time.Minute, 6},
+		{"reset counts the new value", 25.4, 0.4, 5 * time.Minute, 4.8},
+		{"reset to zero", 25.4, 0, 5 * time.Minute, 0},
+		{"no interval", 1, 2, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rainRateFromDelta(tt.prev, tt.cur, tt.interval); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("rainRateFromDelta(%v, %v, %v) = %v, want %v", tt.prev, tt.cur, tt.interval, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDerivedRainRateIsStored(t *testing.T) {
+	setupTestConfig(t, map[string]string{"ENABLE_RAINFALL": "true", "ENABLE_RAIN_RATE": "true"})
+	db := newTestStore(t)
+
+	start := time.Date(2024, 6, 1, 14, 0, 0, 0, config.Location)
+	readings := []struct {
+		minutes int
+		rainMM  float64
+	}{
+		{0, 10.0},   // first sample, no previous counter
+		{10, 10.5},  // 0.5 mm in 10 minutes
+		{20, 10.5},  // dry
+		{30, 0.3},   // counter rolled over
+		{120, 1.3},  // 90 minutes since the last reading
+		{125, 1.35}, // back to a short interval
+	}
+	for _, r := range readings {
+		processFixture(t, db, []byte(fmt.Sprintf(
+			`{"timestamp": %d, "temperature": 15.0, "pressure": 1008.0, "humidity": 95.0, "rain_mm": %.2f}`,
+			start.Add(time.Duration(r.minutes)*time.Minute).Unix(), r.rainMM)))
+	}
+
+	var got []string
+	for _, row := range queryRows(t, db, `SELECT rain_rate FROM weather ORDER BY measured_at`) {
+		got = append(got, row["rain_rate"])
+	}
+	want := []string{"", "3", "0", "1.8", "", "0.6"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("rain_rate = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// withRetry runs fn up to attempts times while it fails with a transient
+// connection error (refused connection, driver.ErrBadConn, timeout), waiting
+// baseDelay, 2*baseDelay, 4*baseDelay, ... plus up to 50 % jitter between
+// attempts. Any other error, e.g. malformed SQL, is returned immediately.
+// Every attempt is reported to dbPool, which rebuilds the pool after
+// repeated connection errors.
+func withRetry(attempts int, baseDelay time.Duration, fn func() error) error {
+	delay := baseDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		dbPool.Observe(err)
+		if err == nil || !isConnectionError(err) || attempt >= attempts {
+			return err
+		}
+
+		wait := delay
+		if delay > 0 {
+			wait += rand.N(delay/2 + 1)
+		}
+		slog.Warn("Transient database error, retrying", "component", "db", "attempt", attempt, "attempts", attempts, "wait", wait, "error", err)
+		time.Sleep(wait)
+		delay *= 2
+	}
+}
+
+// withDBRetry runs fn with the configured MAX_DB_RETRIES and DB_RETRY_BASE_MS
+func withDBRetry(fn func() error) error {
+	return withRetry(config.MaxDBRetries, time.Duration(config.DBRetryBaseMS)*time.Millisecond, fn)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported values of a validation rule's action
+const (
+	RuleActionReject = "reject"
+	RuleActionFlag   = "flag"
+)
+
+// ValidationRule is one declarative check of a metric from RULES_FILE.
+// Values are compared after unit normalization, like the plausible ranges.
+type ValidationRule struct {
+	Metric           string   `json:"metric" yaml:"metric"`
+	Min              *float64 `json:"min" yaml:"min"`
+	Max              *float64 `json:"max" yaml:"max"`
+	MaxRatePerMinute *float64 `json:"max_rate_per_minute" yaml:"max_rate_per_minute"`
+	Required         bool     `json:"required" yaml:"required"`
+	Action           string   `json:"action" yaml:"action"`
+}
+
+// rulesFile is the layout of RULES_FILE
+type rulesFile struct {
+	Rules []ValidationRule `json:"rules" yaml:"rules"`
+}
+
+// plainRuleMetrics are the fields of readingFields decoded into plain
+// floats, which are never nil; whether they were sent is looked up in the
+// payload
+var plainRuleMetrics = map[string]bool{
+	"temperature": true, "pressure": true, "humidity": true,
+	"wind_speed": true, "wind_direction": true,
+}
+
+// loadValidationRules reads and validates a JSON or, by the .yaml/.yml
+// extension, YAML rules file. Unknown keys are errors so that typos do not
+// silently disable a rule.
+func loadValidationRules(path string) ([]ValidationRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var file rulesFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&file)
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&file)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse rules file %s: %w", path, err)
+	}
+
+	for i := range file.Rules {
+		if err := validateRule(&file.Rules[i]); err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, file.Rules[i].Metric, err)
+		}
+	}
+	return file.Rules, nil
+}
+
+// validateRule checks one rule and fills in the default action
+func validateRule(r *ValidationRule) error {
+	if r.Metric == "" {
+		return errors.New("metric is required")
+	}
+	if _, ok := readingFields(WeatherData{})[r.Metric]; !ok {
+		return fmt.Errorf("unknown metric %q", r.Metric)
+	}
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return fmt.Errorf("min %g is greater than max %g", *r.Min, *r.Max)
+	}
+	if r.MaxRatePerMinute != nil && *r.MaxRatePerMinute <= 0 {
+		return fmt.Errorf("max_rate_per_minute must be positive, got %g", *r.MaxRatePerMinute)
+	}
+	if r.Min == nil && r.Max == nil && r.MaxRatePerMinute == nil && !r.Required {
+		return errors.New("rule checks nothing, set min, max, max_rate_per_minute or required")
+	}
+	switch r.Action {
+	case "":
+		r.Action = RuleActionReject
+	case RuleActionReject, RuleActionFlag:
+	default:
+		return fmt.Errorf("action must be %q or %q, got %q", RuleActionReject, RuleActionFlag, r.Action)
+	}
+	return nil
+}
+
+// ruleSample is the last accepted value of a metric, for rate checks
+type ruleSample struct {
+	value     float64
+	timestamp int64
+}
+
+// RuleEngine evaluates the validation rules and remembers the last accepted
+// value of each station's metrics. Rate checks start with the second reading
+// after a restart.
+type RuleEngine struct {
+	rules []ValidationRule
+
+	mu   sync.Mutex
+	last map[string]ruleSample // station/metric -> last accepted value
+}
+
+var ruleEngine *RuleEngine
+
+func newRuleEngine(rules []ValidationRule) *RuleEngine {
+	return &RuleEngine{rules: rules, last: make(map[string]ruleSample)}
+}
+
+// ruleValue returns the value of a metric, nil when the reading lacks it
+func ruleValue(fields map[string]*float64, metric string, sent map[string]bool) *float64 {
+	if plainRuleMetrics[metric] && !sent[metric] {
+		return nil
+	}
+	return fields[metric]
+}
+
+// Evaluate checks a reading against every rule. It returns the violations
+// of reject rules and of flag rules separately. Only readings without
+// reject violations update the values the rate checks compare against.
+func (e *RuleEngine) Evaluate(w *WeatherData) (rejected, flagged []string) {
+	fields := readingFields(*w)
+	sent := payloadKeys(w.Raw)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, r := range e.rules {
+		var problems []string
+		v := ruleValue(fields, r.Metric, sent)
+		switch {
+		case v == nil:
+			if r.Required {
+				problems = append(problems, fmt.Sprintf("%s is missing", r.Metric))
+			}
+		case math.IsNaN(*v):
+			problems = append(problems, fmt.Sprintf("%s is not a number", r.Metric))
+		default:
+			if r.Min != nil && *v < *r.Min {
+				problems = append(problems, fmt.Sprintf("%s %g below %g", r.Metric, *v, *r.Min))
+			}
+			if r.Max != nil && *v > *r.Max {
+				problems = append(problems, fmt.Sprintf("%s %g above %g", r.Metric, *v, *r.Max))
+			}
+			if r.MaxRatePerMinute != nil {
+				if prev, ok := e.last[w.StationID+"/"+r.Metric]; ok && w.Timestamp > prev.timestamp {
+					minutes := float64(w.Timestamp-prev.timestamp) / 60
+					if rate := math.Abs(*v-prev.value) / minutes; rate > *r.MaxRatePerMinute {
+						problems = append(problems, fmt.Sprintf("%s changed %g/min, more than %g/min",
+							r.Metric, math.Round(rate*100)/100, *r.MaxRatePerMinute))
+					}
+				}
+			}
+		}
+
+		if r.Action == RuleActionFlag {
+			flagged = append(flagged, problems...)
+		} else {
+			rejected = append(rejected, problems...)
+		}
+	}
+
+	if len(rejected) == 0 {
+		for _, r := range e.rules {
+			if v := ruleValue(fields, r.Metric, sent); r.MaxRatePerMinute != nil && v != nil && !math.IsNaN(*v) {
+				e.last[w.StationID+"/"+r.Metric] = ruleSample{value: *v, timestamp: w.Timestamp}
+			}
+		}
+	}
+	return rejected, flagged
+}
+
+// payloadKeys returns the top-level keys of a JSON payload that are not null
+func payloadKeys(raw []byte) map[string]bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+	keys := make(map[string]bool, len(fields))
+	for k, v := range fields {
+		keys[k] = string(v) != "null"
+	}
+	return keys
+}
+
+// applyValidationRules logs flagged rule violations and returns an error
+// listing the violations of reject rules
+func applyValidationRules(w *WeatherData) error {
+	rejected, flagged := ruleEngine.Evaluate(w)
+	if len(flagged) > 0 {
+		slog.Warn("Reading flagged by validation rules", "component", "processor",
+			"measured_at", measurementTime(w.Timestamp), "violations", strings.Join(flagged, ", "))
+	}
+	if len(rejected) > 0 {
+		return fmt.Errorf("reading violates validation rules: %s", strings.Join(rejected, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadValidationRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []string // metric/action of each rule
+		wantErr string
+	}{
+		{"json", "rules.json", `{"rules": [{"metric": "temperature", "min": -40, "max": 50}]}`, []string{"temperature/reject"}, ""},
+		{"yaml", "rules.yaml", "rules:\n  - metric: humidity\n    required: true\n    action: flag\n", []string{"humidity/flag"}, ""},
+		{"empty file", "rules.json", "", nil, ""},
+		{"unknown key", "rules.json", `{"rules": [{"metric": "temperature", "maximum": 50}]}`, nil, "unknown field"},
+		{"unknown yaml key", "rules.yml", "rules:\n  - metric: temperature\n    maximum: 50\n", nil, "not found"},
+		{"unknown metric", "rules.json", `{"rules": [{"metric": "temp", "max": 50}]}`, nil, `unknown metric "temp"`},
+		{"missing metric", "rules.json", `{"rules": [{"max": 50}]}`, nil, "metric is required"},
+		{"min above max", "rules.json", `{"rules": [{"metric": "pressure", "min": 1100, "max": 900}]}`, nil, "greater than max"},
+		{"zero rate", "rules.json", `{"rules": [{"metric": "pressure", "max_rate_per_minute": 0}]}`, nil, "must be positive"},
+		{"no check", "rules.json", `{"rules": [{"metric": "pressure"}]}`, nil, "checks nothing"},
+		{"bad action", "rules.json", `{"rules": [{"metric": "pressure", "max": 1100, "action": "drop"}]}`, nil, "action must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			rules, err := loadValidationRules(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadValidationRules() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range rules {
+				got = append(got, r.Metric+"/"+r.Action)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleEngineEvaluate(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	type step struct {
+		payload  string
+		rejected []string
+		flagged  []string
+	}
+	tests := []struct {
+		name  string
+		rules []ValidationRule
+		steps []step
+	}{
+		{
+			name:  "min and max",
+			rules: []ValidationRule{{Metric: "temperature", Min: f(-40), Max: f(50), Action: RuleActionReject}},
+			steps: []step{
+				{payload: `{"timestamp": 1000, "temperature": -40}`},
+				{payload: `{"timestamp": 1060, "temperature": 50}`},
+				{payload: `{"timestamp": 1120, "temperature": -41}`, rejected: []string{"temperature -41 below -40"}},
+				{payload: `{"timestamp": 1180, "temperature": 51}`, rejected: []string{"temperature 51 above 50"}},
+			},
+		},
+		{
+			name:  "required",
+			rules: []ValidationRule{{Metric: "humidity", Required: true, Action: RuleActionReject}},
+			steps: []step{
+				{payload: `{"timestamp": 1000, "humidity": 0}`},
+				{payload: `{"timestamp": 1060}`, rejected: []string{"humidity is missing"}},
+				{payload: `{"timestamp": 1120, "humidity": null}`, rejected: []string{"humidity is missing"}},
+			},
+		},
+		{
+			name:  "optional metric without a value passes min",
+			rules: []ValidationRule{{Metric: "co2", Min: f(300), Action: RuleActionReject}},
+			steps: []step{
+				{payload: `{"timestamp": 1000}`},
+				{payload: `{"timestamp": 1060, "co2": 250}`, rejected: []string{"co2 250 below 300"}},
+			},
+		},
+		{
+			name:  "rate of change",
+			rules: []ValidationRule{{Metric: "pressure", MaxRatePerMinute: f(1), Action: RuleActionReject}},
+			steps: []step{
+				{payload: `{"timestamp": 1000, "pressure": 1010}`},
+				{payload: `{"timestamp": 1120, "pressure": 1012}`},
+				{payload: `{"timestamp": 1180, "pressure": 1020}`, rejected: []string{"pressure changed 8/min, more than 1/min"}},
+				// The rejected reading is not the new reference
+				{payload: `{"timestamp": 1240, "pressure": 1013}`},
+				// No rate without elapsed time
+				{payload: `{"timestamp": 1240, "pressure": 1050}`},
+			},
+		},
+		{
+			name: "flag rules do not reject",
+			rules: []ValidationRule{
+				{Metric: "humidity", Max: f(100), Action: RuleActionFlag},
+				{Metric: "temperature", MaxRatePerMinute: f(0.5), Action: RuleActionFlag},
+			},
+			steps: []step{
+				{payload: `{"timestamp": 1000, "temperature": 10, "humidity": 90}`},
+				{payload: `{"timestamp": 1060, "temperature": 12, "humidity": 101}`,
+					flagged: []string{"humidity 101 above 100", "temperature changed 2/min, more than 0.5/min"}},
+				// Flagged readings are the new reference
+				{payload: `{"timestamp": 1120, "temperature": 12.25, "humidity": 95}`},
+			},
+		},
+		{
+			name: "flag and reject together",
+			rules: []ValidationRule{
+				{Metric: "temperature", Max: f(50), Action: RuleActionReject},
+				{Metric: "wind_speed", Max: f(60), Action: RuleActionFlag},
+			},
+			steps: []step{
+				{payload: `{"timestamp": 1000, "temperature": 55, "wind_speed": 70}`,
+					rejected: []string{"temperature 55 above 50"}, flagged: []string{"wind_speed 70 above 60"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newRuleEngine(tt.rules)
+			for _, s := range tt.steps {
+				var w WeatherData
+				if err := json.Unmarshal([]byte(s.payload), &w); err != nil {
+					t.Fatal(err)
+				}
+				w.Raw = []byte(s.payload)
+				rejected, flagged := engine.Evaluate(&w)
+				if !reflect.DeepEqual(rejected, s.rejected) || !reflect.DeepEqual(flagged, s.flagged) {
+					t.Errorf("Evaluate(%s) = %q, %q, want %q, %q", s.payload, rejected, flagged, s.rejected, s.flagged)
+				}
+			}
+		})
+	}
+}
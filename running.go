@@ -0,0 +1,177 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// weather_running keeps per-day running sums so daily statistics can be read
+// without scanning the raw weather table. Every metric has its own count,
+// since a reading may lack some of them, and sums of squares are kept
+// alongside so the standard deviation can be derived, see runningStddev.
+
+// runningCount is the count increment of a metric of one reading
+func runningCount(v *float64) int {
+	if v == nil {
+		return 0
+	}
+	return 1
+}
+
+// runningSums are the sum and sum of squares increments of a metric of one
+// reading, zero when the metric is missing
+func runningSums(v *float64) (float64, float64) {
+	if v == nil {
+		return 0, 0
+	}
+	return *v, *v * *v
+}
+
+// updateRunningTotals adds a single reading to the running totals of its
+// day. Missing metrics leave their sums, count and extremes unchanged.
+func updateRunningTotals(db dbExecutor, measuredAt time.Time, temperature, pressure, humidity *float64) error {
+	upsert := `
+		INSERT INTO weather_running (
+			station_id, date, samples_count,
+			temperature_count, temperature_sum, temperature_sum_sq, temperature_min, temperature_max,
+			pressure_count, pressure_sum, pressure_sum_sq, pressure_min, pressure_max,
+			humidity_count, humidity_sum, humidity_sum_sq, humidity_min, humidity_max
+		)
+		VALUES (?, ?, 1, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			samples_count = samples_count + 1,
+			temperature_count = temperature_count + VALUES(temperature_count),
+			temperature_sum = temperature_sum + VALUES(temperature_sum),
+			temperature_sum_sq = temperature_sum_sq + VALUES(temperature_sum_sq),
+			temperature_min = COALESCE(LEAST(temperature_min, VALUES(temperature_min)), temperature_min, VALUES(temperature_min)),
+			temperature_max = COALESCE(GREATEST(temperature_max, VALUES(temperature_max)), temperature_max, VALUES(temperature_max)),
+			pressure_count = pressure_count + VALUES(pressure_count),
+			pressure_sum = pressure_sum + VALUES(pressure_sum),
+			pressure_sum_sq = pressure_sum_sq + VALUES(pressure_sum_sq),
+			pressure_min = COALESCE(LEAST(pressure_min, VALUES(pressure_min)), pressure_min, VALUES(pressure_min)),
+			pressure_max = COALESCE(GREATEST(pressure_max, VALUES(pressure_max)), pressure_max, VALUES(pressure_max)),
+			humidity_count = humidity_count + VALUES(humidity_count),
+			humidity_sum = humidity_sum + VALUES(humidity_sum),
+			humidity_sum_sq = humidity_sum_sq + VALUES(humidity_sum_sq),
+			humidity_min = COALESCE(LEAST(humidity_min, VALUES(humidity_min)), humidity_min, VALUES(humidity_min)),
+			humidity_max = COALESCE(GREATEST(humidity_max, VALUES(humidity_max)), humidity_max, VALUES(humidity_max)),
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	args := []any{stationOf(db), observationDate(measuredAt)}
+	for _, v := range []*float64{temperature, pressure, humidity} {
+		sum, sumSq := runningSums(v)
+		args = append(args, runningCount(v), sum, sumSq, v, v)
+	}
+	if _, err := db.Exec(upsert, args...); err != nil {
+		return fmt.Errorf("failed to update running totals: %w", err)
+	}
+	return nil
+}
+
+// rebuildRunningTotals recomputes weather_running of every station from the
+// raw weather table
+func rebuildRunningTotals(db Store) (int64, error) {
+	var total int64
+	err := forEachStation(db, func(db Store) error {
+		affected, err := rebuildStationRunningTotals(db)
+		total += affected
+		return err
+	})
+	return total, err
+}
+
+// rebuildStationRunningTotals recomputes weather_running of the station of db
+func rebuildStationRunningTotals(db dbExecutor) (int64, error) {
+	rebuild := `
+		INSERT INTO weather_running (
+			station_id, date, samples_count,
+			temperature_count, temperature_sum, temperature_sum_sq, temperature_min, temperature_max,
+			pressure_count, pressure_sum, pressure_sum_sq, pressure_min, pressure_max,
+			humidity_count, humidity_sum, humidity_sum_sq, humidity_min, humidity_max
+		)
+		SELECT
+			` + stationLiteral(db) + `, ` + observationDay() + `, COUNT(*),
+			COUNT(temperature), COALESCE(SUM(temperature), 0), COALESCE(SUM(temperature * temperature), 0),
+			MIN(temperature), MAX(temperature),
+			COUNT(pressure), COALESCE(SUM(pressure), 0), COALESCE(SUM(pressure * pressure), 0),
+			MIN(pressure), MAX(pressure),
+			COUNT(humidity), COALESCE(SUM(humidity), 0), COALESCE(SUM(humidity * humidity), 0),
+			MIN(humidity), MAX(humidity)
+		FROM weather
+		WHERE station_id = ` + stationLiteral(db) + exclusionFilter() + `
+		GROUP BY ` + observationDay() + `
+		ON DUPLICATE KEY UPDATE
+			samples_count = VALUES(samples_count),
+			temperature_count = VALUES(temperature_count),
+			temperature_sum = VALUES(temperature_sum),
+			temperature_sum_sq = VALUES(temperature_sum_sq),
+			temperature_min = VALUES(temperature_min),
+			temperature_max = VALUES(temperature_max),
+			pressure_count = VALUES(pressure_count),
+			pressure_sum = VALUES(pressure_sum),
+			pressure_sum_sq = VALUES(pressure_sum_sq),
+			pressure_min = VALUES(pressure_min),
+			pressure_max = VALUES(pressure_max),
+			humidity_count = VALUES(humidity_count),
+			humidity_sum = VALUES(humidity_sum),
+			humidity_sum_sq = VALUES(humidity_sum_sq),
+			humidity_min = VALUES(humidity_min),
+			humidity_max = VALUES(humidity_max),
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	result, err := db.Exec(rebuild)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild running totals: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
+
+// runningStddev derives the population standard deviation of n values from
+// their sum and sum of squares, NULL without values. Rounding can make the
+// variance slightly negative for constant values, so it is clamped at 0.
+func runningStddev(sum, sumSq float64, n int64) sql.NullFloat64 {
+	if n == 0 {
+		return sql.NullFloat64{}
+	}
+	mean := sum / float64(n)
+	return sql.NullFloat64{Float64: math.Sqrt(math.Max(sumSq/float64(n)-mean*mean, 0)), Valid: true}
+}
+
+// updateDailyStddev stores the standard deviation of temperature, pressure
+// and humidity of a date derived from weather_running
+func updateDailyStddev(db dbExecutor, date string) error {
+	var stddev [3]sql.NullFloat64
+	var counts [3]int64
+	var sums, sumsSq [3]float64
+	err := db.QueryRow(`
+		SELECT
+			temperature_count, temperature_sum, temperature_sum_sq,
+			pressure_count, pressure_sum, pressure_sum_sq,
+			humidity_count, humidity_sum, humidity_sum_sq
+		FROM weather_running
+		WHERE date = ?`+stationFilter(db), date).Scan(
+		&counts[0], &sums[0], &sumsSq[0],
+		&counts[1], &sums[1], &sumsSq[1],
+		&counts[2], &sums[2], &sumsSq[2])
+	if err != nil {
+		return fmt.Errorf("failed to read running totals: %w", err)
+	}
+	for i := range stddev {
+		stddev[i] = runningStddev(sums[i], sumsSq[i], counts[i])
+	}
+	roundNullAggregates(&stddev[0], &stddev[1], &stddev[2])
+
+	_, err = db.Exec(`
+		UPDATE weather_daily
+		SET stddev_temperature = ?, stddev_pressure = ?, stddev_humidity = ?
+		WHERE date = ?`+stationFilter(db), stddev[0], stddev[1], stddev[2], date)
+	if err != nil {
+		return fmt.Errorf("failed to store daily standard deviation: %w", err)
+	}
+	return nil
+}
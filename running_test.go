@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunningStddev(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+		valid  bool
+	}{
+		{"no values", nil, 0, false},
+		{"single value", []float64{21.5}, 0, true},
+		{"constant values", []float64{1013.2, 1013.2, 1013.2}, 0, true},
+		{"spread", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 2, true},
+		{"negative", []float64{-10, -20}, 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sum, sumSq float64
+			for _, v := range tt.values {
+				sum += v
+				sumSq += v * v
+			}
+			got := runningStddev(sum, sumSq, int64(len(tt.values)))
+			if got.Valid != tt.valid || math.Abs(got.Float64-tt.want) > 1e-9 {
+				t.Errorf("runningStddev() = %v, want %v (valid %v)", got, tt.want, tt.valid)
+			}
+		})
+	}
+}
+
+// missing marks a metric left out of a test reading
+const missing = -127
+
+func TestRunningTotalsMatchScan(t *testing.T) {
+	type reading struct{ temperature, pressure, humidity float64 }
+	tests := []struct {
+		name     string
+		readings []reading
+	}{
+		{"complete readings", []reading{{12.5, 1012.1, 80}, {18.25, 1010.4, 61}, {15.75, 1009.8, 70}}},
+		{"missing temperature", []reading{{14, 1011, 75}, {missing, 1012, 72}, {16, 1013, missing}}},
+		{"metric missing all day", []reading{{missing, 1011, 75}, {missing, 1012, 72}}},
+		{"single reading", []reading{{-3.5, 1025.6, 93}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestConfig(t, map[string]string{
+				"USE_RUNNING_TOTALS": "true",
+				"ROUND_DECIMALS":     "3",
+				"SENSOR_SENTINELS":   fmt.Sprintf("temperature=%d,humidity=%d", missing, missing),
+			})
+			db := newTestStore(t)
+
+			day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+			for i, r := range tt.readings {
+				at := day.Add(time.Duration(8+i) * time.Hour)
+				processFixture(t, db, []byte(fmt.Sprintf(
+					`{"timestamp": %d, "temperature": %g, "pressure": %g, "humidity": %g}`,
+					at.Unix(), r.temperature, r.pressure, r.humidity)))
+			}
+
+			const daily = `SELECT avg_temperature, min_temperature, max_temperature,
+				avg_pressure, min_pressure, max_pressure,
+				avg_humidity, min_humidity, max_humidity, samples_count
+				FROM weather_daily`
+			dailyFrom := func(running bool) []map[string]string {
+				t.Helper()
+				config.UseRunningTotals = running
+				if err := updateDailyStatisticsFor(db, day); err != nil {
+					t.Fatalf("updateDailyStatisticsFor: %v", err)
+				}
+				return queryRows(t, db, daily)
+			}
+			scan := dailyFrom(false)
+			if running := dailyFrom(true); !reflect.DeepEqual(running, scan) {
+				t.Errorf("daily from running totals = %v, scan = %v", running, scan)
+			}
+
+			stddev := queryRows(t, db, `SELECT stddev_temperature, stddev_pressure, stddev_humidity FROM weather_daily`)[0]
+			for i, metric := range []string{"temperature", "pressure", "humidity"} {
+				var values []float64
+				for _, r := range tt.readings {
+					if v := [3]float64{r.temperature, r.pressure, r.humidity}[i]; v != missing {
+						values = append(values, v)
+					}
+				}
+				if got, want := stddev["stddev_"+metric], populationStddev(values); got != want {
+					t.Errorf("stddev_%s = %q, want %q", metric, got, want)
+				}
+			}
+
+			const running = `SELECT * FROM weather_running`
+			incremental := queryRows(t, db, running)
+			if _, err := db.Exec(`DELETE FROM weather_running`); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := rebuildRunningTotals(db); err != nil {
+				t.Fatalf("rebuildRunningTotals: %v", err)
+			}
+			if rebuilt := queryRows(t, db, running); !reflect.DeepEqual(rebuilt, incremental) {
+				t.Errorf("rebuilt running totals = %v, incremental = %v", rebuilt, incremental)
+			}
+		})
+	}
+}
+
+// populationStddev is the standard deviation of values computed the
+// two-pass way, formatted as queryRows does, "" without values
+func populationStddev(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return fmt.Sprint(roundAggregate(math.Sqrt(variance / float64(len(values)))))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Attempts and initial backoff of an S3 download before the run gives up
+const (
+	s3FetchAttempts = 3
+	s3FetchBackoff  = time.Second
+	s3FetchTimeout  = 30 * time.Second
+)
+
+// S3Reader downloads the weather JSON from an S3 (or S3-compatible) bucket.
+// Credentials come from the standard AWS chain (environment, shared config,
+// instance/task role).
+type S3Reader struct {
+	Client *s3.Client
+	Bucket string
+	Key    string
+}
+
+// isS3Path reports whether JSON_FILE_PATH points to object storage
+func isS3Path(path string) bool {
+	return strings.HasPrefix(path, "s3://")
+}
+
+// newS3Reader creates a reader for an s3://bucket/key path
+func newS3Reader(path, region string) (*S3Reader, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 path %q: %w", path, err)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return nil, fmt.Errorf("invalid S3 path %q, expected s3://bucket/key", path)
+	}
+
+	var opts []func(*awsconfig.LoadOptions) error
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	return &S3Reader{Client: s3.NewFromConfig(cfg), Bucket: u.Host, Key: key}, nil
+}
+
+// Read downloads the object, retrying transient failures with a doubling
+// backoff. A missing object is reported as fs.ErrNotExist, like a missing
+// local file.
+func (r *S3Reader) Read() ([]byte, error) {
+	backoff := s3FetchBackoff
+	var err error
+	for attempt := 1; attempt <= s3FetchAttempts; attempt++ {
+		var data []byte
+		data, err = r.fetch()
+		if err == nil || errors.Is(err, fs.ErrNotExist) {
+			return data, err
+		}
+		if attempt < s3FetchAttempts {
+			slog.Warn("S3 download failed, retrying", "component", "source", "attempt", attempt, "attempts", s3FetchAttempts, "error", err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return nil, err
+}
+
+func (r *S3Reader) fetch() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3FetchTimeout)
+	defer cancel()
+
+	out, err := r.Client.GetObject(ctx, &s3.GetObjectInput{Bucket: &r.Bucket, Key: &r.Key})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, fmt.Errorf("s3://%s/%s: %w", r.Bucket, r.Key, fs.ErrNotExist)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download s3://%s/%s: %w", r.Bucket, r.Key, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read s3://%s/%s: %w", r.Bucket, r.Key, err)
+	}
+	return data, nil
+}
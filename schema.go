@@ -0,0 +1,306 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// Supported values of SCHEMA_CHECK_MODE
+const (
+	SchemaCheckFail = "fail"
+	SchemaCheckWarn = "warn"
+)
+
+// Column kinds compared against INFORMATION_SCHEMA.COLUMNS.DATA_TYPE
+const (
+	kindNumeric  = "numeric"
+	kindDate     = "date"
+	kindDateTime = "datetime"
+	kindString   = "string"
+	kindJSON     = "json"
+)
+
+// compatibleTypes lists the MySQL and PostgreSQL data types accepted for
+// each column kind
+var compatibleTypes = map[string][]string{
+	kindNumeric: {"tinyint", "smallint", "mediumint", "int", "bigint", "decimal", "float", "double",
+		"integer", "numeric", "real", "double precision"},
+	kindDate:     {"date"},
+	kindDateTime: {"datetime", "timestamp", "timestamp without time zone", "timestamp with time zone"},
+	kindString:   {"char", "varchar", "tinytext", "text", "mediumtext", "longtext", "character", "character varying"},
+	kindJSON:     {"json", "text", "mediumtext", "longtext", "jsonb"},
+}
+
+// expectedSchema lists the columns the processor writes, per table, for the
+// current configuration. It doubles as the reference of the required schema.
+func expectedSchema() map[string]map[string]string {
+	schema := make(map[string]map[string]string)
+	add := func(table, kind string, columns ...string) {
+		if schema[table] == nil {
+			schema[table] = make(map[string]string)
+		}
+		for _, c := range columns {
+			schema[table][c] = kind
+		}
+	}
+	aggregates := []string{
+		"avg_temperature", "min_temperature", "max_temperature",
+		"avg_pressure", "min_pressure", "max_pressure",
+		"avg_humidity", "min_humidity", "max_humidity",
+		"samples_count",
+	}
+
+	add("weather", kindDateTime, "measured_at")
+	add("weather", kindNumeric, "id", "temperature", "pressure", "humidity")
+	add("weather", kindString, "station_id")
+	add("weather_hourly", kindDate, "date")
+	add("weather_hourly", kindNumeric, "hour", "avg_temperature", "avg_pressure", "avg_humidity", "samples_count")
+	add("weather_daily", kindDate, "date")
+	add("weather_daily", kindNumeric, aggregates...)
+	add("weather_weekly", kindDate, "week_start", "week_end")
+	add("weather_weekly", kindNumeric, append([]string{"year", "week"}, aggregates...)...)
+	add("weather_monthly", kindNumeric, append([]string{"year", "month"}, aggregates...)...)
+
+	if config.SaveStationMetadata {
+		add("weather_station", kindNumeric, "id", "latitude", "longitude", "altitude")
+		add("weather_station", kindString, "name", "sensor_model")
+		add("weather_station", kindDate, "installed")
+	}
+	if config.PersistProcessorState {
+		add("processor_state", kindString, "station_id")
+		add("processor_state", kindNumeric, "last_timestamp")
+	}
+	if config.SplitDSTHours {
+		add("weather_hourly", kindNumeric, "utc_offset")
+		add("weather", kindNumeric, "measured_epoch")
+	}
+	if config.InputPressureType == PressureTypeSeaLevel {
+		add("weather", kindNumeric, "pressure_station")
+	}
+	if config.EnableRefPressure {
+		add("weather", kindNumeric, "pressure_ref")
+	}
+	if config.EnablePressureChangePctile {
+		add("weather", kindNumeric, "pressure_change_3h", "pressure_change_pctile")
+		add("weather_pressure_change_hist", kindNumeric, "bucket", "samples")
+	}
+	if config.EnablePressureTendency {
+		add("weather", kindNumeric, "pressure_tendency_code", "pressure_tendency_amount")
+	}
+	if config.StoreProcessingLatency {
+		add("weather", kindNumeric, "processing_latency_ms")
+		add("weather_daily", kindNumeric, "p95_processing_latency_ms")
+	}
+	if config.LongFormat {
+		add("weather_long", kindDateTime, "measured_at")
+		add("weather_long", kindString, "station_id", "metric")
+		add("weather_long", kindNumeric, "value")
+	}
+	if config.TSDBEnabled {
+		add("weather_metrics", kindString, "metric_name")
+		add("weather_metrics", kindNumeric, "value")
+		add("weather_metrics", kindDateTime, "measured_at")
+	}
+	if config.EnableArchive {
+		add("weather_archive", kindNumeric, "weather_id", "temperature", "pressure", "humidity", "solar_radiation")
+		add("weather_archive", kindDateTime, "measured_at")
+		add("weather_archive", kindJSON, "payload")
+	}
+	if config.UseRunningTotals {
+		add("weather_running", kindDate, "date")
+		add("weather_running", kindNumeric, "samples_count",
+			"temperature_count", "temperature_sum", "temperature_sum_sq", "temperature_min", "temperature_max",
+			"pressure_count", "pressure_sum", "pressure_sum_sq", "pressure_min", "pressure_max",
+			"humidity_count", "humidity_sum", "humidity_sum_sq", "humidity_min", "humidity_max")
+		add("weather_daily", kindNumeric, "stddev_temperature", "stddev_pressure", "stddev_humidity")
+	}
+	if config.EnableMovingAverages {
+		add("weather_moving", kindString, "window_name")
+		add("weather_moving", kindDateTime, "window_start", "window_end")
+		add("weather_moving", kindNumeric, "avg_temperature", "avg_pressure", "avg_humidity", "samples_count")
+	}
+	if config.EnableExclusions {
+		add("weather_exclusions", kindDateTime, "start_at", "end_at")
+		add("weather_exclusions", kindString, "reason")
+	}
+	if config.EnablePeriodTotals {
+		add("weather_weekly", kindNumeric, "total_gdd", "total_hdd", "total_cdd")
+		add("weather_monthly", kindNumeric, "total_gdd", "total_hdd", "total_cdd")
+	}
+	if config.EnableGrowingSeason {
+		add("weather_season", kindNumeric, "year", "warm_streak", "cold_streak")
+		add("weather_season", kindDate, "season_start", "season_end", "warm_streak_start", "cold_streak_start", "last_date")
+	}
+	if config.EnableFrostFreePeriod {
+		add("weather_yearly", kindNumeric, "year", "frost_free_days")
+		add("weather_yearly", kindDate, "last_spring_frost", "first_autumn_frost")
+	}
+	if config.EnableDriftReport {
+		add("weather_drift", kindNumeric, "year", "month", "normal", "anomaly", "normal_years", "trend_per_month")
+		add("weather_drift", kindString, "metric")
+	}
+	if config.EnableDewPoint {
+		add("weather", kindNumeric, "dew_point")
+	}
+	if config.EnableMixingRatio {
+		add("weather", kindNumeric, "mixing_ratio")
+		add("weather_daily", kindNumeric, "avg_mixing_ratio")
+	}
+	if config.EnableFeelsLike {
+		add("weather", kindNumeric, "feels_like")
+		add("weather_daily", kindNumeric, "avg_feels_like")
+	}
+	if config.EnableInversion {
+		add("weather", kindNumeric, "temperature_elevated", "inversion", "inversion_strength")
+	}
+	if config.EnableWind {
+		add("weather", kindNumeric, "wind_speed", "wind_direction")
+		add("weather_hourly", kindNumeric, "avg_wind_speed", "avg_wind_direction")
+	}
+	if config.EnableRainRate {
+		add("weather", kindNumeric, "rain_rate")
+		add("weather_daily", kindNumeric, "max_rain_rate")
+	}
+	if config.EnableRainfall {
+		add("weather", kindNumeric, "rain_mm")
+		add("weather_daily", kindNumeric, "total_rain")
+		if config.EnableRainStreaks {
+			add("weather_streaks", kindString, "kind")
+			add("weather_streaks", kindNumeric, "current_length", "longest_length")
+			add("weather_streaks", kindDate, "current_start", "longest_start", "longest_end", "last_date")
+		}
+		if config.EnableRainDays {
+			add("weather_monthly", kindNumeric, "rain_days", "wettest_day_rain")
+			add("weather_monthly", kindDate, "wettest_day")
+		}
+	}
+	if config.EnableAirQuality {
+		add("weather", kindNumeric, "co2", "pm25", "pm10")
+		add("weather_daily", kindNumeric, "avg_co2", "max_co2", "avg_pm25", "max_pm25", "avg_pm10", "max_pm10", "max_aqi")
+	}
+	if config.EnableComfortBands {
+		add("weather_daily", kindNumeric, "comfort_cold_hours", "comfort_cool_hours",
+			"comfort_comfortable_hours", "comfort_warm_hours", "comfort_hot_hours")
+	}
+	if config.EnableTempBands {
+		add("weather_daily", kindJSON, "temp_band_hours")
+	}
+	if config.EnableGriddedAverage {
+		add("weather_daily", kindNumeric, "avg_temperature_gridded")
+	}
+	if config.EnableSunshineHours {
+		add("weather", kindNumeric, "solar_radiation")
+		add("weather_daily", kindNumeric, "sunshine_hours")
+	}
+	for _, c := range derivedColumns {
+		add("weather", kindNumeric, c.Name)
+	}
+	for _, m := range extraMetrics {
+		add("weather", kindNumeric, m)
+		add("weather_hourly", kindNumeric, "avg_"+m)
+		for _, table := range []string{"weather_daily", "weather_weekly", "weather_monthly"} {
+			add(table, kindNumeric, "avg_"+m, "min_"+m, "max_"+m)
+		}
+	}
+	if config.EnableWeatherType {
+		add("weather_daily", kindString, "weather_type")
+	}
+	if config.EnableChangeIndex {
+		add("weather_daily", kindNumeric, "change_index")
+	}
+	if config.EnableMoonPhase {
+		add("weather_daily", kindNumeric, "moon_phase")
+		add("weather_daily", kindString, "moon_phase_name")
+	}
+
+	// The aggregates are kept per STATION_ID
+	for _, table := range stationTables {
+		if schema[table] != nil {
+			add(table, kindString, "station_id")
+		}
+	}
+	return schema
+}
+
+// checkSchema compares the expected columns with INFORMATION_SCHEMA and
+// returns a description of every missing or incompatible column
+func checkSchema(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT TABLE_NAME, COLUMN_NAME, DATA_TYPE
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query INFORMATION_SCHEMA: %w", err)
+	}
+	defer rows.Close()
+
+	actual := make(map[string]map[string]string)
+	for rows.Next() {
+		var table, column, dataType string
+		if err := rows.Scan(&table, &column, &dataType); err != nil {
+			return nil, fmt.Errorf("failed to scan column info: %w", err)
+		}
+		if actual[table] == nil {
+			actual[table] = make(map[string]string)
+		}
+		actual[table][column] = strings.ToLower(dataType)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate column info: %w", err)
+	}
+
+	var problems []string
+	for table, columns := range expectedSchema() {
+		if actual[table] == nil {
+			problems = append(problems, fmt.Sprintf("table %s is missing", table))
+			continue
+		}
+		for column, kind := range columns {
+			dataType, ok := actual[table][column]
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("column %s.%s is missing", table, column))
+			case !isCompatibleType(kind, dataType):
+				problems = append(problems, fmt.Sprintf("column %s.%s has type %s, expected %s", table, column, dataType, kind))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
+
+func isCompatibleType(kind, dataType string) bool {
+	for _, t := range compatibleTypes[kind] {
+		if t == dataType {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySchema runs the startup schema check and exits or warns according
+// to SCHEMA_CHECK_MODE. A failing check query is only logged.
+func verifySchema(db *sql.DB) {
+	problems, err := checkSchema(db)
+	if err != nil {
+		slog.Warn("Schema check skipped", "component", "db", "error", err)
+		return
+	}
+	if len(problems) == 0 {
+		slog.Info("Database schema check passed", "component", "db")
+		return
+	}
+
+	for _, p := range problems {
+		slog.Warn("Schema problem", "component", "db", "problem", p)
+	}
+	if config.SchemaCheckMode == SchemaCheckFail {
+		log.Fatalf("Database schema does not match the configuration (%d problems), see README or set SKIP_SCHEMA_CHECK=true", len(problems))
+	}
+	slog.Warn("Database schema does not match the configuration", "component", "db", "problems", len(problems))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SeasonState is the growing season progress for one year. The streak
+// counters are persisted so detection continues across restarts.
+type SeasonState struct {
+	Start           sql.NullTime
+	End             sql.NullTime
+	WarmStreak      int
+	WarmStreakStart sql.NullTime
+	ColdStreak      int
+	ColdStreakStart sql.NullTime
+	LastDate        sql.NullTime
+}
+
+// advanceSeason applies one day's average temperature to the state. The
+// season starts on the first day of the first run of streakDays consecutive
+// days above base, and ends on the first day of the first following run of
+// streakDays consecutive days below base. Days equal to base break both runs.
+func advanceSeason(state SeasonState, date time.Time, avgTemp, base float64, streakDays int) SeasonState {
+	if state.LastDate.Valid && !date.After(state.LastDate.Time) {
+		return state
+	}
+	state.LastDate = sql.NullTime{Time: date, Valid: true}
+
+	switch {
+	case avgTemp > base:
+		if state.WarmStreak == 0 {
+			state.WarmStreakStart = sql.NullTime{Time: date, Valid: true}
+		}
+		state.WarmStreak++
+		state.ColdStreak = 0
+		state.ColdStreakStart = sql.NullTime{}
+	case avgTemp < base:
+		if state.ColdStreak == 0 {
+			state.ColdStreakStart = sql.NullTime{Time: date, Valid: true}
+		}
+		state.ColdStreak++
+		state.WarmStreak = 0
+		state.WarmStreakStart = sql.NullTime{}
+	default:
+		state.WarmStreak = 0
+		state.WarmStreakStart = sql.NullTime{}
+		state.ColdStreak = 0
+		state.ColdStreakStart = sql.NullTime{}
+	}
+
+	if !state.Start.Valid && state.WarmStreak >= streakDays {
+		state.Start = state.WarmStreakStart
+	}
+	if state.Start.Valid && !state.End.Valid && state.ColdStreak >= streakDays {
+		state.End = state.ColdStreakStart
+	}
+	return state
+}
+
+// loadSeasonState reads the stored season state for a year
+func loadSeasonState(db dbExecutor, year int) (SeasonState, error) {
+	var state SeasonState
+
+	query := `
+		SELECT season_start, season_end,
+			warm_streak, warm_streak_start,
+			cold_streak, cold_streak_start,
+			last_date
+		FROM weather_season
+		WHERE year = ?` + stationFilter(db) + `
+	`
+
+	err := db.QueryRow(query, year).Scan(
+		&state.Start, &state.End,
+		&state.WarmStreak, &state.WarmStreakStart,
+		&state.ColdStreak, &state.ColdStreakStart,
+		&state.LastDate)
+	if err == sql.ErrNoRows {
+		return SeasonState{}, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to load season state: %w", err)
+	}
+	return state, nil
+}
+
+// saveSeasonState stores the season state for a year
+func saveSeasonState(db dbExecutor, year int, state SeasonState) error {
+	upsert := `
+		INSERT INTO weather_season (
+			station_id, year, season_start, season_end,
+			warm_streak, warm_streak_start,
+			cold_streak, cold_streak_start,
+			last_date
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			season_start = VALUES(season_start),
+			season_end = VALUES(season_end),
+			warm_streak = VALUES(warm_streak),
+			warm_streak_start = VALUES(warm_streak_start),
+			cold_streak = VALUES(cold_streak),
+			cold_streak_start = VALUES(cold_streak_start),
+			last_date = VALUES(last_date),
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := db.Exec(upsert, stationOf(db), year, state.Start, state.End,
+		state.WarmStreak, state.WarmStreakStart,
+		state.ColdStreak, state.ColdStreakStart,
+		state.LastDate)
+	if err != nil {
+		return fmt.Errorf("failed to save season state: %w", err)
+	}
+	return nil
+}
+
+// updateGrowingSeason feeds one day's average temperature into the growing
+// season detection for that day's year
+func updateGrowingSeason(db dbExecutor, day time.Time, avgTemp float64) error {
+	// Compare calendar dates the way they come back from DATE columns
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	year := day.Year()
+
+	state, err := loadSeasonState(db, year)
+	if err != nil {
+		return err
+	}
+
+	next := advanceSeason(state, day, avgTemp, config.SeasonBaseTemp, config.SeasonStreakDays)
+	if !state.Start.Valid && next.Start.Valid {
+		slog.Info("Growing season started", "component", "stats", "year", year, "date", next.Start.Time.Format("2006-01-02"))
+	}
+	if !state.End.Valid && next.End.Valid {
+		slog.Info("Growing season ended", "component", "stats", "year", year, "date", next.End.Time.Format("2006-01-02"))
+	}
+
+	return saveSeasonState(db, year, next)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdvanceSeason(t *testing.T) {
+	const base, streakDays = 5.0, 3
+
+	tests := []struct {
+		name      string
+		temps     []float64
+		wantStart int // index of the start day, -1 for none
+		wantEnd   int
+	}{
+		{"no season", []float64{1, 2, 3, 4, 3, 2, 1}, -1, -1},
+		{"streak starts season", []float64{2, 6, 7, 8, 9}, 1, -1},
+		{"streak too short", []float64{6, 7, 2, 3}, -1, -1},
+		{"streak broken by one cold day", []float64{6, 7, 4, 6, 7, 8}, 3, -1},
+		{"day equal to base breaks streak", []float64{6, 7, 5, 6, 7, 8}, 3, -1},
+		{"season ends on cold streak", []float64{6, 7, 8, 9, 4, 3, 2, 1}, 0, 4},
+		{"cold day does not end season", []float64{6, 7, 8, 4, 3, 6, 4, 3, 2}, 0, 6},
+	}
+	first := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var state SeasonState
+			for i, temp := range tt.temps {
+				state = advanceSeason(state, first.AddDate(0, 0, i), temp, base, streakDays)
+			}
+
+			checkSeasonDay(t, "start", state.Start.Valid, state.Start.Time, first, tt.wantStart)
+			checkSeasonDay(t, "end", state.End.Valid, state.End.Time, first, tt.wantEnd)
+		})
+	}
+}
+
+func TestAdvanceSeasonIgnoresReplayedDay(t *testing.T) {
+	day := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	state := advanceSeason(SeasonState{}, day, 6, 5, 3)
+	if again := advanceSeason(state, day, 6, 5, 3); again.WarmStreak != 1 {
+		t.Errorf("warm streak = %d after replaying the day, want 1", again.WarmStreak)
+	}
+}
+
+// checkSeasonDay compares a season boundary with the day at index want
+func checkSeasonDay(t *testing.T, name string, valid bool, got, first time.Time, want int) {
+	t.Helper()
+	if want < 0 {
+		if valid {
+			t.Errorf("%s = %s, want none", name, got.Format("2006-01-02"))
+		}
+		return
+	}
+	if wantDay := first.AddDate(0, 0, want); !valid || !got.Equal(wantDay) {
+		t.Errorf("%s = %s (valid %v), want %s", name, got.Format("2006-01-02"), valid, wantDay.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// sensorSentinels holds the parsed SENSOR_SENTINELS declaration: the raw
+// values a sensor reports instead of a measurement, per reading field
+var sensorSentinels map[string][]float64
+
+// optionalFieldRefs give access to the pointer fields of a reading; a
+// sentinel in one of them is cleared by setting it to nil
+var optionalFieldRefs = map[string]func(w *WeatherData) **float64{
+	"temperature_elevated": func(w *WeatherData) **float64 { return &w.TemperatureElevated },
+	"solar_radiation":      func(w *WeatherData) **float64 { return &w.SolarRadiation },
+	"rain_mm":              func(w *WeatherData) **float64 { return &w.RainMM },
+	"rain_rate":            func(w *WeatherData) **float64 { return &w.RainRate },
+	"co2":                  func(w *WeatherData) **float64 { return &w.CO2 },
+	"pm25":                 func(w *WeatherData) **float64 { return &w.PM25 },
+	"pm10":                 func(w *WeatherData) **float64 { return &w.PM10 },
+}
+
+// parseSensorSentinels parses a list of metric=value|value entries separated
+// by commas, e.g. temperature=-127|85,humidity=-1. Metrics are the fields of
+// readingFields, so EXTRA_METRICS must be parsed first.
+func parseSensorSentinels(value string) (map[string][]float64, error) {
+	known := readingFields(WeatherData{})
+	result := make(map[string][]float64)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		metric, list, ok := strings.Cut(entry, "=")
+		metric = strings.TrimSpace(metric)
+		if !ok || metric == "" {
+			return nil, fmt.Errorf("expected metric=value|value, got %q", entry)
+		}
+		if _, exists := known[metric]; !exists {
+			return nil, fmt.Errorf("unknown metric %q", metric)
+		}
+		for _, s := range strings.Split(list, "|") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil, fmt.Errorf("metric %s: invalid sentinel %q", metric, s)
+			}
+			result[metric] = append(result[metric], v)
+		}
+	}
+	return result, nil
+}
+
+// applySensorSentinels marks every field holding one of its sentinels as
+// missing, leaving the rest of the reading intact. It runs on the raw values,
+// before HUMIDITY_SCALE and SOURCE_UNITS conversions.
+func applySensorSentinels(w *WeatherData) {
+	fields := readingFields(*w)
+	for metric, sentinels := range sensorSentinels {
+		v := fields[metric]
+		if v == nil || !containsFloat(sentinels, *v) {
+			continue
+		}
+		slog.Warn("Sensor error sentinel detected", "component", "processor",
+			"measured_at", measurementTime(w.Timestamp), "metric", metric, "value", *v)
+
+		switch ref, optional := optionalFieldRefs[metric]; {
+		case optional:
+			*ref(w) = nil
+		case w.Extra != nil && w.Extra[metric] != nil:
+			w.Extra[metric] = nil
+		default:
+			if w.Missing == nil {
+				w.Missing = make(map[string]bool)
+			}
+			w.Missing[metric] = true
+		}
+	}
+}
+
+// containsFloat reports whether values contains v
+func containsFloat(values []float64, v float64) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// presentValue returns v, or nil when the field metric of the reading is
+// marked missing
+func presentValue(w WeatherData, metric string, v float64) *float64 {
+	if w.Missing[metric] {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// shutdownTimeout bounds finishing the HTTP requests in flight and flushing
+// the Kafka, Telegram and tracing exporters on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// shutdownTracing flushes and stops the tracer provider, nil without tracing
+var shutdownTracing func(context.Context) error
+
+// notifyShutdown returns a channel receiving SIGINT and SIGTERM. Once it is
+// registered the signals no longer kill the process.
+func notifyShutdown() <-chan os.Signal {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	return signals
+}
+
+// shutdown stops the HTTP API and scheduling new jobs, waits for a running
+// job (e.g. the monthly statistics) to finish so no upsert is left
+// half-written, writes the readings buffered during a maintenance window and
+// flushes the exporters. The caller closes the database pool afterwards.
+func shutdown(c *cron.Cron, pool *ReconnectingDB) {
+	if httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := httpServer.Shutdown(ctx); err != nil {
+			slog.Warn("Failed to stop HTTP server", "component", "api", "error", err)
+		}
+		cancel()
+	}
+
+	if c != nil {
+		slog.Info("Waiting for running jobs to finish", "component", "main")
+		<-c.Stop().Done()
+	}
+
+	// Buffered readings and trailing hourly recomputes still need the
+	// database
+	flushBufferedOnShutdown(pool.DB())
+	if hourlyDebouncer != nil {
+		hourlyDebouncer.Flush()
+	}
+
+	if kafkaPublisher != nil {
+		if err := kafkaPublisher.Close(); err != nil {
+			slog.Warn("Failed to close Kafka publisher", "component", "kafka", "error", err)
+		}
+	}
+
+	if telegramNotifier != nil {
+		if err := telegramNotifier.Close(shutdownTimeout); err != nil {
+			slog.Warn("Failed to flush Telegram alerts", "component", "alert", "error", err)
+		}
+	}
+
+	if shutdownTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("Failed to flush traces", "component", "main", "error", err)
+		}
+	}
+
+	if config.ShutdownSummary {
+		processor.logSummary()
+	}
+
+	slog.Info("Shutdown complete", "component", "main")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Supported values of READ_STRATEGY
+const (
+	ReadStrategySingle = "single"
+	ReadStrategyReread = "reread"
+	ReadStrategyMarker = "marker"
+)
+
+// errIncompleteRead means the source was caught mid-write; the reading is
+// skipped and picked up on the next run
+var errIncompleteRead = errors.New("weather data is being written")
+
+// Reader loads the raw weather JSON from its source
+type Reader interface {
+	Read() ([]byte, error)
+}
+
+// FileReader reads the weather JSON from a local file. To avoid parsing a
+// file the sensor is still writing, the reread strategy reads it twice and
+// only accepts identical contents, and the marker strategy requires a
+// "<path>.done" file at least as new as the data file.
+type FileReader struct {
+	Path        string
+	Strategy    string
+	VerifyDelay time.Duration
+}
+
+func (r FileReader) Read() ([]byte, error) {
+	switch r.Strategy {
+	case ReadStrategyReread:
+		return r.readTwice()
+	case ReadStrategyMarker:
+		return r.readWithMarker()
+	default:
+		return os.ReadFile(r.Path)
+	}
+}
+
+func (r FileReader) readTwice() ([]byte, error) {
+	first, err := os.ReadFile(r.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	time.Sleep(r.VerifyDelay)
+
+	second, err := os.ReadFile(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(first, second) {
+		return nil, fmt.Errorf("%w: contents changed between reads", errIncompleteRead)
+	}
+	return second, nil
+}
+
+func (r FileReader) readWithMarker() ([]byte, error) {
+	dataInfo, err := os.Stat(r.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	markerInfo, err := os.Stat(r.Path + ".done")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: marker %s.done not found", errIncompleteRead, r.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if markerInfo.ModTime().Before(dataInfo.ModTime()) {
+		return nil, fmt.Errorf("%w: marker is older than the data file", errIncompleteRead)
+	}
+
+	return os.ReadFile(r.Path)
+}
+
+// source is the Reader used by processWeatherData
+var source Reader
+
+// now returns the current time; statistics periods and the maintenance
+// window are derived from it so it can be replaced with a fixed clock
+var now = time.Now
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	_ "modernc.org/sqlite"
+)
+
+// The SQLite mirror is a local store-and-forward copy of every reading for
+// edge hardware: readings are written there before MySQL and flagged as
+// synced once MySQL has them too. Readings that never reached MySQL are
+// pushed later with -sync-sqlite.
+
+// mirroredReading is a reading as kept in the mirror, including the fields
+// WeatherData does not serialize
+type mirroredReading struct {
+	Data    WeatherData         `json:"data"`
+	Extra   map[string]*float64 `json:"extra,omitempty"`
+	Raw     string              `json:"raw,omitempty"`
+	Missing map[string]bool     `json:"missing,omitempty"`
+}
+
+// SQLiteMirror is the local SQLite copy of the readings
+type SQLiteMirror struct {
+	db *sql.DB
+}
+
+// mirror is the SQLite mirror, nil unless SQLITE_MIRROR_PATH is set
+var mirror *SQLiteMirror
+
+// openSQLiteMirror opens (and creates if needed) the mirror database
+func openSQLiteMirror(path string) (*SQLiteMirror, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite mirror: %w", err)
+	}
+	// SQLite allows a single writer
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS readings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp INTEGER NOT NULL,
+			reading TEXT NOT NULL,
+			synced INTEGER NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create SQLite mirror table: %w", err)
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS readings_unsynced ON readings (synced, timestamp)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create SQLite mirror index: %w", err)
+	}
+	return &SQLiteMirror{db: db}, nil
+}
+
+// Save stores a reading as not yet synced and returns its mirror id
+func (m *SQLiteMirror) Save(data WeatherData) (int64, error) {
+	encoded, err := json.Marshal(mirroredReading{Data: data, Extra: data.Extra, Raw: string(data.Raw), Missing: data.Missing})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode reading for SQLite mirror: %w", err)
+	}
+	if dryRunSkip("mirror", "measured_at", measurementTime(data.Timestamp), "reading", string(encoded)) {
+		return 0, nil
+	}
+
+	result, err := m.db.Exec(`INSERT INTO readings (timestamp, reading) VALUES (?, ?)`, data.Timestamp, string(encoded))
+	if err != nil {
+		return 0, fmt.Errorf("failed to write reading to SQLite mirror: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// MarkSynced flags a mirrored reading as stored in MySQL
+func (m *SQLiteMirror) MarkSynced(id int64) error {
+	if dryRunSkip("mirror", "synced_id", id) {
+		return nil
+	}
+	if _, err := m.db.Exec(`UPDATE readings SET synced = 1 WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to mark mirrored reading %d as synced: %w", id, err)
+	}
+	return nil
+}
+
+// Sync pushes the unsynced readings to MySQL in timestamp order and returns
+// how many were stored. It stops at the first failure so the remaining rows
+// are retried on the next sync.
+func (m *SQLiteMirror) Sync(db *sql.DB) (int, error) {
+	rows, err := m.db.Query(`SELECT id, reading FROM readings WHERE synced = 0 ORDER BY timestamp, id`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query unsynced readings: %w", err)
+	}
+
+	type pending struct {
+		id      int64
+		reading mirroredReading
+	}
+	var unsynced []pending
+	for rows.Next() {
+		var p pending
+		var encoded string
+		if err := rows.Scan(&p.id, &encoded); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan unsynced reading: %w", err)
+		}
+		if err := json.Unmarshal([]byte(encoded), &p.reading); err != nil {
+			slog.Warn("Skipping undecodable mirrored reading", "component", "mirror", "mirror_id", p.id, "error", err)
+			continue
+		}
+		unsynced = append(unsynced, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate unsynced readings: %w", err)
+	}
+
+	for i, p := range unsynced {
+		data := p.reading.Data
+		data.Extra = p.reading.Extra
+		data.Raw = []byte(p.reading.Raw)
+		data.Missing = p.reading.Missing
+
+		waitCatchup()
+		if _, err := storeReading(db, data); err != nil {
+			return i, fmt.Errorf("failed to sync mirrored reading %d: %w", p.id, err)
+		}
+		if err := m.MarkSynced(p.id); err != nil {
+			return i + 1, err
+		}
+	}
+	return len(unsynced), nil
+}
+
+// Close closes the mirror database
+func (m *SQLiteMirror) Close() error {
+	return m.db.Close()
+}
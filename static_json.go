@@ -0,0 +1,106 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// StaticCurrent is the content of CURRENT_JSON_PATH
+type StaticCurrent struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Station     string         `json:"station,omitempty"`
+	Reading     *StoredReading `json:"reading"`
+}
+
+// StaticDaily is the content of DAILY_JSON_PATH
+type StaticDaily struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	Station     string       `json:"station,omitempty"`
+	Days        []DailyStats `json:"days"`
+}
+
+// writeFileAtomic replaces path with data through a temp file in the same
+// directory and a rename, so readers see either the old or the new file
+func writeFileAtomic(path string, data []byte) error {
+	if dryRunSkip("static_json", "path", path, "content", string(data)) {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	// CreateTemp uses 0600, the files are meant for a web server
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
+
+// writeJSONFileAtomic encodes v and writes it with writeFileAtomic
+func writeJSONFileAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// writeCurrentJSON publishes the latest stored reading to CURRENT_JSON_PATH.
+// Reading is null until the first reading is stored.
+func writeCurrentJSON(db dbExecutor) {
+	if config.CurrentJSONPath == "" {
+		return
+	}
+	out := StaticCurrent{GeneratedAt: now().UTC(), Station: config.StationName}
+	reading, err := queryLatestReading(db)
+	switch {
+	case err == nil:
+		out.Reading = &reading
+	case err != sql.ErrNoRows:
+		slog.Warn("Failed to query latest reading", "component", "static_json", "path", config.CurrentJSONPath, "error", err)
+		return
+	}
+	if err := writeJSONFileAtomic(config.CurrentJSONPath, out); err != nil {
+		slog.Warn("Failed to write static JSON", "component", "static_json", "path", config.CurrentJSONPath, "error", err)
+	}
+}
+
+// writeDailyJSON publishes the daily statistics of the last DAILY_JSON_DAYS
+// days, today included, to DAILY_JSON_PATH
+func writeDailyJSON(db dbExecutor) {
+	if config.DailyJSONPath == "" {
+		return
+	}
+	to := midnight(observationDayStart(now().In(config.Location)))
+	from := to.AddDate(0, 0, -(config.DailyJSONDays - 1))
+	days, err := queryDailyStats(db, from, to)
+	if err != nil {
+		slog.Warn("Failed to query daily statistics", "component", "static_json", "path", config.DailyJSONPath, "error", err)
+		return
+	}
+	out := StaticDaily{GeneratedAt: now().UTC(), Station: config.StationName, Days: days}
+	if err := writeJSONFileAtomic(config.DailyJSONPath, out); err != nil {
+		slog.Warn("Failed to write static JSON", "component", "static_json", "path", config.DailyJSONPath, "error", err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+)
+
+// StationMetadata describes the station for dashboards and for features that
+// need its location
+type StationMetadata struct {
+	Name        string   `json:"name"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	Altitude    float64  `json:"altitude"`
+	SensorModel string   `json:"sensor_model,omitempty"`
+	Installed   string   `json:"installed,omitempty"`
+}
+
+// stationMetadata returns the station metadata from the config
+func stationMetadata() StationMetadata {
+	return StationMetadata{
+		Name:        config.StationName,
+		Latitude:    config.StationLatitude,
+		Longitude:   config.StationLongitude,
+		Altitude:    config.StationAltitude,
+		SensorModel: config.StationSensorModel,
+		Installed:   config.StationInstalled,
+	}
+}
+
+// saveStationMetadata stores the metadata in the single-row weather_station
+// table so other consumers of the database can read it
+func saveStationMetadata(db *sql.DB, m StationMetadata) error {
+	if config.ReadOnly {
+		return errReadOnly
+	}
+
+	var installed any
+	if m.Installed != "" {
+		installed = m.Installed
+	}
+
+	upsert := `
+		INSERT INTO weather_station (id, name, latitude, longitude, altitude, sensor_model, installed)
+		VALUES (1, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			name = VALUES(name),
+			latitude = VALUES(latitude),
+			longitude = VALUES(longitude),
+			altitude = VALUES(altitude),
+			sensor_model = VALUES(sensor_model),
+			installed = VALUES(installed),
+			updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := db.Exec(upsert, m.Name, m.Latitude, m.Longitude, m.Altitude, m.SensorModel, installed)
+	if err != nil {
+		return fmt.Errorf("failed to save station metadata: %w", err)
+	}
+	return nil
+}
+
+// ------------------------- STATION ------------------------------
+func stationHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, stationMetadata())
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// defaultStationID is the STATION_ID of single-station setups; existing rows
+// get it from the column default when station_id is added
+const defaultStationID = "default"
+
+// stationTables are the tables whose rows are keyed by STATION_ID
+var stationTables = []string{
+	"weather_hourly", "weather_daily", "weather_weekly", "weather_monthly",
+	"weather_running", "weather_yearly", "weather_season", "weather_drift",
+	"weather_streaks", "weather_pressure_change_hist",
+}
+
+// stationIDPattern restricts STATION_ID to characters that are safe inside a
+// quoted SQL literal, see stationLiteral
+var stationIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// validateStationID checks STATION_ID against stationIDPattern
+func validateStationID(id string) error {
+	if !stationIDPattern.MatchString(id) {
+		return fmt.Errorf("%q must be 1-64 letters, digits, '_', '.' or '-'", id)
+	}
+	return nil
+}
+
+// stationExecutor binds an executor to one station. With JSON_SOURCE_URLS
+// every reading carries the ID of its source, and the aggregates of each
+// station are computed from its own readings and stored under its ID. An
+// unbound executor works on STATION_ID.
+type stationExecutor struct {
+	dbExecutor
+	station string
+}
+
+// stationStore is a Store bound to one station, see stationExecutor
+type stationStore struct {
+	Store
+	station string
+}
+
+// forStation binds db to the station id
+func forStation(db dbExecutor, id string) dbExecutor {
+	switch s := db.(type) {
+	case stationExecutor:
+		return stationExecutor{s.dbExecutor, id}
+	case stationStore:
+		return stationStore{s.Store, id}
+	}
+	return stationExecutor{db, id}
+}
+
+// forStationStore binds a Store to the station id
+func forStationStore(db Store, id string) Store {
+	if s, ok := db.(stationStore); ok {
+		return stationStore{s.Store, id}
+	}
+	return stationStore{db, id}
+}
+
+// stationOf returns the station db is bound to, STATION_ID when unbound
+func stationOf(db dbExecutor) string {
+	switch s := db.(type) {
+	case stationExecutor:
+		return s.station
+	case stationStore:
+		return s.station
+	}
+	return config.StationID
+}
+
+// aggregateStations are the stations the statistics jobs aggregate: every
+// station of JSON_SOURCE_URLS, or STATION_ID alone
+func aggregateStations() []string {
+	if len(stationSources) == 0 {
+		return []string{config.StationID}
+	}
+	ids := make([]string, len(stationSources))
+	for i, s := range stationSources {
+		ids[i] = s.StationID
+	}
+	return ids
+}
+
+// forEachStation runs fn with db bound to each of aggregateStations. A
+// failing station does not stop the others; the errors are joined.
+func forEachStation(db Store, fn func(db Store) error) error {
+	var errs []error
+	for _, id := range aggregateStations() {
+		if err := fn(forStationStore(db, id)); err != nil {
+			errs = append(errs, fmt.Errorf("station %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stationLiteral is the station of db as a quoted SQL literal. The filter
+// below is appended to queries with differing argument lists, like
+// exclusionFilter, so the validated ID is inlined instead of bound.
+func stationLiteral(db dbExecutor) string {
+	return "'" + stationOf(db) + "'"
+}
+
+// stationFilter restricts a query on weather or an aggregate table to the
+// rows of the station of db
+func stationFilter(db dbExecutor) string {
+	return " AND station_id = " + stationLiteral(db)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStationSourcesValidatesIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []string
+		wantErr string
+	}{
+		{"two stations", "garden=http://a/w.json, roof=http://b/w.json", []string{"garden", "roof"}, ""},
+		{"empty entries skipped", "garden=http://a/w.json,,", []string{"garden"}, ""},
+		{"missing url", "garden=", nil, "expected station=url"},
+		{"quote in id", "gar'den=http://a/w.json", nil, "invalid station"},
+		{"space in id", "my garden=http://a/w.json", nil, "invalid station"},
+		{"duplicate", "garden=http://a/w.json,garden=http://b/w.json", nil, "listed twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources, err := parseStationSources(tt.value, time.Second)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseStationSources() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseStationSources(): %v", err)
+			}
+			var got []string
+			for _, s := range sources {
+				got = append(got, s.StationID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStationAggregatesAreIndependent(t *testing.T) {
+	setupTestConfig(t, map[string]string{"LONG_FORMAT": "true"})
+	saved := stationSources
+	stationSources = []StationSource{{StationID: "garden"}, {StationID: "roof"}}
+	t.Cleanup(func() { stationSources = saved })
+	db := newTestStore(t)
+
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	readings := []struct {
+		station     string
+		minute      int
+		temperature float64
+	}{
+		{"garden", 0, 20},
+		{"roof", 5, 10},
+		{"garden", 20, 22},
+		{"roof", 25, 12},
+		{"roof", 40, 14},
+	}
+	for _, r := range readings {
+		at := day.Add(10*time.Hour + time.Duration(r.minute)*time.Minute)
+		payload := fmt.Sprintf(`{"timestamp": %d, "temperature": %g, "pressure": 1013, "humidity": 70}`,
+			at.Unix(), r.temperature)
+		if err := processPayload(context.Background(), db, []byte(payload), r.station); err != nil {
+			t.Fatalf("processPayload(%s): %v", r.station, err)
+		}
+	}
+
+	setTestNow(t, day.AddDate(0, 0, 1).Add(5*time.Minute))
+	err := forEachStation(db, func(db Store) error {
+		return withAggregateTx(db, updateDailyStatistics)
+	})
+	if err != nil {
+		t.Fatalf("updateDailyStatistics: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []map[string]string
+	}{
+		{
+			"hourly",
+			`SELECT station_id, hour, avg_temperature, samples_count FROM weather_hourly ORDER BY station_id`,
+			[]map[string]string{
+				{"station_id": "garden", "hour": "10", "avg_temperature": "21", "samples_count": "2"},
+				{"station_id": "roof", "hour": "10", "avg_temperature": "12", "samples_count": "3"},
+			},
+		},
+		{
+			"daily",
+			`SELECT station_id, min_temperature, max_temperature, samples_count FROM weather_daily ORDER BY station_id`,
+			[]map[string]string{
+				{"station_id": "garden", "min_temperature": "20", "max_temperature": "22", "samples_count": "2"},
+				{"station_id": "roof", "min_temperature": "10", "max_temperature": "14", "samples_count": "3"},
+			},
+		},
+		{
+			"long format",
+			`SELECT station_id, COUNT(*) AS temperature_rows FROM weather_long
+			WHERE metric = 'temperature' GROUP BY station_id ORDER BY station_id`,
+			[]map[string]string{
+				{"station_id": "garden", "temperature_rows": "2"},
+				{"station_id": "roof", "temperature_rows": "3"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := queryRows(t, db, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
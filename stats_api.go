@@ -0,0 +1,209 @@
+package main
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// maxDailyRangeDays limits the span of a /api/daily query
+const maxDailyRangeDays = 366
+
+// HourlyStats is a row of weather_hourly
+type HourlyStats struct {
+	Hour           int      `json:"hour"`
+	AvgTemperature *float64 `json:"avg_temperature"`
+	AvgPressure    *float64 `json:"avg_pressure"`
+	AvgHumidity    *float64 `json:"avg_humidity"`
+	SamplesCount   int      `json:"samples_count"`
+}
+
+// DailyStats is a row of weather_daily
+type DailyStats struct {
+	Date           string   `json:"date"`
+	AvgTemperature *float64 `json:"avg_temperature"`
+	MinTemperature *float64 `json:"min_temperature"`
+	MaxTemperature *float64 `json:"max_temperature"`
+	AvgPressure    *float64 `json:"avg_pressure"`
+	MinPressure    *float64 `json:"min_pressure"`
+	MaxPressure    *float64 `json:"max_pressure"`
+	AvgHumidity    *float64 `json:"avg_humidity"`
+	MinHumidity    *float64 `json:"min_humidity"`
+	MaxHumidity    *float64 `json:"max_humidity"`
+	SamplesCount   int      `json:"samples_count"`
+}
+
+// parseDateParam parses a required YYYY-MM-DD query parameter
+func parseDateParam(r *http.Request, name string) (time.Time, bool) {
+	t, err := time.Parse("2006-01-02", r.URL.Query().Get(name))
+	return t, err == nil
+}
+
+// roundDisplayAll rounds every non-nil value in place for presentation, so
+// aggregates stored at full precision are served like the rest of the API
+func roundDisplayAll(values ...*float64) {
+	for _, v := range values {
+		if v != nil {
+			*v = roundDisplay(*v)
+		}
+	}
+}
+
+// hourlyHandler returns the hourly averages of ?date=YYYY-MM-DD
+func hourlyHandler(db *ReconnectingDB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		date, ok := parseDateParam(r, "date")
+		if !ok {
+			writeError(w, http.StatusBadRequest, "date must be in YYYY-MM-DD format")
+			return
+		}
+
+		rows, err := db.DB().Query(`
+			SELECT hour, avg_temperature, avg_pressure, avg_humidity, samples_count
+			FROM weather_hourly
+			WHERE date = ?`+stationFilter(db.DB())+`
+			ORDER BY hour
+		`, date.Format("2006-01-02"))
+		db.Observe(err)
+		if err != nil {
+			slog.Error("Failed to query hourly statistics", "component", "api", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to query hourly statistics")
+			return
+		}
+		defer rows.Close()
+
+		hours := []HourlyStats{}
+		for rows.Next() {
+			var h HourlyStats
+			if err := rows.Scan(&h.Hour, &h.AvgTemperature, &h.AvgPressure, &h.AvgHumidity, &h.SamplesCount); err != nil {
+				slog.Error("Failed to scan hourly statistics", "component", "api", "error", err)
+				writeError(w, http.StatusInternalServerError, "failed to read hourly statistics")
+				return
+			}
+			roundDisplayAll(h.AvgTemperature, h.AvgPressure, h.AvgHumidity)
+			hours = append(hours, h)
+		}
+		if err := rows.Err(); err != nil {
+			slog.Error("Failed to iterate hourly statistics", "component", "api", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to read hourly statistics")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"date": date.Format("2006-01-02"), "hours": hours})
+	}
+}
+
+// dailyHandler returns the daily statistics from ?from= to ?to= (inclusive)
+func dailyHandler(db *ReconnectingDB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, okFrom := parseDateParam(r, "from")
+		to, okTo := parseDateParam(r, "to")
+		if !okFrom || !okTo {
+			writeError(w, http.StatusBadRequest, "from and to must be in YYYY-MM-DD format")
+			return
+		}
+		if to.Before(from) {
+			writeError(w, http.StatusBadRequest, "to must not be before from")
+			return
+		}
+		if to.Sub(from) >= maxDailyRangeDays*24*time.Hour {
+			writeError(w, http.StatusBadRequest, "range must not exceed 366 days")
+			return
+		}
+
+		days, err := queryDailyStats(db.DB(), from, to)
+		db.Observe(err)
+		if err != nil {
+			slog.Error("Failed to query daily statistics", "component", "api", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to query daily statistics")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"days": days})
+	}
+}
+
+// latestHandler returns the most recent stored reading
+func latestHandler(db *ReconnectingDB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reading, err := queryLatestReading(db.DB())
+		db.Observe(err)
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "no readings stored yet")
+			return
+		}
+		if err != nil {
+			slog.Error("Failed to query latest reading", "component", "api", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to query latest reading")
+			return
+		}
+		writeJSON(w, http.StatusOK, reading)
+	}
+}
+
+// queryDailyStats returns the weather_daily rows between from and to,
+// rounded for display
+func queryDailyStats(db dbExecutor, from, to time.Time) ([]DailyStats, error) {
+	rows, err := db.Query(`
+		SELECT date,
+			avg_temperature, min_temperature, max_temperature,
+			avg_pressure, min_pressure, max_pressure,
+			avg_humidity, min_humidity, max_humidity,
+			samples_count
+		FROM weather_daily
+		WHERE date BETWEEN ? AND ?`+stationFilter(db)+`
+		ORDER BY date
+	`, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []DailyStats{}
+	for rows.Next() {
+		var d DailyStats
+		var date time.Time
+		if err := rows.Scan(&date,
+			&d.AvgTemperature, &d.MinTemperature, &d.MaxTemperature,
+			&d.AvgPressure, &d.MinPressure, &d.MaxPressure,
+			&d.AvgHumidity, &d.MinHumidity, &d.MaxHumidity,
+			&d.SamplesCount); err != nil {
+			return nil, err
+		}
+		roundDisplayAll(d.AvgTemperature, d.MinTemperature, d.MaxTemperature,
+			d.AvgPressure, d.MinPressure, d.MaxPressure,
+			d.AvgHumidity, d.MinHumidity, d.MaxHumidity)
+		d.Date = date.Format("2006-01-02")
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// queryLatestReading returns the most recent stored reading, sql.ErrNoRows
+// when there is none
+func queryLatestReading(db dbExecutor) (StoredReading, error) {
+	var reading StoredReading
+	err := db.QueryRow(`
+		SELECT id, measured_at, temperature, pressure, humidity
+		FROM weather
+		WHERE 1 = 1`+stationFilter(db)+`
+		ORDER BY measured_at DESC, id DESC
+		LIMIT 1
+	`).Scan(&reading.ID, &reading.MeasuredAt, &reading.Temperature, &reading.Pressure, &reading.Humidity)
+	return reading, err
+}
+
+// healthzHandler reports whether the database can be reached
+func healthzHandler(db *ReconnectingDB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := db.DB().PingContext(r.Context())
+		db.Observe(err)
+		if err != nil {
+			slog.Warn("Health check failed", "component", "api", "error", err)
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": "database unreachable"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
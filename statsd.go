@@ -0,0 +1,75 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+)
+
+// StatsdClient sends DogStatsD gauges over UDP. Sends are best-effort: the
+// write deadline is short and errors are only logged.
+type StatsdClient struct {
+	conn   net.Conn
+	prefix string
+	tags   []string
+}
+
+var statsdClient *StatsdClient
+
+// newStatsdClient creates a client for the given host:port. Global tags are
+// added to every metric.
+func newStatsdClient(addr, prefix string, tags []string) (*StatsdClient, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open StatsD socket: %w", err)
+	}
+	return &StatsdClient{conn: conn, prefix: prefix, tags: tags}, nil
+}
+
+// gaugeLine formats a single DogStatsD gauge
+func (c *StatsdClient) gaugeLine(name string, value float64, tags []string) string {
+	line := fmt.Sprintf("%s%s:%g|g", c.prefix, name, value)
+	all := append(append([]string{}, c.tags...), tags...)
+	if len(all) > 0 {
+		line += "|#" + strings.Join(all, ",")
+	}
+	return line
+}
+
+// send writes a packet without blocking on a slow or missing agent
+func (c *StatsdClient) send(lines []string) {
+	c.conn.SetWriteDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := c.conn.Write([]byte(strings.Join(lines, "\n"))); err != nil {
+		slog.Warn("Failed to send StatsD metrics", "component", "statsd", "error", err)
+	}
+}
+
+// emitStatsdGauges sends the avg/min/max of one metric for a statistics
+// period as gauges tagged with granularity and metric. A metric without
+// samples in the period is not sent.
+func emitStatsdGauges(granularity, metric string, avg, min, max sql.NullFloat64) {
+	if statsdClient == nil || !avg.Valid {
+		return
+	}
+
+	tags := []string{"granularity:" + granularity, "metric:" + metric}
+	statsdClient.send([]string{
+		statsdClient.gaugeLine("avg", avg.Float64, tags),
+		statsdClient.gaugeLine("min", min.Float64, tags),
+		statsdClient.gaugeLine("max", max.Float64, tags),
+	})
+}
+
+// parseTags splits a comma-separated tag list
+func parseTags(value string) []string {
+	var tags []string
+	for _, t := range strings.Split(value, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
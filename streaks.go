@@ -0,0 +1,157 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Streak is the current and the longest run of consecutive dry or wet days
+type Streak struct {
+	Length       int
+	Start        sql.NullTime
+	Longest      int
+	LongestStart sql.NullTime
+	LongestEnd   sql.NullTime
+}
+
+// StreakState holds both streaks; it is persisted in weather_streaks so the
+// streaks continue across restarts
+type StreakState struct {
+	Dry      Streak
+	Wet      Streak
+	LastDate sql.NullTime
+}
+
+// extend adds a day to the streak and updates the longest run
+func (s Streak) extend(date time.Time) Streak {
+	if s.Length == 0 {
+		s.Start = sql.NullTime{Time: date, Valid: true}
+	}
+	s.Length++
+	if s.Length > s.Longest {
+		s.Longest = s.Length
+		s.LongestStart = s.Start
+		s.LongestEnd = sql.NullTime{Time: date, Valid: true}
+	}
+	return s
+}
+
+// reset ends the current run, keeping the longest
+func (s Streak) reset() Streak {
+	s.Length = 0
+	s.Start = sql.NullTime{}
+	return s
+}
+
+// advanceStreaks applies one day's rainfall to the state. A day is dry with
+// rainfall below threshold and wet otherwise. A gap in the dates (a day
+// without rainfall data) ends both current streaks. Days not after the last
+// processed date are ignored.
+func advanceStreaks(state StreakState, date time.Time, rainfall, threshold float64) StreakState {
+	if state.LastDate.Valid && !date.After(state.LastDate.Time) {
+		return state
+	}
+	if state.LastDate.Valid && !state.LastDate.Time.AddDate(0, 0, 1).Equal(date) {
+		state.Dry = state.Dry.reset()
+		state.Wet = state.Wet.reset()
+	}
+	state.LastDate = sql.NullTime{Time: date, Valid: true}
+
+	if rainfall < threshold {
+		state.Dry = state.Dry.extend(date)
+		state.Wet = state.Wet.reset()
+	} else {
+		state.Wet = state.Wet.extend(date)
+		state.Dry = state.Dry.reset()
+	}
+	return state
+}
+
+// loadStreakState reads the stored streaks
+func loadStreakState(db dbExecutor) (StreakState, error) {
+	var state StreakState
+
+	rows, err := db.Query(`
+		SELECT kind, current_length, current_start, longest_length, longest_start, longest_end, last_date
+		FROM weather_streaks
+		WHERE 1 = 1` + stationFilter(db) + `
+	`)
+	if err != nil {
+		return state, fmt.Errorf("failed to load streaks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind string
+		var s Streak
+		var lastDate sql.NullTime
+		if err := rows.Scan(&kind, &s.Length, &s.Start, &s.Longest, &s.LongestStart, &s.LongestEnd, &lastDate); err != nil {
+			return state, fmt.Errorf("failed to scan streak: %w", err)
+		}
+		switch kind {
+		case "dry":
+			state.Dry = s
+		case "wet":
+			state.Wet = s
+		}
+		state.LastDate = lastDate
+	}
+	if err := rows.Err(); err != nil {
+		return state, fmt.Errorf("failed to iterate streaks: %w", err)
+	}
+	return state, nil
+}
+
+// saveStreakState stores both streaks
+func saveStreakState(db dbExecutor, state StreakState) error {
+	upsert := `
+		INSERT INTO weather_streaks (station_id, kind, current_length, current_start, longest_length, longest_start, longest_end, last_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			current_length = VALUES(current_length),
+			current_start = VALUES(current_start),
+			longest_length = VALUES(longest_length),
+			longest_start = VALUES(longest_start),
+			longest_end = VALUES(longest_end),
+			last_date = VALUES(last_date),
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	for kind, s := range map[string]Streak{"dry": state.Dry, "wet": state.Wet} {
+		_, err := db.Exec(upsert, stationOf(db), kind, s.Length, s.Start, s.Longest, s.LongestStart, s.LongestEnd, state.LastDate)
+		if err != nil {
+			return fmt.Errorf("failed to save %s streak: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+// updateRainStreaks feeds the stored rainfall of a day into the dry/wet
+// streaks. Days without rainfall data are skipped.
+func updateRainStreaks(db dbExecutor, day time.Time) error {
+	// Compare calendar dates the way they come back from DATE columns
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	date := day.Format("2006-01-02")
+
+	var rainfall sql.NullFloat64
+	err := db.QueryRow(`SELECT total_rain FROM weather_daily WHERE date = ?`+stationFilter(db), date).Scan(&rainfall)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to query daily rainfall: %w", err)
+	}
+	if !rainfall.Valid {
+		slog.Info("No rainfall, skipping streaks", "component", "stats", "date", date)
+		return nil
+	}
+
+	state, err := loadStreakState(db)
+	if err != nil {
+		return err
+	}
+
+	next := advanceStreaks(state, day, rainfall.Float64, config.WetDayThreshold)
+	slog.Info("Rain streaks updated", "component", "stats", "date", date, "dry_days", next.Dry.Length, "wet_days", next.Wet.Length)
+
+	return saveStreakState(db, next)
+}
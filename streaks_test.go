@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdvanceStreaks(t *testing.T) {
+	const threshold = 1.0
+
+	type day struct {
+		offset   int // days after the first date
+		rainfall float64
+	}
+	tests := []struct {
+		name        string
+		days        []day
+		wantDry     int
+		wantWet     int
+		wantLongest int // longest dry run
+		wantStart   int // offset of the current dry run, -1 for none
+	}{
+		{"dry streak starts", []day{{0, 0}}, 1, 0, 1, 0},
+		{"dry streak continues", []day{{0, 0}, {1, 0.2}, {2, 0}}, 3, 0, 3, 0},
+		{"wet day resets dry streak", []day{{0, 0}, {1, 0}, {2, 5}}, 0, 1, 2, -1},
+		{"rainfall at threshold is wet", []day{{0, 0}, {1, 1.0}}, 0, 1, 1, -1},
+		{"dry streak restarts after wet day", []day{{0, 0}, {1, 0}, {2, 3}, {3, 0}}, 1, 0, 2, 3},
+		{"gap resets both streaks", []day{{0, 0}, {1, 0}, {3, 0}}, 1, 0, 2, 3},
+		{"replayed day is ignored", []day{{0, 0}, {1, 0}, {1, 8}}, 2, 0, 2, 0},
+	}
+	first := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var state StreakState
+			for _, d := range tt.days {
+				state = advanceStreaks(state, first.AddDate(0, 0, d.offset), d.rainfall, threshold)
+			}
+
+			if state.Dry.Length != tt.wantDry || state.Wet.Length != tt.wantWet {
+				t.Errorf("dry %d, wet %d, want dry %d, wet %d", state.Dry.Length, state.Wet.Length, tt.wantDry, tt.wantWet)
+			}
+			if state.Dry.Longest != tt.wantLongest {
+				t.Errorf("longest dry = %d, want %d", state.Dry.Longest, tt.wantLongest)
+			}
+			switch {
+			case tt.wantStart < 0 && state.Dry.Start.Valid:
+				t.Errorf("dry streak start = %s, want none", state.Dry.Start.Time.Format("2006-01-02"))
+			case tt.wantStart >= 0 && !state.Dry.Start.Time.Equal(first.AddDate(0, 0, tt.wantStart)):
+				t.Errorf("dry streak start = %s, want %s", state.Dry.Start.Time.Format("2006-01-02"),
+					first.AddDate(0, 0, tt.wantStart).Format("2006-01-02"))
+			}
+		})
+	}
+}
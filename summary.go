@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Processor accumulates counters of the current session for the summary
+// logged on shutdown, and the state of the cron jobs for /api/jobs
+type Processor struct {
+	mu            sync.Mutex
+	started       time.Time
+	readings      int
+	failures      int
+	lastTimestamp int64
+	statsRuns     map[string]int
+
+	scheduler *cron.Cron
+	jobs      map[string]*jobStatus
+}
+
+// ProcessingSummary is the session summary as logged with LOG_FORMAT=json
+type ProcessingSummary struct {
+	UptimeSeconds int64          `json:"uptime_seconds"`
+	Readings      int            `json:"readings_processed"`
+	Failures      int            `json:"failures"`
+	LastTimestamp *int64         `json:"last_reading_timestamp"`
+	StatsRuns     map[string]int `json:"statistics_runs"`
+}
+
+// processor holds the counters of the running process
+var processor = newProcessor()
+
+func newProcessor() *Processor {
+	return &Processor{
+		started:   time.Now(),
+		statsRuns: make(map[string]int),
+		jobs:      make(map[string]*jobStatus),
+	}
+}
+
+// RecordReading counts a stored reading
+func (p *Processor) RecordReading(timestamp int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readings++
+	p.lastTimestamp = timestamp
+}
+
+// RecordFailure counts a failed processing run or statistics job
+func (p *Processor) RecordFailure() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures++
+}
+
+// RecordStatsRun counts a completed run of a statistics job
+func (p *Processor) RecordStatsRun(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statsRuns[name]++
+}
+
+// Summary returns a snapshot of the session counters
+func (p *Processor) Summary() ProcessingSummary {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	summary := ProcessingSummary{
+		UptimeSeconds: int64(time.Since(p.started).Seconds()),
+		Readings:      p.readings,
+		Failures:      p.failures,
+		StatsRuns:     make(map[string]int, len(p.statsRuns)),
+	}
+	if p.readings > 0 {
+		ts := p.lastTimestamp
+		summary.LastTimestamp = &ts
+	}
+	for name, runs := range p.statsRuns {
+		summary.StatsRuns[name] = runs
+	}
+	return summary
+}
+
+// logSummary logs the session summary as a text line or, with
+// LOG_FORMAT=json, as a processing_summary object of the log record
+func (p *Processor) logSummary() {
+	summary := p.Summary()
+
+	if config.LogFormat == LogFormatJSON {
+		slog.Info("Processing summary", "component", "main", "processing_summary", summary)
+		return
+	}
+
+	last := "none"
+	if summary.LastTimestamp != nil {
+		last = time.Unix(*summary.LastTimestamp, 0).In(config.Location).Format(time.RFC3339)
+	}
+
+	names := make([]string, 0, len(summary.StatsRuns))
+	for name := range summary.StatsRuns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	runs := "none"
+	if len(names) > 0 {
+		parts := make([]string, 0, len(names))
+		for _, name := range names {
+			parts = append(parts, fmt.Sprintf("%s=%d", name, summary.StatsRuns[name]))
+		}
+		runs = strings.Join(parts, " ")
+	}
+
+	slog.Info("Processing summary", "component", "main",
+		"uptime", time.Duration(summary.UptimeSeconds)*time.Second, "readings", summary.Readings,
+		"failures", summary.Failures, "last_reading", last, "statistics_runs", runs)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// countSunshineHours counts the hours whose average solar radiation exceeds
+// threshold (W/m²). Hours without radiation data are ignored; ok is false
+// when no hour of the day has radiation data at all.
+func countSunshineHours(hourlyRadiation []sql.NullFloat64, threshold float64) (hours int, ok bool) {
+	for _, radiation := range hourlyRadiation {
+		if !radiation.Valid {
+			continue
+		}
+		ok = true
+		if radiation.Float64 > threshold {
+			hours++
+		}
+	}
+	return hours, ok
+}
+
+// updateDailySunshineHours estimates and stores the sunshine hours of a date
+// from the hourly average solar radiation. Days without radiation data are
+// stored as NULL.
+func updateDailySunshineHours(db dbExecutor, date string) error {
+	query := `
+		SELECT AVG(solar_radiation)
+		FROM weather
+		WHERE ` + observationDay() + ` = ?` + exclusionFilter() + stationFilter(db) + `
+		GROUP BY HOUR(measured_at)
+	`
+
+	rows, err := db.Query(query, date)
+	if err != nil {
+		return fmt.Errorf("failed to query hourly solar radiation: %w", err)
+	}
+	defer rows.Close()
+
+	var hourly []sql.NullFloat64
+	for rows.Next() {
+		var radiation sql.NullFloat64
+		if err := rows.Scan(&radiation); err != nil {
+			return fmt.Errorf("failed to scan hourly solar radiation: %w", err)
+		}
+		hourly = append(hourly, radiation)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate hourly solar radiation: %w", err)
+	}
+
+	var sunshineHours sql.NullInt64
+	if hours, ok := countSunshineHours(hourly, config.SunshineThreshold); ok {
+		sunshineHours = sql.NullInt64{Int64: int64(hours), Valid: true}
+		slog.Info("Sunshine hours", "component", "stats", "date", date, "hours", hours)
+	}
+
+	_, err = db.Exec(`UPDATE weather_daily SET sunshine_hours = ? WHERE date = ?`+stationFilter(db), sunshineHours, date)
+	if err != nil {
+		return fmt.Errorf("failed to store sunshine hours: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCountSunshineHours(t *testing.T) {
+	valid := func(v float64) sql.NullFloat64 { return sql.NullFloat64{Float64: v, Valid: true} }
+	missing := sql.NullFloat64{}
+
+	tests := []struct {
+		name      string
+		hourly    []sql.NullFloat64
+		wantHours int
+		wantOK    bool
+	}{
+		{"no data", nil, 0, false},
+		{"only missing hours", []sql.NullFloat64{missing, missing}, 0, false},
+		{"night", []sql.NullFloat64{valid(0), valid(0)}, 0, true},
+		{"exactly at threshold", []sql.NullFloat64{valid(120)}, 0, true},
+		{"just above threshold", []sql.NullFloat64{valid(120.1)}, 1, true},
+		{"missing hours ignored", []sql.NullFloat64{valid(300), missing, valid(80), valid(450)}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hours, ok := countSunshineHours(tt.hourly, 120)
+			if hours != tt.wantHours || ok != tt.wantOK {
+				t.Errorf("countSunshineHours = %d, %v, want %d, %v", hours, ok, tt.wantHours, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDailySunshineHoursAveragesPartialHours(t *testing.T) {
+	setupTestConfig(t, map[string]string{"ENABLE_SUNSHINE_HOURS": "true", "SUNSHINE_THRESHOLD": "120"})
+	db := newTestStore(t)
+
+	// Readings at :00 and :30. A cloud covering half of an hour drags its
+	// average below the threshold unless the sunny half is strong enough.
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, config.Location)
+	hours := [][2]float64{
+		{300, 0},   // 09:00, average 150, sunny
+		{200, 0},   // 10:00, average 100, not sunny
+		{240, 0},   // 11:00, average exactly 120, not sunny
+		{400, 420}, // 12:00, sunny
+	}
+	for i, radiation := range hours {
+		for j, r := range radiation {
+			at := day.Add(time.Duration(9+i)*time.Hour + time.Duration(30*j)*time.Minute)
+			processFixture(t, db, []byte(fmt.Sprintf(
+				`{"timestamp": %d, "temperature": 20.0, "pressure": 1012.0, "humidity": 60.0, "solar_radiation": %.1f}`,
+				at.Unix(), r)))
+		}
+	}
+	if err := updateDailyStatisticsFor(db, day); err != nil {
+		t.Fatalf("updateDailyStatisticsFor: %v", err)
+	}
+
+	rows := queryRows(t, db, `SELECT sunshine_hours FROM weather_daily`)
+	if len(rows) != 1 || rows[0]["sunshine_hours"] != "2" {
+		t.Errorf("sunshine_hours = %v, want 2", rows)
+	}
+}
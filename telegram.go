@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// telegramAPIURL is the base URL of the Telegram Bot API
+const telegramAPIURL = "https://api.telegram.org"
+
+// telegramTimeout bounds a single sendMessage request
+const telegramTimeout = 10 * time.Second
+
+// telegramQueueSize is the number of alerts waiting for delivery before new
+// ones are dropped
+const telegramQueueSize = 20
+
+// telegramMaxRetryAfter caps how long a rate-limited message waits before
+// its single retry
+const telegramMaxRetryAfter = 30 * time.Second
+
+// TelegramNotifier sends alerts to a Telegram chat through the Bot API
+// sendMessage method. Messages are delivered by a background goroutine from
+// a bounded queue, so a slow or unreachable API never blocks processing.
+type TelegramNotifier struct {
+	token  string
+	chatID string
+	client *http.Client
+	queue  chan string
+	done   chan struct{}
+}
+
+// telegramResponse is the envelope of every Bot API response
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// telegramError is a request rejected by the Bot API
+type telegramError struct {
+	Code        int
+	Description string
+	RetryAfter  time.Duration
+}
+
+func (e *telegramError) Error() string {
+	return fmt.Sprintf("telegram API error %d: %s", e.Code, e.Description)
+}
+
+var telegramNotifier *TelegramNotifier
+
+// newTelegramNotifier creates a notifier for the bot token and chat and
+// starts its delivery goroutine
+func newTelegramNotifier(token, chatID string) *TelegramNotifier {
+	n := &TelegramNotifier{
+		token:  token,
+		chatID: chatID,
+		client: &http.Client{Timeout: telegramTimeout},
+		queue:  make(chan string, telegramQueueSize),
+		done:   make(chan struct{}),
+	}
+	go n.run()
+	return n
+}
+
+func (n *TelegramNotifier) run() {
+	defer close(n.done)
+	for text := range n.queue {
+		err := n.send(text)
+		var apiErr *telegramError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			time.Sleep(min(apiErr.RetryAfter, telegramMaxRetryAfter))
+			err = n.send(text)
+		}
+		if err != nil {
+			slog.Warn("Failed to send Telegram alert", "component", "alert", "error", err)
+		}
+	}
+}
+
+// Notify formats the alert and queues it for delivery without blocking.
+// An error is only returned when the queue is full and the alert dropped.
+func (n *TelegramNotifier) Notify(message string) error {
+	text := formatTelegramAlert(message, now())
+	if dryRunSkip("telegram", "text", text) {
+		return nil
+	}
+	select {
+	case n.queue <- text:
+		return nil
+	default:
+		return errors.New("telegram queue full, dropping alert")
+	}
+}
+
+// Close delivers the queued alerts, giving up after timeout
+func (n *TelegramNotifier) Close(timeout time.Duration) error {
+	close(n.queue)
+	select {
+	case <-n.done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("%d Telegram alerts not delivered", len(n.queue))
+	}
+}
+
+// send posts one message to the chat
+func (n *TelegramNotifier) send(text string) error {
+	body, err := json.Marshal(map[string]any{
+		"chat_id":                  n.chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode Telegram message: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", telegramAPIURL, n.token)
+	resp, err := n.client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The URL contains the bot token, keep it out of the logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to call Telegram API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode Telegram response (status %s): %w", resp.Status, err)
+	}
+	if !result.OK {
+		return &telegramError{
+			Code:        result.ErrorCode,
+			Description: result.Description,
+			RetryAfter:  time.Duration(result.Parameters.RetryAfter) * time.Second,
+		}
+	}
+	return nil
+}
+
+// telegramEmoji picks an icon matching the kind of alert
+func telegramEmoji(message string) string {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "inversion"):
+		return "🌡️"
+	case strings.Contains(lower, "no readings"):
+		return "📭"
+	case strings.Contains(lower, "fail"), strings.Contains(lower, "error"):
+		return "❌"
+	default:
+		return "⚠️"
+	}
+}
+
+// formatTelegramAlert builds the message text: the alert itself, the station
+// it comes from and when it was raised
+func formatTelegramAlert(message string, at time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", telegramEmoji(message), message)
+	if config.StationName != "" {
+		fmt.Fprintf(&b, "📍 %s\n", config.StationName)
+	}
+	fmt.Fprintf(&b, "🕒 %s", at.In(config.Location).Format("2006-01-02 15:04:05 MST"))
+	return b.String()
+}
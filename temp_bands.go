@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tempBandEdges are the ascending band edges (°C) parsed from
+// TEMP_BAND_EDGES; n edges give n+1 bands
+var tempBandEdges []float64
+
+// parseTempBandEdges parses a comma-separated list of strictly ascending
+// temperatures such as "0,10,20,30"
+func parseTempBandEdges(value string) ([]float64, error) {
+	var edges []float64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		edge, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid band edge %q", part)
+		}
+		if len(edges) > 0 && edge <= edges[len(edges)-1] {
+			return nil, fmt.Errorf("band edges must be strictly ascending, got %s after %s",
+				formatBandEdge(edge), formatBandEdge(edges[len(edges)-1]))
+		}
+		edges = append(edges, edge)
+	}
+	if len(edges) == 0 {
+		return nil, fmt.Errorf("at least one band edge is required")
+	}
+	return edges, nil
+}
+
+func formatBandEdge(edge float64) string {
+	return strconv.FormatFloat(edge, 'f', -1, 64)
+}
+
+// tempBandLabels names the bands: "<0", "0-10", ..., ">=30"
+func tempBandLabels(edges []float64) []string {
+	labels := make([]string, 0, len(edges)+1)
+	labels = append(labels, "<"+formatBandEdge(edges[0]))
+	for i := 1; i < len(edges); i++ {
+		labels = append(labels, formatBandEdge(edges[i-1])+"-"+formatBandEdge(edges[i]))
+	}
+	return append(labels, ">="+formatBandEdge(edges[len(edges)-1]))
+}
+
+// tempBand returns the index of the band containing temperature. A value
+// equal to an edge belongs to the band above it.
+func tempBand(temperature float64, edges []float64) int {
+	return sort.Search(len(edges), func(i int) bool { return temperature < edges[i] })
+}
+
+// tempBandHours counts the hours of hourly averages falling in each band
+func tempBandHours(temperatures []float64, edges []float64) []int {
+	hours := make([]int, len(edges)+1)
+	for _, t := range temperatures {
+		hours[tempBand(t, edges)]++
+	}
+	return hours
+}
+
+// encodeTempBandHours encodes the counts as a JSON object keyed by band
+// label, keeping the bands in ascending order. Labels hold only digits and
+// "<>=-.", so quoting them needs no JSON escaping.
+func encodeTempBandHours(hours []int, edges []float64) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, label := range tempBandLabels(edges) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s:%d", strconv.Quote(label), hours[i])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// updateDailyTempBandHours allocates every hourly average temperature of an
+// observation day to a band and stores the hours per band as JSON in
+// temp_band_hours
+func updateDailyTempBandHours(db dbExecutor, date string) error {
+	hours, args, err := observationDayHours(date)
+	if err != nil {
+		return err
+	}
+	rows, err := db.Query(`SELECT avg_temperature FROM weather_hourly WHERE `+hours+` AND avg_temperature IS NOT NULL`+stationFilter(db), args...)
+	if err != nil {
+		return fmt.Errorf("failed to query hourly averages: %w", err)
+	}
+	defer rows.Close()
+
+	var temperatures []float64
+	for rows.Next() {
+		var temperature float64
+		if err := rows.Scan(&temperature); err != nil {
+			return fmt.Errorf("failed to scan hourly average: %w", err)
+		}
+		temperatures = append(temperatures, temperature)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate hourly averages: %w", err)
+	}
+
+	payload := encodeTempBandHours(tempBandHours(temperatures, tempBandEdges), tempBandEdges)
+	if _, err := db.Exec(`UPDATE weather_daily SET temp_band_hours = ? WHERE date = ?`+stationFilter(db), payload, date); err != nil {
+		return fmt.Errorf("failed to store temperature band hours: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTempBandHours(t *testing.T) {
+	edges := []float64{0, 10, 20, 30}
+	tests := []struct {
+		name         string
+		temperatures []float64
+		want         string
+	}{
+		{"no hours", nil, `{"<0":0,"0-10":0,"10-20":0,"20-30":0,">=30":0}`},
+		{"edge belongs to the band above", []float64{-0.1, 0, 9.9, 10, 29.9, 30},
+			`{"<0":1,"0-10":2,"10-20":1,"20-30":1,">=30":1}`},
+		{"whole day in one band", []float64{12, 14, 15, 17, 19.9}, `{"<0":0,"0-10":0,"10-20":5,"20-30":0,">=30":0}`},
+		{"outside the edges", []float64{-25, 41}, `{"<0":1,"0-10":0,"10-20":0,"20-30":0,">=30":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeTempBandHours(tempBandHours(tt.temperatures, edges), edges); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDailyTempBandHoursFollowObservationDay(t *testing.T) {
+	setupTestConfig(t, map[string]string{"ENABLE_TEMP_BANDS": "true", "DAY_BOUNDARY_HOUR": "6"})
+	db := newTestStore(t)
+
+	// The observation day 2024-06-01 runs from 06:00 to 06:00 the next
+	// morning: 12 hours at 15 °C, then 12 hours at 5 °C. The hot hour before
+	// and the frosty hour after it belong to the neighbouring days.
+	midnight := time.Date(2024, 6, 1, 0, 0, 0, 0, config.Location)
+	temperature := func(hour int) float64 {
+		switch {
+		case hour < 6:
+			return 35
+		case hour < 18:
+			return 15
+		case hour < 30:
+			return 5
+		}
+		return -5
+	}
+	for hour := 3; hour <= 31; hour++ {
+		processFixture(t, db, []byte(fmt.Sprintf(
+			`{"timestamp": %d, "temperature": %.1f, "pressure": 1012.0, "humidity": 60.0}`,
+			midnight.Add(time.Duration(hour)*time.Hour).Unix(), temperature(hour))))
+	}
+	if err := updateDailyStatisticsFor(db, midnight); err != nil {
+		t.Fatalf("updateDailyStatisticsFor: %v", err)
+	}
+
+	rows := queryRows(t, db, `SELECT temp_band_hours FROM weather_daily WHERE date = '2024-06-01'`)
+	want := `{"<0":0,"0-10":12,"10-20":12,"20-30":0,">=30":0}`
+	if len(rows) != 1 || rows[0]["temp_band_hours"] != want {
+		t.Errorf("temp_band_hours = %v, want %s", rows, want)
+	}
+}
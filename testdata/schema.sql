@@ -0,0 +1,151 @@
+-- SQLite version of the tables in README.md used by the integration tests.
+-- Aggregate columns are nullable so a metric without samples can be stored
+-- as NULL.
+
+CREATE TABLE weather (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    measured_at DATETIME NOT NULL,
+    temperature DOUBLE NULL,
+    pressure DOUBLE NULL,
+    humidity DOUBLE NULL,
+    station_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    rain_mm DOUBLE NULL,
+    rain_rate DOUBLE NULL,
+    solar_radiation DOUBLE NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_station_measured_at ON weather (station_id, measured_at);
+
+CREATE TABLE weather_hourly (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    station_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    date DATE NOT NULL,
+    hour INTEGER NOT NULL,
+    avg_temperature DOUBLE NULL,
+    avg_pressure DOUBLE NULL,
+    avg_humidity DOUBLE NULL,
+    samples_count INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (station_id, date, hour)
+);
+
+CREATE TABLE weather_daily (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    station_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    date DATE NOT NULL,
+    avg_temperature DOUBLE NULL,
+    min_temperature DOUBLE NULL,
+    max_temperature DOUBLE NULL,
+    avg_pressure DOUBLE NULL,
+    min_pressure DOUBLE NULL,
+    max_pressure DOUBLE NULL,
+    avg_humidity DOUBLE NULL,
+    min_humidity DOUBLE NULL,
+    max_humidity DOUBLE NULL,
+    samples_count INTEGER NOT NULL,
+    sea_temperature DOUBLE NULL,
+    total_rain DOUBLE NULL,
+    sunshine_hours INTEGER NULL,
+    change_index INTEGER NULL,
+    temp_band_hours TEXT NULL,
+    avg_temperature_gridded DOUBLE NULL,
+    comfort_cold_hours INTEGER NULL,
+    comfort_cool_hours INTEGER NULL,
+    comfort_comfortable_hours INTEGER NULL,
+    comfort_warm_hours INTEGER NULL,
+    comfort_hot_hours INTEGER NULL,
+    stddev_temperature DOUBLE NULL,
+    stddev_pressure DOUBLE NULL,
+    stddev_humidity DOUBLE NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (station_id, date)
+);
+
+CREATE TABLE weather_weekly (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    station_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    year INTEGER NOT NULL,
+    week INTEGER NOT NULL,
+    week_start DATE NOT NULL,
+    week_end DATE NOT NULL,
+    avg_temperature DOUBLE NULL,
+    min_temperature DOUBLE NULL,
+    max_temperature DOUBLE NULL,
+    avg_pressure DOUBLE NULL,
+    min_pressure DOUBLE NULL,
+    max_pressure DOUBLE NULL,
+    avg_humidity DOUBLE NULL,
+    min_humidity DOUBLE NULL,
+    max_humidity DOUBLE NULL,
+    samples_count INTEGER NOT NULL,
+    total_gdd DOUBLE NULL,
+    total_hdd DOUBLE NULL,
+    total_cdd DOUBLE NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (station_id, year, week)
+);
+
+CREATE TABLE weather_monthly (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    station_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    year INTEGER NOT NULL,
+    month INTEGER NOT NULL,
+    avg_temperature DOUBLE NULL,
+    min_temperature DOUBLE NULL,
+    max_temperature DOUBLE NULL,
+    avg_pressure DOUBLE NULL,
+    min_pressure DOUBLE NULL,
+    max_pressure DOUBLE NULL,
+    avg_humidity DOUBLE NULL,
+    min_humidity DOUBLE NULL,
+    max_humidity DOUBLE NULL,
+    samples_count INTEGER NOT NULL,
+    rain_days INTEGER NULL,
+    wettest_day DATE NULL,
+    wettest_day_rain DOUBLE NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (station_id, year, month)
+);
+
+CREATE TABLE weather_long (
+    station_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    measured_at DATETIME NOT NULL,
+    metric VARCHAR(64) NOT NULL,
+    value DOUBLE NOT NULL,
+    PRIMARY KEY (station_id, measured_at, metric)
+);
+
+CREATE TABLE weather_running (
+    station_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    date DATE NOT NULL,
+    samples_count INTEGER NOT NULL,
+    temperature_count INTEGER NOT NULL,
+    temperature_sum DOUBLE NOT NULL,
+    temperature_sum_sq DOUBLE NOT NULL,
+    temperature_min DOUBLE NULL,
+    temperature_max DOUBLE NULL,
+    pressure_count INTEGER NOT NULL,
+    pressure_sum DOUBLE NOT NULL,
+    pressure_sum_sq DOUBLE NOT NULL,
+    pressure_min DOUBLE NULL,
+    pressure_max DOUBLE NULL,
+    humidity_count INTEGER NOT NULL,
+    humidity_sum DOUBLE NOT NULL,
+    humidity_sum_sq DOUBLE NOT NULL,
+    humidity_min DOUBLE NULL,
+    humidity_max DOUBLE NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (station_id, date)
+);
+
+CREATE TABLE weather_exclusions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    start_at DATETIME NOT NULL,
+    end_at DATETIME NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// webhookTimeout bounds a single webhook POST, so an unreachable endpoint
+// delays processing by at most this long
+const webhookTimeout = 10 * time.Second
+
+// Kinds of temperature threshold breaches
+const (
+	breachBelowMin = "below_min"
+	breachAboveMax = "above_max"
+)
+
+// ThresholdAlert is the JSON payload posted to ALERT_WEBHOOK_URL. Text makes
+// it readable as a Slack incoming webhook message as is.
+type ThresholdAlert struct {
+	Text       string    `json:"text"`
+	Station    string    `json:"station,omitempty"`
+	StationID  string    `json:"station_id"`
+	Metric     string    `json:"metric"`
+	Breach     string    `json:"breach"`
+	Value      float64   `json:"value"`
+	Threshold  float64   `json:"threshold"`
+	MeasuredAt time.Time `json:"measured_at"`
+}
+
+// thresholdAlerter posts an alert when a station's temperature leaves the
+// ALERT_TEMP_MIN..ALERT_TEMP_MAX range. It alerts once per continuous breach;
+// the state is kept in memory only, so a restart during a breach alerts again.
+type thresholdAlerter struct {
+	url    string
+	client *http.Client
+
+	mu     sync.Mutex
+	active map[string]string // station ID -> current breach kind
+}
+
+var tempAlerter *thresholdAlerter
+
+// newThresholdAlerter creates an alerter posting to endpoint
+func newThresholdAlerter(endpoint string) *thresholdAlerter {
+	return &thresholdAlerter{
+		url:    endpoint,
+		client: &http.Client{Timeout: webhookTimeout},
+		active: make(map[string]string),
+	}
+}
+
+// tempBreach returns the kind of breach of temperature and the threshold it
+// crossed, "" when it is within the configured range
+func tempBreach(temperature float64) (string, float64) {
+	switch {
+	case config.AlertTempMin != nil && temperature < *config.AlertTempMin:
+		return breachBelowMin, *config.AlertTempMin
+	case config.AlertTempMax != nil && temperature > *config.AlertTempMax:
+		return breachAboveMax, *config.AlertTempMax
+	}
+	return "", 0
+}
+
+// Check updates the breach state of a stored reading's station and posts an
+// alert when a new breach starts. Delivery failures are only logged.
+func (a *thresholdAlerter) Check(w WeatherData) {
+	if w.Missing["temperature"] {
+		return
+	}
+	breach, threshold := tempBreach(w.Temperature)
+
+	a.mu.Lock()
+	previous := a.active[w.StationID]
+	if breach == "" {
+		delete(a.active, w.StationID)
+	} else {
+		a.active[w.StationID] = breach
+	}
+	a.mu.Unlock()
+
+	if breach == "" {
+		if previous != "" {
+			slog.Info("Temperature back within alert thresholds", "component", "processor",
+				"station_id", w.StationID, "temperature", w.Temperature)
+		}
+		return
+	}
+	if breach == previous {
+		return
+	}
+
+	alert := ThresholdAlert{
+		Station:    config.StationName,
+		StationID:  w.StationID,
+		Metric:     "temperature",
+		Breach:     breach,
+		Value:      w.Temperature,
+		Threshold:  threshold,
+		MeasuredAt: measurementTime(w.Timestamp),
+	}
+	alert.Text = formatThresholdAlert(alert)
+	slog.Warn("Temperature threshold breached", "component", "processor",
+		"station_id", w.StationID, "breach", breach, "temperature", w.Temperature, "threshold", threshold)
+	if err := a.post(alert); err != nil {
+		slog.Warn("Failed to send threshold alert", "component", "processor",
+			"station_id", w.StationID, "measured_at", alert.MeasuredAt, "error", err)
+	}
+}
+
+// post sends one alert to the webhook
+func (a *thresholdAlerter) post(alert ThresholdAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+	if dryRunSkip("webhook", "payload", string(body)) {
+		return nil
+	}
+	resp, err := a.client.Post(a.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// Webhook URLs usually embed a secret, keep it out of the logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// formatThresholdAlert describes a breach in one line
+func formatThresholdAlert(a ThresholdAlert) string {
+	station := a.StationID
+	if a.Station != "" {
+		station = a.Station
+	}
+	direction := "below the minimum"
+	if a.Breach == breachAboveMax {
+		direction = "above the maximum"
+	}
+	return fmt.Sprintf("%s: temperature %.1f °C is %s of %.1f °C (measured %s)",
+		station, a.Value, direction, a.Threshold, a.MeasuredAt.Format("2006-01-02 15:04"))
+}
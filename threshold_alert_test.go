@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestThresholdAlerterDebounce(t *testing.T) {
+	type step struct {
+		station     string
+		temperature float64
+		missing     bool
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		want  []string // station/breach of each alert posted
+	}{
+		{"within range", []step{{"a", 0, false}, {"a", 30, false}}, nil},
+		{"at the thresholds", []step{{"a", -5, false}, {"a", 35, false}}, nil},
+		{"continuous breach alerts once", []step{{"a", -6, false}, {"a", -8, false}, {"a", -5.5, false}}, []string{"a/below_min"}},
+		{"breach again after recovery", []step{{"a", -6, false}, {"a", 0, false}, {"a", -6, false}}, []string{"a/below_min", "a/below_min"}},
+		{"switch of threshold", []step{{"a", -6, false}, {"a", 36, false}}, []string{"a/below_min", "a/above_max"}},
+		{"stations independent", []step{{"a", 36, false}, {"b", 36, false}, {"a", 37, false}}, []string{"a/above_max", "b/above_max"}},
+		{"missing temperature keeps the state", []step{{"a", 36, false}, {"a", 0, true}, {"a", 36, false}}, []string{"a/above_max"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestConfig(t, map[string]string{"ALERT_TEMP_MIN": "-5", "ALERT_TEMP_MAX": "35"})
+
+			var mu sync.Mutex
+			var got []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var alert ThresholdAlert
+				if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+					t.Errorf("invalid alert payload: %v", err)
+				}
+				mu.Lock()
+				got = append(got, alert.StationID+"/"+alert.Breach)
+				mu.Unlock()
+			}))
+			defer server.Close()
+
+			alerter := newThresholdAlerter(server.URL)
+			for i, s := range tt.steps {
+				w := WeatherData{Timestamp: int64(1717200000 + 60*i), Temperature: s.temperature, StationID: s.station}
+				if s.missing {
+					w.Missing = map[string]bool{"temperature": true}
+				}
+				alerter.Check(w)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("alerts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThresholdAlerterWebhookFailure(t *testing.T) {
+	setupTestConfig(t, map[string]string{"ALERT_TEMP_MAX": "35"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	alerter := newThresholdAlerter(server.URL)
+	if err := alerter.post(ThresholdAlert{Breach: breachAboveMax}); err == nil {
+		t.Error("post() to a failing webhook returned no error")
+	}
+	// A failed delivery is only logged and still counts as alerted
+	alerter.Check(WeatherData{Temperature: 40, StationID: "a"})
+	if alerter.active["a"] != breachAboveMax {
+		t.Errorf("breach state = %q, want %q", alerter.active["a"], breachAboveMax)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timestampFields are the TIMESTAMP_FIELDS candidates in priority order
+var timestampFields []string
+
+// parseTimestampFieldList splits TIMESTAMP_FIELDS into candidate names
+func parseTimestampFieldList(value string) []string {
+	var fields []string
+	for _, f := range strings.Split(value, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// readingTimestamp returns the Unix timestamp from the first candidate field
+// present in the raw reading, and the name of that field. See
+// parseTimestampValue for the accepted forms.
+func readingTimestamp(raw []byte, candidates []string) (int64, string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return 0, "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	for _, name := range candidates {
+		value, ok := fields[name]
+		if !ok {
+			continue
+		}
+		ts, err := parseTimestampValue(value)
+		if err != nil {
+			return 0, name, fmt.Errorf("invalid timestamp in field %q: %w", name, err)
+		}
+		return ts, name, nil
+	}
+	return 0, "", fmt.Errorf("no timestamp field found, tried %s", strings.Join(candidates, ", "))
+}
+
+// parseTimestampValue parses a JSON timestamp value into Unix seconds. An
+// integer is epoch seconds. A string is tried as RFC 3339 (e.g.
+// "2024-03-05T14:30:00Z"), then as a naive station-local datetime in
+// TIMESTAMP_LAYOUT, interpreted in TIMEZONE.
+func parseTimestampValue(value json.RawMessage) (int64, error) {
+	var ts int64
+	if err := json.Unmarshal(value, &ts); err == nil {
+		return ts, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil {
+		return 0, fmt.Errorf("expected Unix epoch seconds or a datetime string, got %s", value)
+	}
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t.Unix(), nil
+	}
+	ts, err := parseLocalTimestamp(text, config.TimestampLayout, config.Location)
+	if err != nil {
+		return 0, fmt.Errorf("%q is neither RFC 3339 nor in TIMESTAMP_LAYOUT %q", text, config.TimestampLayout)
+	}
+	return ts, nil
+}
+
+// UnmarshalJSON decodes a reading, accepting the timestamp field in any form
+// parseTimestampValue does. A payload whose timestamp parses in none of them
+// is rejected.
+func (w *WeatherData) UnmarshalJSON(data []byte) error {
+	type plain WeatherData
+	aux := struct {
+		*plain
+		Timestamp json.RawMessage `json:"timestamp"`
+	}{plain: (*plain)(w)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	// A missing timestamp is left to TIMESTAMP_FIELDS
+	if len(aux.Timestamp) == 0 || string(aux.Timestamp) == "null" {
+		return nil
+	}
+	ts, err := parseTimestampValue(aux.Timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	w.Timestamp = ts
+	return nil
+}
+
+// measurementTime is the instant of a Unix timestamp in TIMEZONE. measured_at
+// is stored as wall clock time in that zone and every date and hour of the
+// statistics is derived in it.
+func measurementTime(timestamp int64) time.Time {
+	return time.Unix(timestamp, 0).In(config.Location)
+}
+
+// parseLocalTimestamp parses a datetime without a zone as local time of loc.
+// time.Parse would treat it as UTC and shift the reading by the UTC offset.
+func parseLocalTimestamp(value, layout string, loc *time.Location) (int64, error) {
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
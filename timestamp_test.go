@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseLocalTimestamp(t *testing.T) {
+	prague, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  string // UTC instant
+	}{
+		{"summer time, UTC+2", "2024-06-01 14:00:00", "2024-06-01T12:00:00Z"},
+		{"winter time, UTC+1", "2024-01-15 14:00:00", "2024-01-15T13:00:00Z"},
+		{"last second before spring forward", "2024-03-31 01:59:59", "2024-03-31T00:59:59Z"},
+		{"first hour after spring forward", "2024-03-31 03:00:00", "2024-03-31T01:00:00Z"},
+		{"first hour after fall back", "2024-10-27 03:00:00", "2024-10-27T02:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLocalTimestamp(tt.value, "2006-01-02 15:04:05", prague)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if utc := time.Unix(got, 0).UTC().Format(time.RFC3339); utc != tt.want {
+				t.Errorf("parseLocalTimestamp(%q) = %s, want %s", tt.value, utc, tt.want)
+			}
+		})
+	}
+}
+
+func TestNaiveTimestampInStationZone(t *testing.T) {
+	setupTestConfig(t, map[string]string{"TIMEZONE": "Europe/Prague"})
+
+	var reading WeatherData
+	if err := json.Unmarshal([]byte(`{"timestamp": "2024-06-01 14:00:00", "temperature": 21.0}`), &reading); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC).Unix(); reading.Timestamp != want {
+		t.Errorf("timestamp = %d, want %d", reading.Timestamp, want)
+	}
+	// measured_at is stored as the same wall time
+	if got := measurementTime(reading.Timestamp).Format("2006-01-02 15:04:05"); got != "2024-06-01 14:00:00" {
+		t.Errorf("measurement time = %s, want 2024-06-01 14:00:00", got)
+	}
+}
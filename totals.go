@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Accumulating metrics (degree-days) must be summed over a period rather
+// than averaged, so weekly and monthly totals are computed from the daily
+// rows in weather_daily instead of from raw readings.
+
+// PeriodTotals holds summed daily values for a week or month
+type PeriodTotals struct {
+	GDD float64 // growing degree-days
+	HDD float64 // heating degree-days
+	CDD float64 // cooling degree-days
+}
+
+// sumDailyTotals sums the daily degree-days between from and to (inclusive)
+func sumDailyTotals(db dbExecutor, from, to string) (PeriodTotals, error) {
+	var totals PeriodTotals
+
+	query := `
+		SELECT
+			COALESCE(SUM(GREATEST(avg_temperature - ?, 0)), 0),
+			COALESCE(SUM(GREATEST(? - avg_temperature, 0)), 0),
+			COALESCE(SUM(GREATEST(avg_temperature - ?, 0)), 0)
+		FROM weather_daily
+		WHERE date >= ? AND date <= ?` + stationFilter(db) + `
+	`
+
+	err := db.QueryRow(query,
+		config.GDDBase, config.HDDBase, config.CDDBase,
+		from, to).Scan(&totals.GDD, &totals.HDD, &totals.CDD)
+	if err != nil {
+		return totals, fmt.Errorf("failed to sum daily totals: %w", err)
+	}
+
+	totals.GDD = roundAggregate(totals.GDD)
+	totals.HDD = roundAggregate(totals.HDD)
+	totals.CDD = roundAggregate(totals.CDD)
+	return totals, nil
+}
+
+// updateWeeklyTotals stores the summed daily values for an ISO week
+func updateWeeklyTotals(db dbExecutor, year, week int, weekStart, weekEnd string) error {
+	totals, err := sumDailyTotals(db, weekStart, weekEnd)
+	if err != nil {
+		return err
+	}
+
+	update := `
+		UPDATE weather_weekly
+		SET total_gdd = ?, total_hdd = ?, total_cdd = ?
+		WHERE year = ? AND week = ?` + stationFilter(db) + `
+	`
+	if _, err := db.Exec(update, totals.GDD, totals.HDD, totals.CDD, year, week); err != nil {
+		return fmt.Errorf("failed to store weekly totals: %w", err)
+	}
+	return nil
+}
+
+// updateMonthlyTotals stores the summed daily values for a month
+func updateMonthlyTotals(db dbExecutor, year, month int, firstDay, lastDay string) error {
+	totals, err := sumDailyTotals(db, firstDay, lastDay)
+	if err != nil {
+		return err
+	}
+
+	update := `
+		UPDATE weather_monthly
+		SET total_gdd = ?, total_hdd = ?, total_cdd = ?
+		WHERE year = ? AND month = ?` + stationFilter(db) + `
+	`
+	if _, err := db.Exec(update, totals.GDD, totals.HDD, totals.CDD, year, month); err != nil {
+		return fmt.Errorf("failed to store monthly totals: %w", err)
+	}
+	return nil
+}
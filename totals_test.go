@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWeeklyTotalsSumDailyRows(t *testing.T) {
+	setupTestConfig(t, map[string]string{
+		"ENABLE_PERIOD_TOTALS": "true",
+		"GDD_BASE":             "10",
+		"HDD_BASE":             "18",
+		"CDD_BASE":             "18",
+	})
+	db := newTestStore(t)
+
+	// Monday to Wednesday of ISO week 2024-W23, two readings a day averaging
+	// 8, 15 and 22 °C
+	monday := time.Date(2024, 6, 3, 0, 0, 0, 0, config.Location)
+	temperatures := [][2]float64{{6, 10}, {14, 16}, {20, 24}}
+	for i, pair := range temperatures {
+		day := monday.AddDate(0, 0, i)
+		for j, temperature := range pair {
+			at := day.Add(time.Duration(6+6*j) * time.Hour)
+			processFixture(t, db, []byte(fmt.Sprintf(
+				`{"timestamp": %d, "temperature": %.1f, "pressure": 1012.0, "humidity": 70.0}`, at.Unix(), temperature)))
+		}
+		if err := updateDailyStatisticsFor(db, day); err != nil {
+			t.Fatalf("updateDailyStatisticsFor: %v", err)
+		}
+	}
+	if err := updateWeeklyStatisticsFor(db, monday); err != nil {
+		t.Fatalf("updateWeeklyStatisticsFor: %v", err)
+	}
+
+	var want PeriodTotals
+	for _, row := range queryRows(t, db, `SELECT avg_temperature FROM weather_daily ORDER BY date`) {
+		avg, err := strconv.ParseFloat(row["avg_temperature"], 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want.GDD += max(avg-config.GDDBase, 0)
+		want.HDD += max(config.HDDBase-avg, 0)
+		want.CDD += max(avg-config.CDDBase, 0)
+	}
+	if want != (PeriodTotals{GDD: 17, HDD: 13, CDD: 4}) {
+		t.Fatalf("daily rows sum to %+v, want GDD 17, HDD 13, CDD 4", want)
+	}
+
+	rows := queryRows(t, db, `SELECT total_gdd, total_hdd, total_cdd FROM weather_weekly WHERE year = 2024 AND week = 23`)
+	if len(rows) != 1 {
+		t.Fatalf("got %d weekly rows, want 1", len(rows))
+	}
+	for column, value := range map[string]float64{"total_gdd": want.GDD, "total_hdd": want.HDD, "total_cdd": want.CDD} {
+		if got := rows[0][column]; got != strconv.FormatFloat(value, 'f', -1, 64) {
+			t.Errorf("%s = %q, want %v", column, got, value)
+		}
+	}
+}
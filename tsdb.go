@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// With TSDB_ENABLED the core metrics of a reading are also written to
+// weather_metrics, one (metric_name, value, measured_at) row each, in the
+// transaction of the weather row. Grafana's MySQL data source graphs this
+// shape directly, without a query per column.
+
+// insertMetricRows writes the stored temperature, pressure and humidity of
+// a reading to weather_metrics; missing values get no row
+func insertMetricRows(db dbExecutor, w WeatherData, stored storedValues) error {
+	measuredAt := measurementTime(w.Timestamp)
+	metrics := []struct {
+		name  string
+		value *float64
+	}{
+		{"temperature", stored.Temperature},
+		{"pressure", stored.Pressure},
+		{"humidity", stored.Humidity},
+	}
+
+	tuples := make([]string, 0, len(metrics))
+	args := make([]any, 0, len(metrics)*4)
+	for _, m := range metrics {
+		if m.value == nil {
+			continue
+		}
+		tuples = append(tuples, "(?, ?, ?, ?)")
+		args = append(args, w.StationID, m.name, *m.value, measuredAt)
+	}
+	if len(tuples) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO weather_metrics (station_id, metric_name, value, measured_at) VALUES ` + strings.Join(tuples, ", ")
+	if _, err := db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to write metric rows: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// dbExecutor is implemented by both *sql.DB and *sql.Tx so the aggregate
+// updates can run either in autocommit mode or inside a transaction
+type dbExecutor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Store is the database the processing path writes readings to. *sql.DB
+// satisfies it in production; the integration tests pass an in-memory SQLite
+// database behind the same interface.
+type Store interface {
+	dbExecutor
+	Begin() (*sql.Tx, error)
+	Ping() error
+}
+
+// withAggregateTx runs fn in a single transaction when BATCH_AGGREGATE_WRITES
+// is enabled, so a set of aggregate upserts is committed all or nothing.
+// Otherwise every statement autocommits as before. The upserts are
+// idempotent, so fn is retried as a whole on transient connection errors.
+func withAggregateTx(db Store, fn func(db dbExecutor) error) error {
+	return withDBRetry(func() error {
+		return aggregateTx(db, fn)
+	})
+}
+
+// aggregateTx is one attempt of withAggregateTx
+func aggregateTx(db Store, fn func(db dbExecutor) error) error {
+	if !config.BatchAggregateWrites {
+		return fn(db)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// The transaction works on the station db is bound to
+	if err := fn(forStation(tx, stationOf(db))); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit aggregates: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Readings are stored in canonical units: °C and hPa. Sources reporting in
+// other units declare them in SOURCE_UNITS and are converted before insert.
+
+// unitConversions maps metric -> unit -> conversion to the canonical unit
+var unitConversions = map[string]map[string]func(float64) float64{
+	"temperature": {
+		"C": func(v float64) float64 { return v },
+		"F": func(v float64) float64 { return (v - 32) * 5 / 9 },
+		"K": func(v float64) float64 { return v - 273.15 },
+	},
+	"pressure": {
+		"hPa":  func(v float64) float64 { return v },
+		"mbar": func(v float64) float64 { return v },
+		"Pa":   func(v float64) float64 { return v / 100 },
+		"kPa":  func(v float64) float64 { return v * 10 },
+		"inHg": func(v float64) float64 { return v * 33.8639 },
+		"mmHg": func(v float64) float64 { return v * 1.33322 },
+	},
+}
+
+// defaultSource is the source name of readings without a "source" field
+const defaultSource = "default"
+
+// SourceUnits maps a metric to the unit a source reports it in
+type SourceUnits map[string]string
+
+// sourceUnits holds the parsed SOURCE_UNITS declaration
+var sourceUnits map[string]SourceUnits
+
+// parseSourceUnits parses "source:metric=unit,metric=unit;source:..." and
+// rejects unknown metrics and units
+func parseSourceUnits(value string) (map[string]SourceUnits, error) {
+	result := make(map[string]SourceUnits)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, decls, ok := strings.Cut(entry, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("expected source:metric=unit, got %q", entry)
+		}
+		name = strings.TrimSpace(name)
+
+		units := make(SourceUnits)
+		for _, decl := range strings.Split(decls, ",") {
+			metric, unit, ok := strings.Cut(strings.TrimSpace(decl), "=")
+			if !ok {
+				return nil, fmt.Errorf("source %s: expected metric=unit, got %q", name, decl)
+			}
+			conversions, known := unitConversions[metric]
+			if !known {
+				return nil, fmt.Errorf("source %s: unknown metric %q", name, metric)
+			}
+			if _, known := conversions[unit]; !known {
+				return nil, fmt.Errorf("source %s: unknown %s unit %q", name, metric, unit)
+			}
+			units[metric] = unit
+		}
+		result[name] = units
+	}
+	return result, nil
+}
+
+// normalizeUnits converts a reading to the canonical units according to the
+// declaration of its source. Sources without a declaration are assumed to
+// report canonical units already.
+func normalizeUnits(data *WeatherData) {
+	name := data.Source
+	if name == "" {
+		name = defaultSource
+	}
+	units, ok := sourceUnits[name]
+	if !ok {
+		return
+	}
+
+	if unit, ok := units["temperature"]; ok {
+		data.Temperature = unitConversions["temperature"][unit](data.Temperature)
+	}
+	if unit, ok := units["pressure"]; ok {
+		data.Pressure = unitConversions["pressure"][unit](data.Pressure)
+	}
+}
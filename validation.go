@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// plausibleRange is the physically plausible range of a reading field
+type plausibleRange struct {
+	field    string
+	min, max float64
+	value    func(w *WeatherData) *float64
+}
+
+// plausibleRanges reject sensor glitches such as -999 °C or 1500 % humidity
+var plausibleRanges = []plausibleRange{
+	{"temperature", -90, 60, func(w *WeatherData) *float64 { return &w.Temperature }},
+	{"humidity", 0, 100, func(w *WeatherData) *float64 { return &w.Humidity }},
+	{"pressure", 850, 1100, func(w *WeatherData) *float64 { return &w.Pressure }},
+}
+
+// validateReading reports every field of a (unit-normalized) reading that
+// lies outside its plausible range. Fields marked missing are not checked.
+func validateReading(w WeatherData) error {
+	var problems []string
+	for _, r := range plausibleRanges {
+		if w.Missing[r.field] {
+			continue
+		}
+		if v := *r.value(&w); math.IsNaN(v) || v < r.min || v > r.max {
+			problems = append(problems, fmt.Sprintf("%s %g outside %g..%g", r.field, v, r.min, r.max))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("implausible reading: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// clampReading moves out-of-range fields to the nearest plausible value and
+// returns a description of each change
+func clampReading(w *WeatherData) []string {
+	var changes []string
+	for _, r := range plausibleRanges {
+		if w.Missing[r.field] {
+			continue
+		}
+		v := r.value(w)
+		clamped := math.Max(r.min, math.Min(*v, r.max))
+		if math.IsNaN(*v) || clamped != *v {
+			changes = append(changes, fmt.Sprintf("%s %g -> %g", r.field, *v, clamped))
+			*v = clamped
+		}
+	}
+	return changes
+}
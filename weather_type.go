@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// Weather type labels stored in weather_daily.weather_type
+const (
+	WeatherSunny  = "sunny"
+	WeatherCloudy = "cloudy"
+	WeatherRainy  = "rainy"
+	WeatherStormy = "stormy"
+	WeatherFoggy  = "foggy"
+)
+
+// DayConditions holds the daily values the weather type classifier uses.
+// Rainfall is nil when the station has no rain gauge.
+type DayConditions struct {
+	MinTemperature float64
+	MaxTemperature float64
+	AvgHumidity    float64
+	PressureTrend  float64 // hPa, last reading of the day minus the first
+	Rainfall       *float64
+}
+
+// WeatherTypeThresholds configures the classifier rules
+type WeatherTypeThresholds struct {
+	RainyRainMM        float64
+	StormyRainMM       float64
+	StormyPressureDrop float64
+	FallingPressure    float64
+	RainyHumidity      float64
+	FoggyHumidity      float64
+	CloudyHumidity     float64
+	SunnyAmplitude     float64
+}
+
+// classifyWeather assigns a coarse weather label to a day. It is a heuristic,
+// rules are evaluated in order and the first match wins:
+//
+//   - stormy: heavy rain with a sharp pressure drop (without a rain gauge:
+//     a sharp drop on a humid day)
+//   - rainy:  measurable rain (without a rain gauge: very humid and falling
+//     pressure)
+//   - foggy:  saturated air, steady pressure and a small temperature range
+//   - sunny:  large temperature range and moderate humidity
+//   - cloudy: everything else
+func classifyWeather(c DayConditions, t WeatherTypeThresholds) string {
+	falling := c.PressureTrend <= -t.FallingPressure
+	sharpDrop := c.PressureTrend <= -t.StormyPressureDrop
+	amplitude := c.MaxTemperature - c.MinTemperature
+
+	if c.Rainfall != nil {
+		if *c.Rainfall >= t.StormyRainMM && sharpDrop {
+			return WeatherStormy
+		}
+		if *c.Rainfall >= t.RainyRainMM {
+			return WeatherRainy
+		}
+	} else {
+		if sharpDrop && c.AvgHumidity >= t.CloudyHumidity {
+			return WeatherStormy
+		}
+		if falling && c.AvgHumidity >= t.RainyHumidity {
+			return WeatherRainy
+		}
+	}
+
+	if c.AvgHumidity >= t.FoggyHumidity && !falling && amplitude < t.SunnyAmplitude {
+		return WeatherFoggy
+	}
+	if amplitude >= t.SunnyAmplitude && c.AvgHumidity < t.CloudyHumidity {
+		return WeatherSunny
+	}
+	return WeatherCloudy
+}
+
+// weatherTypeThresholds returns the classifier thresholds from the config
+func weatherTypeThresholds() WeatherTypeThresholds {
+	return WeatherTypeThresholds{
+		RainyRainMM:        config.WeatherTypeRainyMM,
+		StormyRainMM:       config.WeatherTypeStormyMM,
+		StormyPressureDrop: config.WeatherTypeStormyPressureDrop,
+		FallingPressure:    config.WeatherTypeFallingPressure,
+		RainyHumidity:      config.WeatherTypeRainyHumidity,
+		FoggyHumidity:      config.WeatherTypeFoggyHumidity,
+		CloudyHumidity:     config.WeatherTypeCloudyHumidity,
+		SunnyAmplitude:     config.WeatherTypeSunnyAmplitude,
+	}
+}
+
+// pressureTrend returns the difference between the last and the first
+// pressure reading of the given date, readings without a pressure left out
+func pressureTrend(db dbExecutor, date string) (float64, error) {
+	var first, last float64
+
+	query := `
+		SELECT
+			(SELECT pressure FROM weather WHERE ` + observationDay() + ` = ? AND pressure IS NOT NULL` + exclusionFilter() + stationFilter(db) + ` ORDER BY measured_at ASC LIMIT 1),
+			(SELECT pressure FROM weather WHERE ` + observationDay() + ` = ? AND pressure IS NOT NULL` + exclusionFilter() + stationFilter(db) + ` ORDER BY measured_at DESC LIMIT 1)
+	`
+
+	if err := db.QueryRow(query, date, date).Scan(&first, &last); err != nil {
+		return 0, fmt.Errorf("failed to query pressure trend: %w", err)
+	}
+	return last - first, nil
+}
+
+// updateDailyWeatherType classifies the given day and stores the label
+func updateDailyWeatherType(db dbExecutor, date string, conditions DayConditions) error {
+	trend, err := pressureTrend(db, date)
+	if err != nil {
+		return err
+	}
+	conditions.PressureTrend = trend
+
+	// The daily job stores total_rain before classifying the day
+	if config.EnableRainfall && conditions.Rainfall == nil {
+		var rainfall sql.NullFloat64
+		err := db.QueryRow(`SELECT total_rain FROM weather_daily WHERE date = ?`+stationFilter(db), date).Scan(&rainfall)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to query daily rainfall: %w", err)
+		}
+		if rainfall.Valid {
+			conditions.Rainfall = &rainfall.Float64
+		}
+	}
+
+	weatherType := classifyWeather(conditions, weatherTypeThresholds())
+	slog.Info("Weather type classified", "component", "stats", "date", date, "weather_type", weatherType,
+		"pressure_trend", conditions.PressureTrend)
+
+	_, err = db.Exec(`UPDATE weather_daily SET weather_type = ? WHERE date = ?`+stationFilter(db), weatherType, date)
+	if err != nil {
+		return fmt.Errorf("failed to store weather type: %w", err)
+	}
+	return nil
+}
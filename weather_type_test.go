@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestClassifyWeather(t *testing.T) {
+	thresholds := WeatherTypeThresholds{
+		RainyRainMM:        1,
+		StormyRainMM:       10,
+		StormyPressureDrop: 4,
+		FallingPressure:    1,
+		RainyHumidity:      85,
+		FoggyHumidity:      95,
+		CloudyHumidity:     75,
+		SunnyAmplitude:     10,
+	}
+	rain := func(mm float64) *float64 { return &mm }
+
+	tests := []struct {
+		name string
+		c    DayConditions
+		want string
+	}{
+		{"heavy rain with a sharp drop", DayConditions{12, 18, 90, -5, rain(15)}, WeatherStormy},
+		{"heavy rain at the storm thresholds", DayConditions{12, 18, 90, -4, rain(10)}, WeatherStormy},
+		{"heavy rain with steady pressure", DayConditions{12, 18, 90, 0, rain(15)}, WeatherRainy},
+		{"rain at the rainy threshold", DayConditions{12, 18, 80, 0, rain(1)}, WeatherRainy},
+		{"drizzle below the threshold", DayConditions{12, 14, 80, 0, rain(0.9)}, WeatherCloudy},
+		{"no gauge, sharp drop on a humid day", DayConditions{12, 18, 80, -4, nil}, WeatherStormy},
+		{"no gauge, sharp drop on a dry day", DayConditions{5, 20, 50, -4, nil}, WeatherSunny},
+		{"no gauge, humid and falling", DayConditions{12, 15, 85, -1, nil}, WeatherRainy},
+		{"no gauge, humid and steady", DayConditions{12, 15, 85, -0.5, nil}, WeatherCloudy},
+		{"saturated steady day", DayConditions{3, 6, 97, 0.5, rain(0)}, WeatherFoggy},
+		{"saturated day with a large range", DayConditions{3, 13, 97, 0.5, rain(0)}, WeatherCloudy},
+		{"saturated but falling", DayConditions{3, 6, 97, -2, rain(0)}, WeatherCloudy},
+		{"large range and moderate humidity", DayConditions{8, 26, 55, 0, rain(0)}, WeatherSunny},
+		{"large range at the cloudy humidity", DayConditions{8, 26, 75, 0, rain(0)}, WeatherCloudy},
+		{"range just below sunny", DayConditions{10, 19.9, 55, 0, rain(0)}, WeatherCloudy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyWeather(tt.c, thresholds); got != tt.want {
+				t.Errorf("classifyWeather(%+v) = %s, want %s", tt.c, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+)
+
+// calmVectorLength is the mean unit vector length below which directions
+// cancel out and the mean direction is undefined
+const calmVectorLength = 1e-6
+
+// meanWindDirection averages directions (degrees) as unit vectors, so that
+// 350° and 10° average to 0° rather than 180°. sinMean and cosMean are the
+// means of the sines and cosines of the directions; ok is false when they
+// cancel out.
+func meanWindDirection(sinMean, cosMean float64) (direction float64, ok bool) {
+	if math.Hypot(sinMean, cosMean) < calmVectorLength {
+		return 0, false
+	}
+	direction = math.Atan2(sinMean, cosMean) * 180 / math.Pi
+	if direction < 0 {
+		direction += 360
+	}
+	return direction, true
+}
+
+// updateHourlyWind stores the mean wind speed and the vector mean wind
+// direction of the readings matching rangeWhere into the weather_hourly row
+// matching keyWhere
+func updateHourlyWind(db dbExecutor, rangeWhere string, rangeArgs []any, keyWhere string, keyArgs []any) error {
+	query := `
+		SELECT
+			AVG(wind_speed),
+			AVG(SIN(RADIANS(wind_direction))),
+			AVG(COS(RADIANS(wind_direction)))
+		FROM weather
+		WHERE ` + rangeWhere + exclusionFilter() + stationFilter(db)
+
+	var avgSpeed, sinMean, cosMean sql.NullFloat64
+	if err := db.QueryRow(query, rangeArgs...).Scan(&avgSpeed, &sinMean, &cosMean); err != nil {
+		return fmt.Errorf("failed to calculate hourly wind: %w", err)
+	}
+
+	var avgDirection any
+	if sinMean.Valid && cosMean.Valid {
+		if direction, ok := meanWindDirection(sinMean.Float64, cosMean.Float64); ok {
+			avgDirection = roundAggregate(direction)
+		}
+	}
+
+	update := `UPDATE weather_hourly SET avg_wind_speed = ?, avg_wind_direction = ? WHERE ` + keyWhere + stationFilter(db)
+	args := append([]any{roundNullAggregate(avgSpeed), avgDirection}, keyArgs...)
+	if _, err := db.Exec(update, args...); err != nil {
+		return fmt.Errorf("failed to store hourly wind: %w", err)
+	}
+	return nil
+}